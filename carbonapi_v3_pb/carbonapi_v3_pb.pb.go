@@ -0,0 +1,3949 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: carbonapi_v3_pb.proto
+
+package carbonapi_v3_pb
+
+import (
+	encoding_binary "encoding/binary"
+	fmt "fmt"
+	_ "github.com/gogo/protobuf/gogoproto"
+	proto "github.com/gogo/protobuf/proto"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+type FilteringFunction struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Arguments            []string `protobuf:"bytes,2,rep,name=arguments,proto3" json:"arguments,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FilteringFunction) Reset()         { *m = FilteringFunction{} }
+func (m *FilteringFunction) String() string { return proto.CompactTextString(m) }
+func (*FilteringFunction) ProtoMessage()    {}
+func (*FilteringFunction) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{0}
+}
+func (m *FilteringFunction) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *FilteringFunction) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_FilteringFunction.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *FilteringFunction) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FilteringFunction.Merge(m, src)
+}
+func (m *FilteringFunction) XXX_Size() int {
+	return m.Size()
+}
+func (m *FilteringFunction) XXX_DiscardUnknown() {
+	xxx_messageInfo_FilteringFunction.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FilteringFunction proto.InternalMessageInfo
+
+func (m *FilteringFunction) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *FilteringFunction) GetArguments() []string {
+	if m != nil {
+		return m.Arguments
+	}
+	return nil
+}
+
+type FetchRequest struct {
+	Name                    string               `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	StartTime               int64                `protobuf:"varint,2,opt,name=startTime,proto3" json:"startTime,omitempty"`
+	StopTime                int64                `protobuf:"varint,3,opt,name=stopTime,proto3" json:"stopTime,omitempty"`
+	HighPrecisionTimestamps bool                 `protobuf:"varint,4,opt,name=highPrecisionTimestamps,proto3" json:"highPrecisionTimestamps,omitempty"`
+	PathExpression          string               `protobuf:"bytes,5,opt,name=pathExpression,proto3" json:"pathExpression,omitempty"`
+	FilterFunctions         []*FilteringFunction `protobuf:"bytes,6,rep,name=filterFunctions,proto3" json:"filterFunctions,omitempty"`
+	MaxDataPoints           int64                `protobuf:"varint,7,opt,name=maxDataPoints,proto3" json:"maxDataPoints,omitempty"`
+	XXX_NoUnkeyedLiteral    struct{}             `json:"-"`
+	XXX_unrecognized        []byte               `json:"-"`
+	XXX_sizecache           int32                `json:"-"`
+}
+
+func (m *FetchRequest) Reset()         { *m = FetchRequest{} }
+func (m *FetchRequest) String() string { return proto.CompactTextString(m) }
+func (*FetchRequest) ProtoMessage()    {}
+func (*FetchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{1}
+}
+func (m *FetchRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *FetchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_FetchRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *FetchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FetchRequest.Merge(m, src)
+}
+func (m *FetchRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *FetchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FetchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FetchRequest proto.InternalMessageInfo
+
+func (m *FetchRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *FetchRequest) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *FetchRequest) GetStopTime() int64 {
+	if m != nil {
+		return m.StopTime
+	}
+	return 0
+}
+
+func (m *FetchRequest) GetHighPrecisionTimestamps() bool {
+	if m != nil {
+		return m.HighPrecisionTimestamps
+	}
+	return false
+}
+
+func (m *FetchRequest) GetPathExpression() string {
+	if m != nil {
+		return m.PathExpression
+	}
+	return ""
+}
+
+func (m *FetchRequest) GetFilterFunctions() []*FilteringFunction {
+	if m != nil {
+		return m.FilterFunctions
+	}
+	return nil
+}
+
+func (m *FetchRequest) GetMaxDataPoints() int64 {
+	if m != nil {
+		return m.MaxDataPoints
+	}
+	return 0
+}
+
+type MultiFetchRequest struct {
+	Metrics              []FetchRequest `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *MultiFetchRequest) Reset()         { *m = MultiFetchRequest{} }
+func (m *MultiFetchRequest) String() string { return proto.CompactTextString(m) }
+func (*MultiFetchRequest) ProtoMessage()    {}
+func (*MultiFetchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{2}
+}
+func (m *MultiFetchRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MultiFetchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MultiFetchRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MultiFetchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiFetchRequest.Merge(m, src)
+}
+func (m *MultiFetchRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *MultiFetchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiFetchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiFetchRequest proto.InternalMessageInfo
+
+func (m *MultiFetchRequest) GetMetrics() []FetchRequest {
+	if m != nil {
+		return m.Metrics
+	}
+	return nil
+}
+
+type FetchResponse struct {
+	Name                    string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	PathExpression          string            `protobuf:"bytes,2,opt,name=pathExpression,proto3" json:"pathExpression,omitempty"`
+	ConsolidationFunc       string            `protobuf:"bytes,3,opt,name=consolidationFunc,proto3" json:"consolidationFunc,omitempty"`
+	StartTime               int64             `protobuf:"varint,4,opt,name=startTime,proto3" json:"startTime,omitempty"`
+	StopTime                int64             `protobuf:"varint,5,opt,name=stopTime,proto3" json:"stopTime,omitempty"`
+	StepTime                int64             `protobuf:"varint,6,opt,name=stepTime,proto3" json:"stepTime,omitempty"`
+	XFilesFactor            float32           `protobuf:"fixed32,7,opt,name=xFilesFactor,proto3" json:"xFilesFactor,omitempty"`
+	HighPrecisionTimestamps bool              `protobuf:"varint,8,opt,name=highPrecisionTimestamps,proto3" json:"highPrecisionTimestamps,omitempty"`
+	Values                  []float64         `protobuf:"fixed64,9,rep,packed,name=values,proto3" json:"values,omitempty"`
+	Tags                    map[string]string `protobuf:"bytes,10,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	AppliedFunctions        []string          `protobuf:"bytes,11,rep,name=appliedFunctions,proto3" json:"appliedFunctions,omitempty"`
+	RequestStartTime        int64             `protobuf:"varint,12,opt,name=requestStartTime,proto3" json:"requestStartTime,omitempty"`
+	RequestStopTime         int64             `protobuf:"varint,13,opt,name=requestStopTime,proto3" json:"requestStopTime,omitempty"`
+	XXX_NoUnkeyedLiteral    struct{}          `json:"-"`
+	XXX_unrecognized        []byte            `json:"-"`
+	XXX_sizecache           int32             `json:"-"`
+}
+
+func (m *FetchResponse) Reset()         { *m = FetchResponse{} }
+func (m *FetchResponse) String() string { return proto.CompactTextString(m) }
+func (*FetchResponse) ProtoMessage()    {}
+func (*FetchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{3}
+}
+func (m *FetchResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *FetchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_FetchResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *FetchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FetchResponse.Merge(m, src)
+}
+func (m *FetchResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *FetchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_FetchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FetchResponse proto.InternalMessageInfo
+
+func (m *FetchResponse) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *FetchResponse) GetPathExpression() string {
+	if m != nil {
+		return m.PathExpression
+	}
+	return ""
+}
+
+func (m *FetchResponse) GetConsolidationFunc() string {
+	if m != nil {
+		return m.ConsolidationFunc
+	}
+	return ""
+}
+
+func (m *FetchResponse) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *FetchResponse) GetStopTime() int64 {
+	if m != nil {
+		return m.StopTime
+	}
+	return 0
+}
+
+func (m *FetchResponse) GetStepTime() int64 {
+	if m != nil {
+		return m.StepTime
+	}
+	return 0
+}
+
+func (m *FetchResponse) GetXFilesFactor() float32 {
+	if m != nil {
+		return m.XFilesFactor
+	}
+	return 0
+}
+
+func (m *FetchResponse) GetHighPrecisionTimestamps() bool {
+	if m != nil {
+		return m.HighPrecisionTimestamps
+	}
+	return false
+}
+
+func (m *FetchResponse) GetValues() []float64 {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+func (m *FetchResponse) GetTags() map[string]string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *FetchResponse) GetAppliedFunctions() []string {
+	if m != nil {
+		return m.AppliedFunctions
+	}
+	return nil
+}
+
+func (m *FetchResponse) GetRequestStartTime() int64 {
+	if m != nil {
+		return m.RequestStartTime
+	}
+	return 0
+}
+
+func (m *FetchResponse) GetRequestStopTime() int64 {
+	if m != nil {
+		return m.RequestStopTime
+	}
+	return 0
+}
+
+type MultiFetchResponse struct {
+	Metrics              []FetchResponse `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *MultiFetchResponse) Reset()         { *m = MultiFetchResponse{} }
+func (m *MultiFetchResponse) String() string { return proto.CompactTextString(m) }
+func (*MultiFetchResponse) ProtoMessage()    {}
+func (*MultiFetchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{4}
+}
+func (m *MultiFetchResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MultiFetchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MultiFetchResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MultiFetchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiFetchResponse.Merge(m, src)
+}
+func (m *MultiFetchResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *MultiFetchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiFetchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiFetchResponse proto.InternalMessageInfo
+
+func (m *MultiFetchResponse) GetMetrics() []FetchResponse {
+	if m != nil {
+		return m.Metrics
+	}
+	return nil
+}
+
+type GlobMatch struct {
+	Path                 string   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	IsLeaf               bool     `protobuf:"varint,2,opt,name=isLeaf,proto3" json:"isLeaf,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GlobMatch) Reset()         { *m = GlobMatch{} }
+func (m *GlobMatch) String() string { return proto.CompactTextString(m) }
+func (*GlobMatch) ProtoMessage()    {}
+func (*GlobMatch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{5}
+}
+func (m *GlobMatch) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *GlobMatch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_GlobMatch.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *GlobMatch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GlobMatch.Merge(m, src)
+}
+func (m *GlobMatch) XXX_Size() int {
+	return m.Size()
+}
+func (m *GlobMatch) XXX_DiscardUnknown() {
+	xxx_messageInfo_GlobMatch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GlobMatch proto.InternalMessageInfo
+
+func (m *GlobMatch) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *GlobMatch) GetIsLeaf() bool {
+	if m != nil {
+		return m.IsLeaf
+	}
+	return false
+}
+
+type GlobResponse struct {
+	Name                 string      `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Matches              []GlobMatch `protobuf:"bytes,2,rep,name=matches,proto3" json:"matches"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *GlobResponse) Reset()         { *m = GlobResponse{} }
+func (m *GlobResponse) String() string { return proto.CompactTextString(m) }
+func (*GlobResponse) ProtoMessage()    {}
+func (*GlobResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{6}
+}
+func (m *GlobResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *GlobResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_GlobResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *GlobResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GlobResponse.Merge(m, src)
+}
+func (m *GlobResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *GlobResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GlobResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GlobResponse proto.InternalMessageInfo
+
+func (m *GlobResponse) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *GlobResponse) GetMatches() []GlobMatch {
+	if m != nil {
+		return m.Matches
+	}
+	return nil
+}
+
+type MultiGlobResponse struct {
+	Metrics              []GlobResponse `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *MultiGlobResponse) Reset()         { *m = MultiGlobResponse{} }
+func (m *MultiGlobResponse) String() string { return proto.CompactTextString(m) }
+func (*MultiGlobResponse) ProtoMessage()    {}
+func (*MultiGlobResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{7}
+}
+func (m *MultiGlobResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MultiGlobResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MultiGlobResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MultiGlobResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiGlobResponse.Merge(m, src)
+}
+func (m *MultiGlobResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *MultiGlobResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiGlobResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiGlobResponse proto.InternalMessageInfo
+
+func (m *MultiGlobResponse) GetMetrics() []GlobResponse {
+	if m != nil {
+		return m.Metrics
+	}
+	return nil
+}
+
+type MultiGlobRequest struct {
+	Metrics              []string `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	StartTime            int64    `protobuf:"varint,2,opt,name=startTime,proto3" json:"startTime,omitempty"`
+	StopTime             int64    `protobuf:"varint,3,opt,name=stopTime,proto3" json:"stopTime,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MultiGlobRequest) Reset()         { *m = MultiGlobRequest{} }
+func (m *MultiGlobRequest) String() string { return proto.CompactTextString(m) }
+func (*MultiGlobRequest) ProtoMessage()    {}
+func (*MultiGlobRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{8}
+}
+func (m *MultiGlobRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MultiGlobRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MultiGlobRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MultiGlobRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiGlobRequest.Merge(m, src)
+}
+func (m *MultiGlobRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *MultiGlobRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiGlobRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiGlobRequest proto.InternalMessageInfo
+
+func (m *MultiGlobRequest) GetMetrics() []string {
+	if m != nil {
+		return m.Metrics
+	}
+	return nil
+}
+
+func (m *MultiGlobRequest) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *MultiGlobRequest) GetStopTime() int64 {
+	if m != nil {
+		return m.StopTime
+	}
+	return 0
+}
+
+type Retention struct {
+	SecondsPerPoint      int64    `protobuf:"varint,1,opt,name=secondsPerPoint,proto3" json:"secondsPerPoint,omitempty"`
+	NumberOfPoints       int64    `protobuf:"varint,2,opt,name=numberOfPoints,proto3" json:"numberOfPoints,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Retention) Reset()         { *m = Retention{} }
+func (m *Retention) String() string { return proto.CompactTextString(m) }
+func (*Retention) ProtoMessage()    {}
+func (*Retention) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{9}
+}
+func (m *Retention) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Retention) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Retention.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Retention) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Retention.Merge(m, src)
+}
+func (m *Retention) XXX_Size() int {
+	return m.Size()
+}
+func (m *Retention) XXX_DiscardUnknown() {
+	xxx_messageInfo_Retention.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Retention proto.InternalMessageInfo
+
+func (m *Retention) GetSecondsPerPoint() int64 {
+	if m != nil {
+		return m.SecondsPerPoint
+	}
+	return 0
+}
+
+func (m *Retention) GetNumberOfPoints() int64 {
+	if m != nil {
+		return m.NumberOfPoints
+	}
+	return 0
+}
+
+type MetricsInfoResponse struct {
+	Name                 string      `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	ConsolidationFunc    string      `protobuf:"bytes,2,opt,name=consolidationFunc,proto3" json:"consolidationFunc,omitempty"`
+	XFilesFactor         float32     `protobuf:"fixed32,3,opt,name=xFilesFactor,proto3" json:"xFilesFactor,omitempty"`
+	MaxRetention         int64       `protobuf:"varint,4,opt,name=maxRetention,proto3" json:"maxRetention,omitempty"`
+	Retentions           []Retention `protobuf:"bytes,5,rep,name=retentions,proto3" json:"retentions"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *MetricsInfoResponse) Reset()         { *m = MetricsInfoResponse{} }
+func (m *MetricsInfoResponse) String() string { return proto.CompactTextString(m) }
+func (*MetricsInfoResponse) ProtoMessage()    {}
+func (*MetricsInfoResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{10}
+}
+func (m *MetricsInfoResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MetricsInfoResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MetricsInfoResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MetricsInfoResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MetricsInfoResponse.Merge(m, src)
+}
+func (m *MetricsInfoResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *MetricsInfoResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MetricsInfoResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MetricsInfoResponse proto.InternalMessageInfo
+
+func (m *MetricsInfoResponse) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *MetricsInfoResponse) GetConsolidationFunc() string {
+	if m != nil {
+		return m.ConsolidationFunc
+	}
+	return ""
+}
+
+func (m *MetricsInfoResponse) GetXFilesFactor() float32 {
+	if m != nil {
+		return m.XFilesFactor
+	}
+	return 0
+}
+
+func (m *MetricsInfoResponse) GetMaxRetention() int64 {
+	if m != nil {
+		return m.MaxRetention
+	}
+	return 0
+}
+
+func (m *MetricsInfoResponse) GetRetentions() []Retention {
+	if m != nil {
+		return m.Retentions
+	}
+	return nil
+}
+
+type MultiMetricsInfoResponse struct {
+	Metrics              []MetricsInfoResponse `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *MultiMetricsInfoResponse) Reset()         { *m = MultiMetricsInfoResponse{} }
+func (m *MultiMetricsInfoResponse) String() string { return proto.CompactTextString(m) }
+func (*MultiMetricsInfoResponse) ProtoMessage()    {}
+func (*MultiMetricsInfoResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{11}
+}
+func (m *MultiMetricsInfoResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MultiMetricsInfoResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MultiMetricsInfoResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MultiMetricsInfoResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiMetricsInfoResponse.Merge(m, src)
+}
+func (m *MultiMetricsInfoResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *MultiMetricsInfoResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiMetricsInfoResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiMetricsInfoResponse proto.InternalMessageInfo
+
+func (m *MultiMetricsInfoResponse) GetMetrics() []MetricsInfoResponse {
+	if m != nil {
+		return m.Metrics
+	}
+	return nil
+}
+
+type MultiMetricsInfoRequest struct {
+	Names                []string `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MultiMetricsInfoRequest) Reset()         { *m = MultiMetricsInfoRequest{} }
+func (m *MultiMetricsInfoRequest) String() string { return proto.CompactTextString(m) }
+func (*MultiMetricsInfoRequest) ProtoMessage()    {}
+func (*MultiMetricsInfoRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_aa81c5198068fa1d, []int{12}
+}
+func (m *MultiMetricsInfoRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MultiMetricsInfoRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MultiMetricsInfoRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MultiMetricsInfoRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiMetricsInfoRequest.Merge(m, src)
+}
+func (m *MultiMetricsInfoRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *MultiMetricsInfoRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiMetricsInfoRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiMetricsInfoRequest proto.InternalMessageInfo
+
+func (m *MultiMetricsInfoRequest) GetNames() []string {
+	if m != nil {
+		return m.Names
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*FilteringFunction)(nil), "carbonapi_v3_pb.FilteringFunction")
+	proto.RegisterType((*FetchRequest)(nil), "carbonapi_v3_pb.FetchRequest")
+	proto.RegisterType((*MultiFetchRequest)(nil), "carbonapi_v3_pb.MultiFetchRequest")
+	proto.RegisterType((*FetchResponse)(nil), "carbonapi_v3_pb.FetchResponse")
+	proto.RegisterMapType((map[string]string)(nil), "carbonapi_v3_pb.FetchResponse.TagsEntry")
+	proto.RegisterType((*MultiFetchResponse)(nil), "carbonapi_v3_pb.MultiFetchResponse")
+	proto.RegisterType((*GlobMatch)(nil), "carbonapi_v3_pb.GlobMatch")
+	proto.RegisterType((*GlobResponse)(nil), "carbonapi_v3_pb.GlobResponse")
+	proto.RegisterType((*MultiGlobResponse)(nil), "carbonapi_v3_pb.MultiGlobResponse")
+	proto.RegisterType((*MultiGlobRequest)(nil), "carbonapi_v3_pb.MultiGlobRequest")
+	proto.RegisterType((*Retention)(nil), "carbonapi_v3_pb.Retention")
+	proto.RegisterType((*MetricsInfoResponse)(nil), "carbonapi_v3_pb.MetricsInfoResponse")
+	proto.RegisterType((*MultiMetricsInfoResponse)(nil), "carbonapi_v3_pb.MultiMetricsInfoResponse")
+	proto.RegisterType((*MultiMetricsInfoRequest)(nil), "carbonapi_v3_pb.MultiMetricsInfoRequest")
+}
+
+func init() { proto.RegisterFile("carbonapi_v3_pb.proto", fileDescriptor_aa81c5198068fa1d) }
+
+var fileDescriptor_aa81c5198068fa1d = []byte{
+	// 767 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x55, 0xd1, 0x4e, 0xdb, 0x48,
+	0x14, 0x5d, 0xc7, 0x49, 0x88, 0x2f, 0x61, 0x03, 0xb3, 0xec, 0x62, 0x45, 0xbb, 0xd9, 0xc8, 0x42,
+	0x2b, 0x6b, 0xb5, 0x1b, 0x24, 0x78, 0x00, 0xa1, 0xb6, 0xaa, 0x10, 0xa4, 0xaa, 0x04, 0x2a, 0x9a,
+	0xf2, 0xda, 0xd2, 0x89, 0x99, 0x38, 0xa3, 0xc6, 0x1e, 0xd7, 0x33, 0x46, 0xe1, 0xb7, 0xfa, 0x01,
+	0x7d, 0xe6, 0xb1, 0x5f, 0x50, 0xb5, 0x7c, 0x49, 0x35, 0x63, 0xc7, 0x24, 0x76, 0x12, 0x55, 0x7d,
+	0xf3, 0x3d, 0x77, 0xee, 0xcc, 0x9d, 0x73, 0xce, 0x5c, 0xc3, 0xef, 0x1e, 0x89, 0x07, 0x3c, 0x24,
+	0x11, 0xbb, 0xbe, 0x3d, 0xb8, 0x8e, 0x06, 0xbd, 0x28, 0xe6, 0x92, 0xa3, 0x56, 0x01, 0x6e, 0xff,
+	0xef, 0x33, 0x39, 0x4a, 0x06, 0x3d, 0x8f, 0x07, 0x7b, 0x3e, 0xf7, 0xf9, 0x9e, 0x5e, 0x37, 0x48,
+	0x86, 0x3a, 0xd2, 0x81, 0xfe, 0x4a, 0xeb, 0x9d, 0x33, 0xd8, 0xea, 0xb3, 0xb1, 0xa4, 0x31, 0x0b,
+	0xfd, 0x7e, 0x12, 0x7a, 0x92, 0xf1, 0x10, 0x21, 0xa8, 0x86, 0x24, 0xa0, 0xb6, 0xd1, 0x35, 0x5c,
+	0x0b, 0xeb, 0x6f, 0xf4, 0x27, 0x58, 0x24, 0xf6, 0x93, 0x80, 0x86, 0x52, 0xd8, 0x95, 0xae, 0xe9,
+	0x5a, 0xf8, 0x11, 0x70, 0x3e, 0x56, 0xa0, 0xd9, 0xa7, 0xd2, 0x1b, 0x61, 0xfa, 0x21, 0xa1, 0x42,
+	0x2e, 0xdb, 0x42, 0x48, 0x12, 0xcb, 0x2b, 0x16, 0x50, 0xbb, 0xd2, 0x35, 0x5c, 0x13, 0x3f, 0x02,
+	0xa8, 0x0d, 0x0d, 0x21, 0x79, 0xa4, 0x93, 0xa6, 0x4e, 0xe6, 0x31, 0x3a, 0x82, 0x9d, 0x11, 0xf3,
+	0x47, 0x97, 0x31, 0xf5, 0x98, 0x60, 0x3c, 0x54, 0xa0, 0x90, 0x24, 0x88, 0x84, 0x5d, 0xed, 0x1a,
+	0x6e, 0x03, 0x2f, 0x4b, 0xa3, 0x7f, 0xe0, 0xd7, 0x88, 0xc8, 0xd1, 0xd9, 0x24, 0x8a, 0xa9, 0x50,
+	0x39, 0xbb, 0xa6, 0x3b, 0x2a, 0xa0, 0xe8, 0x1c, 0x5a, 0x43, 0xcd, 0xc3, 0x94, 0x04, 0x61, 0xd7,
+	0xbb, 0xa6, 0xbb, 0xbe, 0xef, 0xf4, 0x8a, 0xc4, 0x97, 0xf8, 0xc2, 0xc5, 0x52, 0xb4, 0x0b, 0x1b,
+	0x01, 0x99, 0x9c, 0x12, 0x49, 0x2e, 0x39, 0x53, 0x84, 0xad, 0xe9, 0x0b, 0xcd, 0x83, 0x0e, 0x86,
+	0xad, 0x8b, 0x64, 0x2c, 0xd9, 0x1c, 0x71, 0x4f, 0x61, 0x2d, 0xa0, 0x32, 0x66, 0x9e, 0xb0, 0x0d,
+	0xdd, 0xc0, 0x5f, 0xe5, 0x06, 0x66, 0xd6, 0x9f, 0x54, 0xef, 0xbf, 0xfc, 0xfd, 0x0b, 0x9e, 0xd6,
+	0x38, 0x9f, 0xaa, 0xb0, 0x91, 0xe5, 0x45, 0xc4, 0x43, 0x41, 0x17, 0x2a, 0x51, 0x66, 0xa5, 0xb2,
+	0x90, 0x95, 0xff, 0x60, 0xcb, 0xe3, 0xa1, 0xe0, 0x63, 0x76, 0x43, 0xd4, 0xcd, 0xd4, 0x0d, 0xb5,
+	0x38, 0x16, 0x2e, 0x27, 0xe6, 0xf5, 0xad, 0xae, 0xd2, 0xb7, 0x56, 0xd0, 0x57, 0xe7, 0x68, 0x9a,
+	0xab, 0x4f, 0x73, 0x69, 0x8c, 0x1c, 0x68, 0x4e, 0xfa, 0x6c, 0x4c, 0x45, 0x9f, 0x78, 0x92, 0xc7,
+	0x9a, 0xca, 0x0a, 0x9e, 0xc3, 0x56, 0xf9, 0xa3, 0xb1, 0xda, 0x1f, 0x7f, 0x40, 0xfd, 0x96, 0x8c,
+	0x13, 0x2a, 0x6c, 0xab, 0x6b, 0xba, 0x06, 0xce, 0x22, 0xf4, 0x04, 0xaa, 0x92, 0xf8, 0xc2, 0x06,
+	0xad, 0x81, 0xbb, 0x4c, 0x83, 0x94, 0xe3, 0xde, 0x15, 0xf1, 0xc5, 0x59, 0x28, 0xe3, 0x3b, 0xac,
+	0xab, 0xd0, 0xbf, 0xb0, 0x49, 0xa2, 0x68, 0xcc, 0xe8, 0xcd, 0xa3, 0x9d, 0xd6, 0xf5, 0x9b, 0x29,
+	0xe1, 0x6a, 0x6d, 0x9c, 0x6a, 0xf9, 0x3a, 0x27, 0xaf, 0xa9, 0x39, 0x28, 0xe1, 0xc8, 0x85, 0x56,
+	0x8e, 0x65, 0x54, 0x6e, 0xe8, 0xa5, 0x45, 0xb8, 0x7d, 0x08, 0x56, 0xde, 0x14, 0xda, 0x04, 0xf3,
+	0x3d, 0xbd, 0xcb, 0x1c, 0xa0, 0x3e, 0xd1, 0x36, 0xd4, 0xf4, 0x45, 0x33, 0xdd, 0xd3, 0xe0, 0xb8,
+	0x72, 0x64, 0x38, 0x57, 0x80, 0x66, 0x4d, 0x99, 0x99, 0xe8, 0x59, 0xd1, 0x95, 0x9d, 0xd5, 0x8c,
+	0x14, 0x6d, 0x79, 0x08, 0xd6, 0x8b, 0x31, 0x1f, 0x5c, 0x10, 0xe9, 0x8d, 0x94, 0x23, 0x95, 0xcf,
+	0xa6, 0x8e, 0x54, 0xdf, 0x4a, 0x07, 0x26, 0xce, 0x29, 0x19, 0xea, 0x8e, 0x1a, 0x38, 0x8b, 0x9c,
+	0xb7, 0xd0, 0x54, 0x85, 0x2b, 0xdd, 0x7c, 0x0c, 0x6b, 0x81, 0xda, 0x98, 0xa6, 0x83, 0x69, 0x7d,
+	0xbf, 0x5d, 0x6a, 0x2e, 0x3f, 0x3c, 0x6f, 0x2c, 0x2d, 0xc8, 0xdf, 0xe0, 0xdc, 0x21, 0x3f, 0xf0,
+	0x06, 0x67, 0xd7, 0x17, 0x2f, 0x3b, 0x84, 0xcd, 0x99, 0x3d, 0xd3, 0x67, 0x6d, 0xcf, 0x6f, 0x69,
+	0xe5, 0xab, 0x7f, 0x7e, 0x2a, 0x3a, 0x6f, 0xc0, 0xc2, 0x54, 0xd2, 0x50, 0xcf, 0x6c, 0x17, 0x5a,
+	0x82, 0x7a, 0x3c, 0xbc, 0x11, 0x97, 0x34, 0xd6, 0x03, 0x46, 0x73, 0x64, 0xe2, 0x22, 0xac, 0x1e,
+	0x7f, 0x98, 0x04, 0x03, 0x1a, 0xbf, 0x1a, 0x66, 0xd3, 0x29, 0x3d, 0xb5, 0x80, 0x3a, 0xdf, 0x0c,
+	0xf8, 0xed, 0x22, 0x6d, 0xf2, 0x65, 0x38, 0xe4, 0x2b, 0x25, 0x58, 0x38, 0x28, 0x2a, 0xcb, 0x06,
+	0x45, 0xf1, 0x49, 0x9b, 0x0b, 0x9e, 0xb4, 0x03, 0xcd, 0x80, 0x4c, 0xf2, 0xfb, 0x65, 0xf3, 0x64,
+	0x0e, 0x43, 0xcf, 0x01, 0xe2, 0x69, 0x20, 0xec, 0xda, 0x12, 0xed, 0xf3, 0xf5, 0x99, 0x4e, 0x33,
+	0x35, 0xce, 0x3b, 0xb0, 0xb5, 0x54, 0x8b, 0xee, 0x79, 0x5a, 0x74, 0xc1, 0x6e, 0x69, 0xeb, 0x05,
+	0x65, 0x45, 0x33, 0xec, 0xc1, 0x4e, 0xf9, 0x84, 0xd4, 0x13, 0xdb, 0x50, 0x53, 0xe4, 0x4d, 0x1d,
+	0x91, 0x06, 0x27, 0xcd, 0xfb, 0x87, 0x8e, 0xf1, 0xf9, 0xa1, 0x63, 0x7c, 0x7d, 0xe8, 0x18, 0x83,
+	0xba, 0xfe, 0x4d, 0x1f, 0x7c, 0x1f, 0x00, 0x86, 0xa4, 0xff, 0x21, 0xff, 0x07, 0x00, 0x00,
+}
+
+func (m *FilteringFunction) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *FilteringFunction) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *FilteringFunction) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Arguments) > 0 {
+		for iNdEx := len(m.Arguments) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Arguments[iNdEx])
+			copy(dAtA[i:], m.Arguments[iNdEx])
+			i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.Arguments[iNdEx])))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *FetchRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *FetchRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *FetchRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.MaxDataPoints != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.MaxDataPoints))
+		i--
+		dAtA[i] = 0x38
+	}
+	if len(m.FilterFunctions) > 0 {
+		for iNdEx := len(m.FilterFunctions) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.FilterFunctions[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x32
+		}
+	}
+	if len(m.PathExpression) > 0 {
+		i -= len(m.PathExpression)
+		copy(dAtA[i:], m.PathExpression)
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.PathExpression)))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if m.HighPrecisionTimestamps {
+		i--
+		if m.HighPrecisionTimestamps {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.StopTime != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.StopTime))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.StartTime != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.StartTime))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MultiFetchRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MultiFetchRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MultiFetchRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Metrics) > 0 {
+		for iNdEx := len(m.Metrics) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Metrics[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *FetchResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *FetchResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *FetchResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.RequestStopTime != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.RequestStopTime))
+		i--
+		dAtA[i] = 0x68
+	}
+	if m.RequestStartTime != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.RequestStartTime))
+		i--
+		dAtA[i] = 0x60
+	}
+	if len(m.AppliedFunctions) > 0 {
+		for iNdEx := len(m.AppliedFunctions) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.AppliedFunctions[iNdEx])
+			copy(dAtA[i:], m.AppliedFunctions[iNdEx])
+			i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.AppliedFunctions[iNdEx])))
+			i--
+			dAtA[i] = 0x5a
+		}
+	}
+	if len(m.Tags) > 0 {
+		for k := range m.Tags {
+			v := m.Tags[k]
+			baseI := i
+			i -= len(v)
+			copy(dAtA[i:], v)
+			i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(v)))
+			i--
+			dAtA[i] = 0x12
+			i -= len(k)
+			copy(dAtA[i:], k)
+			i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(k)))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x52
+		}
+	}
+	if len(m.Values) > 0 {
+		for iNdEx := len(m.Values) - 1; iNdEx >= 0; iNdEx-- {
+			f1 := math.Float64bits(float64(m.Values[iNdEx]))
+			i -= 8
+			encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(f1))
+		}
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.Values)*8))
+		i--
+		dAtA[i] = 0x4a
+	}
+	if m.HighPrecisionTimestamps {
+		i--
+		if m.HighPrecisionTimestamps {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.XFilesFactor != 0 {
+		i -= 4
+		encoding_binary.LittleEndian.PutUint32(dAtA[i:], uint32(math.Float32bits(float32(m.XFilesFactor))))
+		i--
+		dAtA[i] = 0x3d
+	}
+	if m.StepTime != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.StepTime))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.StopTime != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.StopTime))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.StartTime != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.StartTime))
+		i--
+		dAtA[i] = 0x20
+	}
+	if len(m.ConsolidationFunc) > 0 {
+		i -= len(m.ConsolidationFunc)
+		copy(dAtA[i:], m.ConsolidationFunc)
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.ConsolidationFunc)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if len(m.PathExpression) > 0 {
+		i -= len(m.PathExpression)
+		copy(dAtA[i:], m.PathExpression)
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.PathExpression)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MultiFetchResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MultiFetchResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MultiFetchResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Metrics) > 0 {
+		for iNdEx := len(m.Metrics) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Metrics[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *GlobMatch) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *GlobMatch) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *GlobMatch) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.IsLeaf {
+		i--
+		if m.IsLeaf {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Path) > 0 {
+		i -= len(m.Path)
+		copy(dAtA[i:], m.Path)
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.Path)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *GlobResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *GlobResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *GlobResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Matches) > 0 {
+		for iNdEx := len(m.Matches) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Matches[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MultiGlobResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MultiGlobResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MultiGlobResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Metrics) > 0 {
+		for iNdEx := len(m.Metrics) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Metrics[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MultiGlobRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MultiGlobRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MultiGlobRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.StopTime != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.StopTime))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.StartTime != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.StartTime))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Metrics) > 0 {
+		for iNdEx := len(m.Metrics) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Metrics[iNdEx])
+			copy(dAtA[i:], m.Metrics[iNdEx])
+			i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.Metrics[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Retention) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Retention) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Retention) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.NumberOfPoints != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.NumberOfPoints))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.SecondsPerPoint != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.SecondsPerPoint))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MetricsInfoResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MetricsInfoResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MetricsInfoResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Retentions) > 0 {
+		for iNdEx := len(m.Retentions) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Retentions[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x2a
+		}
+	}
+	if m.MaxRetention != 0 {
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(m.MaxRetention))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.XFilesFactor != 0 {
+		i -= 4
+		encoding_binary.LittleEndian.PutUint32(dAtA[i:], uint32(math.Float32bits(float32(m.XFilesFactor))))
+		i--
+		dAtA[i] = 0x1d
+	}
+	if len(m.ConsolidationFunc) > 0 {
+		i -= len(m.ConsolidationFunc)
+		copy(dAtA[i:], m.ConsolidationFunc)
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.ConsolidationFunc)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MultiMetricsInfoResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MultiMetricsInfoResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MultiMetricsInfoResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Metrics) > 0 {
+		for iNdEx := len(m.Metrics) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Metrics[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MultiMetricsInfoRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MultiMetricsInfoRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MultiMetricsInfoRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Names) > 0 {
+		for iNdEx := len(m.Names) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Names[iNdEx])
+			copy(dAtA[i:], m.Names[iNdEx])
+			i = encodeVarintCarbonapiV3Pb(dAtA, i, uint64(len(m.Names[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintCarbonapiV3Pb(dAtA []byte, offset int, v uint64) int {
+	offset -= sovCarbonapiV3Pb(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *FilteringFunction) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+	}
+	if len(m.Arguments) > 0 {
+		for _, s := range m.Arguments {
+			l = len(s)
+			n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *FetchRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+	}
+	if m.StartTime != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.StartTime))
+	}
+	if m.StopTime != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.StopTime))
+	}
+	if m.HighPrecisionTimestamps {
+		n += 2
+	}
+	l = len(m.PathExpression)
+	if l > 0 {
+		n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+	}
+	if len(m.FilterFunctions) > 0 {
+		for _, e := range m.FilterFunctions {
+			l = e.Size()
+			n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+		}
+	}
+	if m.MaxDataPoints != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.MaxDataPoints))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *MultiFetchRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Metrics) > 0 {
+		for _, e := range m.Metrics {
+			l = e.Size()
+			n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *FetchResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+	}
+	l = len(m.PathExpression)
+	if l > 0 {
+		n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+	}
+	l = len(m.ConsolidationFunc)
+	if l > 0 {
+		n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+	}
+	if m.StartTime != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.StartTime))
+	}
+	if m.StopTime != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.StopTime))
+	}
+	if m.StepTime != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.StepTime))
+	}
+	if m.XFilesFactor != 0 {
+		n += 5
+	}
+	if m.HighPrecisionTimestamps {
+		n += 2
+	}
+	if len(m.Values) > 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(len(m.Values)*8)) + len(m.Values)*8
+	}
+	if len(m.Tags) > 0 {
+		for k, v := range m.Tags {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovCarbonapiV3Pb(uint64(len(k))) + 1 + len(v) + sovCarbonapiV3Pb(uint64(len(v)))
+			n += mapEntrySize + 1 + sovCarbonapiV3Pb(uint64(mapEntrySize))
+		}
+	}
+	if len(m.AppliedFunctions) > 0 {
+		for _, s := range m.AppliedFunctions {
+			l = len(s)
+			n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+		}
+	}
+	if m.RequestStartTime != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.RequestStartTime))
+	}
+	if m.RequestStopTime != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.RequestStopTime))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *MultiFetchResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Metrics) > 0 {
+		for _, e := range m.Metrics {
+			l = e.Size()
+			n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *GlobMatch) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Path)
+	if l > 0 {
+		n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+	}
+	if m.IsLeaf {
+		n += 2
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *GlobResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+	}
+	if len(m.Matches) > 0 {
+		for _, e := range m.Matches {
+			l = e.Size()
+			n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *MultiGlobResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Metrics) > 0 {
+		for _, e := range m.Metrics {
+			l = e.Size()
+			n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *MultiGlobRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Metrics) > 0 {
+		for _, s := range m.Metrics {
+			l = len(s)
+			n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+		}
+	}
+	if m.StartTime != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.StartTime))
+	}
+	if m.StopTime != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.StopTime))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Retention) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.SecondsPerPoint != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.SecondsPerPoint))
+	}
+	if m.NumberOfPoints != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.NumberOfPoints))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *MetricsInfoResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+	}
+	l = len(m.ConsolidationFunc)
+	if l > 0 {
+		n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+	}
+	if m.XFilesFactor != 0 {
+		n += 5
+	}
+	if m.MaxRetention != 0 {
+		n += 1 + sovCarbonapiV3Pb(uint64(m.MaxRetention))
+	}
+	if len(m.Retentions) > 0 {
+		for _, e := range m.Retentions {
+			l = e.Size()
+			n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *MultiMetricsInfoResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Metrics) > 0 {
+		for _, e := range m.Metrics {
+			l = e.Size()
+			n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *MultiMetricsInfoRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Names) > 0 {
+		for _, s := range m.Names {
+			l = len(s)
+			n += 1 + l + sovCarbonapiV3Pb(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func sovCarbonapiV3Pb(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozCarbonapiV3Pb(x uint64) (n int) {
+	return sovCarbonapiV3Pb(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *FilteringFunction) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FilteringFunction: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FilteringFunction: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Arguments", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Arguments = append(m.Arguments, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *FetchRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FetchRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FetchRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartTime", wireType)
+			}
+			m.StartTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StartTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StopTime", wireType)
+			}
+			m.StopTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StopTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field HighPrecisionTimestamps", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.HighPrecisionTimestamps = bool(v != 0)
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PathExpression", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.PathExpression = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FilterFunctions", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.FilterFunctions = append(m.FilterFunctions, &FilteringFunction{})
+			if err := m.FilterFunctions[len(m.FilterFunctions)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxDataPoints", wireType)
+			}
+			m.MaxDataPoints = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxDataPoints |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MultiFetchRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MultiFetchRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MultiFetchRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metrics", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Metrics = append(m.Metrics, FetchRequest{})
+			if err := m.Metrics[len(m.Metrics)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *FetchResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FetchResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FetchResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PathExpression", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.PathExpression = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ConsolidationFunc", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ConsolidationFunc = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartTime", wireType)
+			}
+			m.StartTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StartTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StopTime", wireType)
+			}
+			m.StopTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StopTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StepTime", wireType)
+			}
+			m.StepTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StepTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 5 {
+				return fmt.Errorf("proto: wrong wireType = %d for field XFilesFactor", wireType)
+			}
+			var v uint32
+			if (iNdEx + 4) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint32(encoding_binary.LittleEndian.Uint32(dAtA[iNdEx:]))
+			iNdEx += 4
+			m.XFilesFactor = float32(math.Float32frombits(v))
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field HighPrecisionTimestamps", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.HighPrecisionTimestamps = bool(v != 0)
+		case 9:
+			if wireType == 1 {
+				var v uint64
+				if (iNdEx + 8) > l {
+					return io.ErrUnexpectedEOF
+				}
+				v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+				iNdEx += 8
+				v2 := float64(math.Float64frombits(v))
+				m.Values = append(m.Values, v2)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowCarbonapiV3Pb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthCarbonapiV3Pb
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthCarbonapiV3Pb
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				elementCount = packedLen / 8
+				if elementCount != 0 && len(m.Values) == 0 {
+					m.Values = make([]float64, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					if (iNdEx + 8) > l {
+						return io.ErrUnexpectedEOF
+					}
+					v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+					iNdEx += 8
+					v2 := float64(math.Float64frombits(v))
+					m.Values = append(m.Values, v2)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Values", wireType)
+			}
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Tags", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Tags == nil {
+				m.Tags = make(map[string]string)
+			}
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowCarbonapiV3Pb
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowCarbonapiV3Pb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthCarbonapiV3Pb
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthCarbonapiV3Pb
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowCarbonapiV3Pb
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthCarbonapiV3Pb
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthCarbonapiV3Pb
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthCarbonapiV3Pb
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Tags[mapkey] = mapvalue
+			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AppliedFunctions", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.AppliedFunctions = append(m.AppliedFunctions, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RequestStartTime", wireType)
+			}
+			m.RequestStartTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.RequestStartTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RequestStopTime", wireType)
+			}
+			m.RequestStopTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.RequestStopTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MultiFetchResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MultiFetchResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MultiFetchResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metrics", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Metrics = append(m.Metrics, FetchResponse{})
+			if err := m.Metrics[len(m.Metrics)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *GlobMatch) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: GlobMatch: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: GlobMatch: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Path", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Path = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IsLeaf", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.IsLeaf = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *GlobResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: GlobResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: GlobResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Matches", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Matches = append(m.Matches, GlobMatch{})
+			if err := m.Matches[len(m.Matches)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MultiGlobResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MultiGlobResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MultiGlobResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metrics", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Metrics = append(m.Metrics, GlobResponse{})
+			if err := m.Metrics[len(m.Metrics)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MultiGlobRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MultiGlobRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MultiGlobRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metrics", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Metrics = append(m.Metrics, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartTime", wireType)
+			}
+			m.StartTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StartTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StopTime", wireType)
+			}
+			m.StopTime = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StopTime |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Retention) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Retention: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Retention: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SecondsPerPoint", wireType)
+			}
+			m.SecondsPerPoint = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SecondsPerPoint |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NumberOfPoints", wireType)
+			}
+			m.NumberOfPoints = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.NumberOfPoints |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MetricsInfoResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MetricsInfoResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MetricsInfoResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ConsolidationFunc", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ConsolidationFunc = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 5 {
+				return fmt.Errorf("proto: wrong wireType = %d for field XFilesFactor", wireType)
+			}
+			var v uint32
+			if (iNdEx + 4) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint32(encoding_binary.LittleEndian.Uint32(dAtA[iNdEx:]))
+			iNdEx += 4
+			m.XFilesFactor = float32(math.Float32frombits(v))
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxRetention", wireType)
+			}
+			m.MaxRetention = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxRetention |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Retentions", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Retentions = append(m.Retentions, Retention{})
+			if err := m.Retentions[len(m.Retentions)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MultiMetricsInfoResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MultiMetricsInfoResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MultiMetricsInfoResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metrics", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Metrics = append(m.Metrics, MetricsInfoResponse{})
+			if err := m.Metrics[len(m.Metrics)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MultiMetricsInfoRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MultiMetricsInfoRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MultiMetricsInfoRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Names", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Names = append(m.Names, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipCarbonapiV3Pb(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthCarbonapiV3Pb
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipCarbonapiV3Pb(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowCarbonapiV3Pb
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowCarbonapiV3Pb
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthCarbonapiV3Pb
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupCarbonapiV3Pb
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthCarbonapiV3Pb
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthCarbonapiV3Pb        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowCarbonapiV3Pb          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupCarbonapiV3Pb = fmt.Errorf("proto: unexpected end of group")
+)
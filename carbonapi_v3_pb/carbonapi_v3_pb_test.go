@@ -0,0 +1,130 @@
+package carbonapi_v3_pb
+
+import (
+	"math"
+	"testing"
+
+	proto "github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalFetch(t *testing.T) {
+	assert := assert.New(t)
+
+	response := &MultiFetchResponse{
+		Metrics: []FetchResponse{
+			{
+				Name:              "a.b",
+				PathExpression:    "a.*",
+				ConsolidationFunc: "avg",
+				StartTime:         60,
+				StopTime:          180,
+				StepTime:          60,
+				Values:            []float64{1, math.NaN(), 3},
+				RequestStartTime:  50,
+				RequestStopTime:   200,
+			},
+		},
+	}
+
+	body, err := proto.Marshal(response)
+	assert.NoError(err)
+
+	var decoded MultiFetchResponse
+	assert.NoError(proto.Unmarshal(body, &decoded))
+	if assert.Len(decoded.Metrics, 1) {
+		m := decoded.Metrics[0]
+		assert.Equal("a.b", m.Name)
+		assert.Equal("a.*", m.PathExpression)
+		assert.Equal("avg", m.ConsolidationFunc)
+		assert.Equal(int64(60), m.StepTime)
+		assert.Equal(int64(200), m.RequestStopTime)
+		assert.Len(m.Values, 3)
+		assert.True(math.IsNaN(m.Values[1]))
+	}
+
+	// field 1 (metrics), then field 1 of FetchResponse (name)
+	assert.Equal([]byte{0x0a}, body[:1])
+}
+
+func TestMarshalRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	request := &MultiFetchRequest{
+		Metrics: []FetchRequest{
+			{Name: "a.*", StartTime: 1, StopTime: 2, PathExpression: "a.*"},
+			{Name: "b", StartTime: 3, StopTime: 4, FilterFunctions: []*FilteringFunction{{Name: "f", Arguments: []string{"1"}}}},
+		},
+	}
+
+	body, err := proto.Marshal(request)
+	assert.NoError(err)
+
+	var decoded MultiFetchRequest
+	assert.NoError(proto.Unmarshal(body, &decoded))
+	assert.Equal(request.Metrics, decoded.Metrics)
+
+	glob := &MultiGlobRequest{Metrics: []string{"a.*", "b.*"}, StartTime: 10}
+	body, err = proto.Marshal(glob)
+	assert.NoError(err)
+	// metrics: tag 1, wire type 2, length 3, "a.*"
+	assert.Equal([]byte{0x0a, 3, 'a', '.', '*'}, body[:5])
+}
+
+// Fixtures are encoded by field numbers of go-graphite/protocol carbonapi_v3_pb.proto
+// as carbonapi sends request and expects response
+func TestDecodeFixture(t *testing.T) {
+	assert := assert.New(t)
+
+	request := []byte{
+		0x0a, 0x12, // metrics, length 18
+		0x0a, 0x03, 'a', '.', '*', // name
+		0x10, 0xe8, 0x07, // startTime 1000
+		0x18, 0xf2, 0x07, // stopTime 1010
+		0x2a, 0x03, 'a', '.', '*', // pathExpression
+		0x38, 0x64, // maxDataPoints 100
+	}
+
+	var fetch MultiFetchRequest
+	assert.NoError(proto.Unmarshal(request, &fetch))
+	assert.Equal([]FetchRequest{
+		{Name: "a.*", StartTime: 1000, StopTime: 1010, PathExpression: "a.*", MaxDataPoints: 100},
+	}, fetch.Metrics)
+
+	response := []byte{
+		0x0a, 0x39, // metrics, length 57
+		0x0a, 0x03, 'a', '.', 'b', // name
+		0x1a, 0x03, 'a', 'v', 'g', // consolidationFunc
+		0x20, 0xe8, 0x07, // startTime 1000
+		0x28, 0xfc, 0x07, // stopTime 1020
+		0x30, 0x0a, // stepTime 10
+		0x3d, 0x00, 0x00, 0x00, 0x3f, // xFilesFactor 0.5
+		0x4a, 0x10, // values, packed
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f, // 1
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf8, 0x7f, // NaN
+		0x52, 0x0b, 0x0a, 0x04, 'n', 'a', 'm', 'e', 0x12, 0x03, 'a', '.', 'b', // tags
+		0x60, 0xe8, 0x07, // requestStartTime 1000
+	}
+
+	var multi MultiFetchResponse
+	assert.NoError(proto.Unmarshal(response, &multi))
+	if assert.Len(multi.Metrics, 1) {
+		m := multi.Metrics[0]
+		assert.Equal("a.b", m.Name)
+		assert.Equal("avg", m.ConsolidationFunc)
+		assert.Equal(int64(1000), m.StartTime)
+		assert.Equal(int64(1020), m.StopTime)
+		assert.Equal(int64(10), m.StepTime)
+		assert.Equal(float32(0.5), m.XFilesFactor)
+		if assert.Len(m.Values, 2) {
+			assert.Equal(float64(1), m.Values[0])
+			assert.True(math.IsNaN(m.Values[1]))
+		}
+		assert.Equal(map[string]string{"name": "a.b"}, m.Tags)
+		assert.Equal(int64(1000), m.RequestStartTime)
+	}
+
+	body, err := proto.Marshal(&multi)
+	assert.NoError(err)
+	assert.Equal(response, body)
+}
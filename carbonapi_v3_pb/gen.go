@@ -0,0 +1,3 @@
+package carbonapi_v3_pb
+
+//go:generate protoc -I=. -I=../vendor -I=../vendor/github.com/gogo/protobuf/protobuf --gogofast_out=. carbonapi_v3_pb.proto
@@ -43,30 +43,79 @@ type Common struct {
 	// MetricPrefix   string    `toml:"metric-prefix"`
 	// MetricInterval *Duration `toml:"metric-interval"`
 	// MetricEndpoint string    `toml:"metric-endpoint"`
-	MaxCPU          int              `toml:"max-cpu"`
-	TargetBlacklist []string         `toml:"target-blacklist"`
-	Blacklist       []*regexp.Regexp `toml:"-"` // compiled TargetBlacklist
+	MaxCPU                   int              `toml:"max-cpu"`
+	TargetBlacklist          []string         `toml:"target-blacklist"`
+	Blacklist                []*regexp.Regexp `toml:"-"` // compiled TargetBlacklist
+	MaxMetricsInFindAnswer   int              `toml:"max-metrics-in-find-answer"`
+	MaxMetricsInRenderAnswer int              `toml:"max-metrics-in-render-answer"`
+	MaxPointsInRenderAnswer  int              `toml:"max-points-in-render-answer"`
 }
 
 type ClickHouse struct {
 	Url              string    `toml:"url"`
 	DataTable        string    `toml:"data-table"`
 	DataTimeout      *Duration `toml:"data-timeout"`
+	DataChunks       int       `toml:"data-chunks"`
+	DataChunkBy      string    `toml:"data-chunk-by"`
+	DataConcurrency  int       `toml:"data-concurrency"`
+	TreeSubquery     bool      `toml:"tree-subquery"`
 	TreeTable        string    `toml:"tree-table"`
 	ReverseTreeTable string    `toml:"reverse-tree-table"`
 	TreeTimeout      *Duration `toml:"tree-timeout"`
 	TagTable         string    `toml:"tag-table"`
+	TaggedTable      string    `toml:"tagged-table"`
 	RollupConf       string    `toml:"rollup-conf"`
 	ExtraPrefix      string    `toml:"extra-prefix"`
 }
 
+const (
+	ChunkByCount = "count"
+	ChunkByHash  = "hash"
+)
+
 type Tags struct {
 	Rules      string `toml:"rules"`
 	Date       string `toml:"date"`
 	InputFile  string `toml:"input-file"`
 	OutputFile string `toml:"output-file"`
+	// Formats of input-file and output-file: "rowbinary", "text" (input only, path per line),
+	// "tsv" and "json" (JSON lines). Output tsv and json are row per path with Path, Level, IsLeaf, Tags
+	InputFormat  string `toml:"input-format"`
+	OutputFormat string `toml:"output-format"`
+	// Process tree rows changed since checkpoint of last run. Checkpoint is saved in state-file
+	Incremental bool   `toml:"incremental"`
+	StateFile   string `toml:"state-file"`
+	// Run tagger inside server every interval. 0 - disabled
+	Interval *Duration `toml:"interval"`
+	// Write coverage report (JSON) after run
+	ReportFile  string `toml:"report-file"`
+	ReportDepth int    `toml:"report-depth"` // unmatched metrics are grouped by first N nodes
+	ReportTop   int    `toml:"report-top"`   // count of largest tag sets in report
+	// Rows in one insert. Next batch is encoded while previous is uploading. 0 - single insert
+	BatchSize int `toml:"batch-size"`
+	// "fixed" - Date of all rows is date option, "run" - date of run start (new partition for each day of runs)
+	DateScheme string `toml:"date-scheme"`
+	// Remove versions older than last keep-versions after successful upload. 0 - keep all
+	KeepVersions int    `toml:"keep-versions"`
+	PruneMethod  string `toml:"prune-method"`  // "delete" (lightweight DELETE) or "partition" (drop partitions with old versions only)
+	PruneDryRun  bool   `toml:"prune-dry-run"` // only log versions and partitions to remove
 }
 
+const (
+	FormatRowBinary = "rowbinary"
+	FormatText      = "text"
+	FormatTSV       = "tsv"
+	FormatJSON      = "json"
+)
+
+const (
+	DateSchemeFixed = "fixed"
+	DateSchemeRun   = "run"
+
+	PruneDelete    = "delete"
+	PrunePartition = "partition"
+)
+
 type Carbonlink struct {
 	Server         string    `toml:"server"`
 	Threads        int       `toml:"threads-per-request"`
@@ -86,6 +135,27 @@ type DataTable struct {
 	TargetMatch string    `toml:"target-match"`
 }
 
+// Receiver accepts graphite plaintext (tcp and udp), pickle (tcp) and Prometheus remote write protocols and writes points to data-table,
+// new paths to tree-table and reverse-tree-table. Empty listen address - disabled
+type Receiver struct {
+	PlainTCPListen  string    `toml:"plain-tcp-listen"`
+	PlainUDPListen  string    `toml:"plain-udp-listen"`
+	PickleTCPListen string    `toml:"pickle-tcp-listen"`
+	BatchSize       int       `toml:"batch-size"`     // points are inserted when batch-size is reached or every flush-interval
+	FlushInterval   *Duration `toml:"flush-interval"` // max time between receive and insert of point
+	TreeDate        string    `toml:"tree-date"`      // Date of tree rows
+	KnownPaths      int       `toml:"known-paths"`    // max paths remembered as inserted to tree, set is cleared when full
+	// Accept Prometheus remote_write on /api/v1/write. Series are stored as path by prometheus-path-template
+	// ("{__name__}.{job}.{instance}") or as tagged series "name;label=value" if template is empty
+	PrometheusWrite        bool   `toml:"prometheus-remote-write"`
+	PrometheusPathTemplate string `toml:"prometheus-path-template"`
+}
+
+// Enabled returns true if any listen address is set
+func (r *Receiver) Enabled() bool {
+	return r.PlainTCPListen != "" || r.PlainUDPListen != "" || r.PickleTCPListen != ""
+}
+
 // Config ...
 type Config struct {
 	Common     Common             `toml:"common"`
@@ -93,6 +163,7 @@ type Config struct {
 	DataTable  []DataTable        `toml:"data-table"`
 	Tags       Tags               `toml:"tags"`
 	Carbonlink Carbonlink         `toml:"carbonlink"`
+	Receiver   Receiver           `toml:"receiver"`
 	Logging    []zapwriter.Config `toml:"logging"`
 	Rollup     *rollup.Rollup     `toml:"-"`
 }
@@ -116,7 +187,10 @@ func New() *Config {
 			DataTimeout: &Duration{
 				Duration: time.Minute,
 			},
-			TreeTable: "graphite_tree",
+			DataChunks:      1,
+			DataChunkBy:     ChunkByCount,
+			DataConcurrency: 4,
+			TreeTable:       "graphite_tree",
 			TreeTimeout: &Duration{
 				Duration: time.Minute,
 			},
@@ -124,8 +198,16 @@ func New() *Config {
 			TagTable:   "",
 		},
 		Tags: Tags{
-			Date:  "2016-11-01",
-			Rules: "/etc/graphite-clickhouse/tag.d/*.conf",
+			Date:         "2016-11-01",
+			Rules:        "/etc/graphite-clickhouse/tag.d/*.conf",
+			Interval:     &Duration{},
+			ReportDepth:  1,
+			ReportTop:    10,
+			BatchSize:    1000000,
+			DateScheme:   DateSchemeFixed,
+			InputFormat:  FormatRowBinary,
+			OutputFormat: FormatRowBinary,
+			PruneMethod:  PruneDelete,
 		},
 		Carbonlink: Carbonlink{
 			Threads:        10,
@@ -134,6 +216,12 @@ func New() *Config {
 			QueryTimeout:   &Duration{Duration: 50 * time.Millisecond},
 			TotalTimeout:   &Duration{Duration: 500 * time.Millisecond},
 		},
+		Receiver: Receiver{
+			BatchSize:     100000,
+			FlushInterval: &Duration{Duration: time.Second},
+			TreeDate:      "2016-11-01",
+			KnownPaths:    1000000,
+		},
 		Logging: nil,
 	}
 
@@ -215,6 +303,71 @@ func ReadConfig(filename string) (*Config, error) {
 
 	cfg.Rollup = r
 
+	if cfg.ClickHouse.DataChunks < 1 {
+		return nil, fmt.Errorf("data-chunks must be greater than 0")
+	}
+
+	if cfg.ClickHouse.DataConcurrency < 1 {
+		return nil, fmt.Errorf("data-concurrency must be greater than 0")
+	}
+
+	if cfg.ClickHouse.DataChunkBy != ChunkByCount && cfg.ClickHouse.DataChunkBy != ChunkByHash {
+		return nil, fmt.Errorf("unknown data-chunk-by %#v", cfg.ClickHouse.DataChunkBy)
+	}
+
+	switch cfg.Tags.InputFormat {
+	case FormatRowBinary, FormatText, FormatTSV, FormatJSON:
+	default:
+		return nil, fmt.Errorf("unknown tags input-format %#v", cfg.Tags.InputFormat)
+	}
+
+	switch cfg.Tags.OutputFormat {
+	case FormatRowBinary, FormatTSV, FormatJSON:
+	default:
+		return nil, fmt.Errorf("unknown tags output-format %#v", cfg.Tags.OutputFormat)
+	}
+
+	if cfg.Tags.Incremental && cfg.Tags.StateFile == "" {
+		return nil, fmt.Errorf("tags incremental requires state-file")
+	}
+
+	if cfg.Tags.DateScheme != DateSchemeFixed && cfg.Tags.DateScheme != DateSchemeRun {
+		return nil, fmt.Errorf("unknown tags date-scheme %#v", cfg.Tags.DateScheme)
+	}
+
+	if cfg.Tags.PruneMethod != PruneDelete && cfg.Tags.PruneMethod != PrunePartition {
+		return nil, fmt.Errorf("unknown tags prune-method %#v", cfg.Tags.PruneMethod)
+	}
+
+	if cfg.Tags.ReportDepth < 0 {
+		return nil, fmt.Errorf("tags report-depth must not be negative")
+	}
+
+	if cfg.Tags.ReportTop < 0 {
+		return nil, fmt.Errorf("tags report-top must not be negative")
+	}
+
+	// with fixed date all versions are in one partition, it is never dropped
+	if cfg.Tags.PruneMethod == PrunePartition && cfg.Tags.DateScheme == DateSchemeFixed {
+		return nil, fmt.Errorf("tags prune-method %#v requires date-scheme %#v", PrunePartition, DateSchemeRun)
+	}
+
+	if cfg.Receiver.BatchSize < 1 {
+		return nil, fmt.Errorf("receiver batch-size must be greater than 0")
+	}
+
+	if cfg.Receiver.KnownPaths < 1 {
+		return nil, fmt.Errorf("receiver known-paths must be greater than 0")
+	}
+
+	if cfg.Receiver.FlushInterval.Value() <= 0 {
+		return nil, fmt.Errorf("receiver flush-interval must be greater than 0")
+	}
+
+	if _, err := time.ParseInLocation("2006-01-02", cfg.Receiver.TreeDate, time.Local); err != nil {
+		return nil, fmt.Errorf("invalid receiver tree-date %#v: %s", cfg.Receiver.TreeDate, err.Error())
+	}
+
 	l := len(cfg.Common.TargetBlacklist)
 	if l > 0 {
 		cfg.Common.Blacklist = make([]*regexp.Regexp, l)
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConfigTags(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	rollup := filepath.Join(dir, "rollup.xml")
+	assert.NoError(ioutil.WriteFile(rollup, []byte(`<graphite_rollup>
+	<default>
+		<function>avg</function>
+		<retention>
+			<age>0</age>
+			<precision>60</precision>
+		</retention>
+	</default>
+</graphite_rollup>`), 0644))
+
+	read := func(tags string) error {
+		filename := filepath.Join(dir, "config.toml")
+		body := "[clickhouse]\nrollup-conf = \"" + rollup + "\"\n\n[tags]\n" + tags
+		if err := ioutil.WriteFile(filename, []byte(body), 0644); err != nil {
+			return err
+		}
+		_, err := ReadConfig(filename)
+		return err
+	}
+
+	assert.NoError(read(""))
+	assert.NoError(read("prune-method = \"partition\"\ndate-scheme = \"run\"\n"))
+	assert.EqualError(read("prune-method = \"partition\"\n"), `tags prune-method "partition" requires date-scheme "run"`)
+	assert.EqualError(read("incremental = true\n"), "tags incremental requires state-file")
+	assert.NoError(read("incremental = true\nstate-file = \"/tmp/state\"\n"))
+	assert.NoError(read("report-top = 0\nreport-depth = 0\n"))
+	assert.EqualError(read("report-top = -1\n"), "tags report-top must not be negative")
+	assert.EqualError(read("report-depth = -1\n"), "tags report-depth must not be negative")
+}
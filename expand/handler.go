@@ -0,0 +1,116 @@
+package expand
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/finder"
+	"github.com/lomik/graphite-clickhouse/helper/limit"
+)
+
+// Handler serves graphite-web compatible /metrics/expand
+type Handler struct {
+	config *config.Config
+}
+
+func NewHandler(config *config.Config) *Handler {
+	return &Handler{
+		config: config,
+	}
+}
+
+// Expand returns sorted unique paths matched query. Nodes are skipped if leavesOnly
+func (h *Handler) Expand(r *http.Request, query string, leavesOnly bool) ([]string, error) {
+	f := finder.New(r.Context(), h.config)
+
+	if err := f.Execute(query); err != nil {
+		return nil, err
+	}
+
+	list := f.List()
+
+	if err := limit.Check("max-metrics-in-find-answer", len(list), h.config.Common.MaxMetricsInFindAnswer); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(list))
+	result := make([]string, 0, len(list))
+
+	for _, row := range list {
+		if len(row) == 0 {
+			continue
+		}
+
+		path, isLeaf := finder.Leaf(row)
+		if leavesOnly && !isLeaf {
+			continue
+		}
+
+		if !seen[string(path)] {
+			seen[string(path)] = true
+			result = append(result, string(path))
+		}
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	queries := r.Form["query"]
+	if len(queries) == 0 {
+		http.Error(w, "Bad request (no query)", http.StatusBadRequest)
+		return
+	}
+
+	leavesOnly := r.Form.Get("leavesOnly") == "1"
+	groupByExpr := r.Form.Get("groupByExpr") == "1"
+
+	groups := make(map[string][]string, len(queries))
+	seen := make(map[string]bool)
+	var all []string
+
+	for _, query := range queries {
+		paths, err := h.Expand(r, query, leavesOnly)
+		if err != nil {
+			status := http.StatusBadRequest
+			if _, ok := err.(*limit.Error); ok {
+				status = http.StatusForbidden
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		groups[query] = paths
+
+		for _, p := range paths {
+			if !seen[p] {
+				seen[p] = true
+				all = append(all, p)
+			}
+		}
+	}
+
+	var body []byte
+	var err error
+	if groupByExpr {
+		body, err = json.Marshal(map[string]interface{}{"results": groups})
+	} else {
+		if all == nil {
+			all = []string{}
+		}
+		sort.Strings(all)
+		body, err = json.Marshal(map[string]interface{}{"results": all})
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
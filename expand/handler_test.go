@@ -0,0 +1,43 @@
+package expand
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/config"
+)
+
+func TestExpand(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("host.cpu1\nhost.dir.\nhost.cpu0\n"))
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+
+	testCase := func(url string, expectedStatus int, expected string) {
+		w := httptest.NewRecorder()
+		NewHandler(cfg).ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		assert.Equal(expectedStatus, w.Code, url)
+		if expectedStatus == http.StatusOK {
+			assert.Equal(expected, w.Body.String(), url)
+		}
+	}
+
+	testCase("http://localhost/metrics/expand?query=host.*", http.StatusOK,
+		`{"results":["host.cpu0","host.cpu1","host.dir"]}`)
+	testCase("http://localhost/metrics/expand?query=host.*&leavesOnly=1", http.StatusOK,
+		`{"results":["host.cpu0","host.cpu1"]}`)
+	testCase("http://localhost/metrics/expand?query=host.*&query=host.c*&leavesOnly=1&groupByExpr=1", http.StatusOK,
+		`{"results":{"host.*":["host.cpu0","host.cpu1"],"host.c*":["host.cpu0","host.cpu1"]}}`)
+	testCase("http://localhost/metrics/expand", http.StatusBadRequest, "")
+
+	cfg.Common.MaxMetricsInFindAnswer = 2
+	testCase("http://localhost/metrics/expand?query=host.*", http.StatusForbidden, "")
+}
@@ -2,13 +2,18 @@ package find
 
 import (
 	"context"
+	"encoding/json"
 	"io"
+	"sort"
+	"strings"
 
 	"github.com/gogo/protobuf/proto"
 
+	"github.com/lomik/graphite-clickhouse/carbonapi_v3_pb"
 	"github.com/lomik/graphite-clickhouse/carbonzipperpb"
 	"github.com/lomik/graphite-clickhouse/config"
 	"github.com/lomik/graphite-clickhouse/finder"
+	"github.com/lomik/graphite-clickhouse/helper/msgpack"
 	"github.com/lomik/graphite-clickhouse/helper/pickle"
 )
 
@@ -17,6 +22,7 @@ type Find struct {
 	context context.Context
 	query   string // original query
 	finder  finder.Finder
+	rows    [][]byte // finder result
 }
 
 func New(config *config.Config, ctx context.Context, query string) (*Find, error) {
@@ -31,11 +37,18 @@ func New(config *config.Config, ctx context.Context, query string) (*Find, error
 		return nil, err
 	}
 
+	f.rows = f.finder.List()
+
 	return f, nil
 }
 
+// Len returns number of rows in finder result
+func (f *Find) Len() int {
+	return len(f.rows)
+}
+
 func (f *Find) WritePickle(w io.Writer) error {
-	rows := f.finder.List()
+	rows := f.rows
 
 	if len(rows) == 0 { // empty
 		w.Write(pickle.EmptyList)
@@ -70,8 +83,167 @@ func (f *Find) WritePickle(w io.Writer) error {
 	return nil
 }
 
+func (f *Find) WriteMsgpack(w io.Writer) error {
+	rows := f.rows
+
+	count := 0
+	for i := 0; i < len(rows); i++ {
+		if len(rows[i]) > 0 {
+			count++
+		}
+	}
+
+	p := msgpack.NewWriter(w)
+
+	p.Array(count)
+
+	for i := 0; i < len(rows); i++ {
+		if len(rows[i]) == 0 {
+			continue
+		}
+
+		path, isLeaf := finder.Leaf(rows[i])
+
+		p.Map(2)
+
+		p.String("metric_path")
+		p.Bytes(path)
+
+		p.String("isLeaf")
+		p.Bool(isLeaf)
+	}
+
+	return nil
+}
+
+// node is item of treejson and completer response
+type node struct {
+	path   string
+	name   string
+	isLeaf bool
+}
+
+// nodes returns rows sorted by name. Node which is both a leaf and a branch is returned twice
+func (f *Find) nodes() []node {
+	nodes := make([]node, 0, len(f.rows))
+	seen := make(map[node]bool, len(f.rows))
+
+	for i := 0; i < len(f.rows); i++ {
+		if len(f.rows[i]) == 0 {
+			continue
+		}
+
+		path, isLeaf := finder.Leaf(f.rows[i])
+
+		n := node{path: string(path), isLeaf: isLeaf}
+		n.name = n.path[strings.LastIndexByte(n.path, '.')+1:]
+
+		if !seen[n] {
+			seen[n] = true
+			nodes = append(nodes, n)
+		}
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].name < nodes[j].name })
+
+	return nodes
+}
+
+type treeNode struct {
+	ID            string   `json:"id"`
+	Text          string   `json:"text"`
+	Leaf          int      `json:"leaf"`
+	Expandable    int      `json:"expandable"`
+	AllowChildren int      `json:"allowChildren"`
+	Context       struct{} `json:"context"`
+}
+
+func newTreeNode(id string, text string, isLeaf bool) treeNode {
+	if isLeaf {
+		return treeNode{ID: id, Text: text, Leaf: 1}
+	}
+	return treeNode{ID: id, Text: text, Expandable: 1, AllowChildren: 1}
+}
+
+// WriteTreeJSON writes nodes for graphite-web composer tree: branches first, then leafs.
+// Leaf and branch with same path are written once as expandable leaf. wildcards adds "*" node if more than one node found
+func (f *Find) WriteTreeJSON(w io.Writer, wildcards bool) error {
+	nodes := f.nodes()
+
+	result := make([]treeNode, 0, len(nodes)+1)
+
+	if wildcards && len(nodes) > 1 {
+		var basePath string
+		if i := strings.LastIndexByte(f.query, '.'); i >= 0 {
+			basePath = f.query[:i+1]
+		}
+
+		allLeafs := true
+		for _, n := range nodes {
+			allLeafs = allLeafs && n.isLeaf
+		}
+
+		result = append(result, newTreeNode(basePath+"*", "*", allLeafs))
+	}
+
+	// index of written node by path
+	written := make(map[string]int, len(nodes))
+
+	for _, leafs := range []bool{false, true} {
+		for _, n := range nodes {
+			if n.isLeaf != leafs {
+				continue
+			}
+			if i, ok := written[n.path]; ok {
+				result[i].Leaf = 1
+				continue
+			}
+			written[n.path] = len(result)
+			result = append(result, newTreeNode(n.path, n.name, n.isLeaf))
+		}
+	}
+
+	return writeJSON(w, result)
+}
+
+type completerNode struct {
+	Path   string `json:"path,omitempty"`
+	Name   string `json:"name"`
+	IsLeaf string `json:"is_leaf,omitempty"`
+}
+
+// WriteCompleter writes {"metrics": [...]} for metric name autocompletion. Path of branch has trailing dot
+func (f *Find) WriteCompleter(w io.Writer, wildcards bool) error {
+	nodes := f.nodes()
+
+	result := make([]completerNode, 0, len(nodes)+1)
+
+	for _, n := range nodes {
+		if n.isLeaf {
+			result = append(result, completerNode{Path: n.path, Name: n.name, IsLeaf: "1"})
+		} else {
+			result = append(result, completerNode{Path: n.path + ".", Name: n.name, IsLeaf: "0"})
+		}
+	}
+
+	if wildcards && len(result) > 1 {
+		result = append(result, completerNode{Name: "*"})
+	}
+
+	return writeJSON(w, map[string][]completerNode{"metrics": result})
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
 func (f *Find) WriteProtobuf(w io.Writer) error {
-	rows := f.finder.List()
+	rows := f.rows
 
 	if len(rows) == 0 { // empty
 		return nil
@@ -112,3 +284,26 @@ func (f *Find) WriteProtobuf(w io.Writer) error {
 
 	return nil
 }
+
+// GlobResponseV3 returns result as carbonapi_v3_pb message
+func (f *Find) GlobResponseV3() carbonapi_v3_pb.GlobResponse {
+	response := carbonapi_v3_pb.GlobResponse{
+		Name:    f.query,
+		Matches: make([]carbonapi_v3_pb.GlobMatch, 0, len(f.rows)),
+	}
+
+	for i := 0; i < len(f.rows); i++ {
+		if len(f.rows[i]) == 0 {
+			continue
+		}
+
+		path, isLeaf := finder.Leaf(f.rows[i])
+
+		response.Matches = append(response.Matches, carbonapi_v3_pb.GlobMatch{
+			Path:   string(path),
+			IsLeaf: isLeaf,
+		})
+	}
+
+	return response
+}
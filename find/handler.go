@@ -1,11 +1,21 @@
 package find
 
 import (
+	"bytes"
+	"io/ioutil"
 	"net/http"
+	"regexp"
 
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/lomik/graphite-clickhouse/carbonapi_v3_pb"
 	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/limit"
 )
 
+// jsonpCallback allows only javascript identifiers, callback is written to response as is
+var jsonpCallback = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$.]*$`)
+
 type Handler struct {
 	config *config.Config
 }
@@ -17,6 +27,11 @@ func NewHandler(config *config.Config) *Handler {
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("format") == "carbonapi_v3_pb" {
+		h.ServeV3(w, r)
+		return
+	}
+
 	query := r.URL.Query().Get("query")
 
 	f, err := New(h.config, r.Context(), query)
@@ -25,14 +40,106 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := limit.Check("max-metrics-in-find-answer", f.Len(), h.config.Common.MaxMetricsInFindAnswer); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	h.Reply(w, r, f)
 }
 
+// ServeV3 handles carbonapi_v3_pb request: MultiGlobRequest in POST body or query arguments
+func (h *Handler) ServeV3(w http.ResponseWriter, r *http.Request) {
+	var request carbonapi_v3_pb.MultiGlobRequest
+
+	if r.Method == http.MethodPost {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := proto.Unmarshal(body, &request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		request.Metrics = r.URL.Query()["query"]
+	}
+
+	var response carbonapi_v3_pb.MultiGlobResponse
+
+	for _, query := range request.Metrics {
+		f, err := New(h.config, r.Context(), query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := limit.Check("max-metrics-in-find-answer", f.Len(), h.config.Common.MaxMetricsInFindAnswer); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		response.Metrics = append(response.Metrics, f.GlobResponseV3())
+	}
+
+	body, err := proto.Marshal(&response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(body)
+}
+
 func (h *Handler) Reply(w http.ResponseWriter, r *http.Request, f *Find) {
 	switch r.URL.Query().Get("format") {
 	case "pickle":
 		f.WritePickle(w)
 	case "protobuf":
 		f.WriteProtobuf(w)
+	case "msgpack":
+		w.Header().Set("Content-Type", "application/x-msgpack")
+		f.WriteMsgpack(w)
+	case "treejson", "completer":
+		h.ReplyJSON(w, r, f)
+	}
+}
+
+// ReplyJSON writes treejson and completer formats. Response is wrapped with jsonp callback if set
+func (h *Handler) ReplyJSON(w http.ResponseWriter, r *http.Request, f *Find) {
+	wildcards := r.URL.Query().Get("wildcards") == "1"
+	jsonp := r.URL.Query().Get("jsonp")
+	if jsonp != "" && !jsonpCallback.MatchString(jsonp) {
+		http.Error(w, "Bad request (invalid jsonp callback)", http.StatusBadRequest)
+		return
+	}
+
+	body := new(bytes.Buffer)
+
+	if jsonp != "" {
+		body.WriteString(jsonp)
+		body.WriteByte('(')
+	}
+
+	var err error
+	if r.URL.Query().Get("format") == "treejson" {
+		err = f.WriteTreeJSON(body, wildcards)
+	} else {
+		err = f.WriteCompleter(body, wildcards)
 	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if jsonp != "" {
+		body.WriteByte(')')
+		w.Header().Set("Content-Type", "text/javascript")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	w.Write(body.Bytes())
 }
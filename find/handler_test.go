@@ -1,19 +1,24 @@
 package find
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
+	"github.com/gogo/protobuf/proto"
 	"go.uber.org/zap"
 
+	"github.com/lomik/graphite-clickhouse/carbonapi_v3_pb"
 	"github.com/lomik/graphite-clickhouse/config"
 )
 
 type clickhouseMock struct {
 	requestLog chan []byte
+	response   []byte
 }
 
 func (m *clickhouseMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -22,6 +27,8 @@ func (m *clickhouseMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if m.requestLog != nil {
 		m.requestLog <- body
 	}
+
+	w.Write(m.response)
 }
 
 func TestFind(t *testing.T) {
@@ -60,6 +67,163 @@ func TestFind(t *testing.T) {
 
 	testCase(
 		"host.top.cpu.cpu%2A",
-		"SELECT Path FROM graphite_tree WHERE (Level = 4) AND (Path LIKE 'host.top.cpu.cpu%') GROUP BY Path HAVING argMax(Deleted, Version)==0",
+		"SELECT Path FROM graphite_tree WHERE (Level = {p0:UInt32}) AND (Path LIKE {p1:String}) GROUP BY Path HAVING argMax(Deleted, Version)==0",
+	)
+}
+
+func TestFindLimit(t *testing.T) {
+	m := &clickhouseMock{
+		response: []byte("host.cpu0\nhost.cpu1\nhost.cpu2\n"),
+	}
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	testCase := func(maxMetrics int, expectedStatus int) {
+		cfg := config.New()
+		cfg.ClickHouse.Url = srv.URL
+		cfg.Common.MaxMetricsInFindAnswer = maxMetrics
+
+		handler := NewHandler(cfg)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(
+			"GET",
+			"http://localhost/metrics/find/?local=1&format=pickle&query=host.cpu%2A",
+			nil,
+		)
+
+		handler.ServeHTTP(w, r)
+
+		if w.Code != expectedStatus {
+			t.Fatalf("max-metrics-in-find-answer=%d: status %d (actual) != %d (expected)", maxMetrics, w.Code, expectedStatus)
+		}
+	}
+
+	testCase(0, http.StatusOK)
+	testCase(3, http.StatusOK)
+	testCase(2, http.StatusForbidden)
+}
+
+func TestFindV3(t *testing.T) {
+	m := &clickhouseMock{
+		response: []byte("host.cpu0\nhost.dir.\n"),
+	}
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+
+	body, _ := proto.Marshal(&carbonapi_v3_pb.MultiGlobRequest{Metrics: []string{"host.*", "other.*"}})
+
+	handler := NewHandler(cfg)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		"POST",
+		"http://localhost/metrics/find/?format=carbonapi_v3_pb",
+		bytes.NewReader(body),
+	)
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	var response carbonapi_v3_pb.MultiGlobResponse
+	if err := proto.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := carbonapi_v3_pb.MultiGlobResponse{
+		Metrics: []carbonapi_v3_pb.GlobResponse{
+			{Name: "host.*", Matches: []carbonapi_v3_pb.GlobMatch{{Path: "host.cpu0", IsLeaf: true}, {Path: "host.dir", IsLeaf: false}}},
+			{Name: "other.*", Matches: []carbonapi_v3_pb.GlobMatch{{Path: "host.cpu0", IsLeaf: true}, {Path: "host.dir", IsLeaf: false}}},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, response) {
+		t.Fatalf("%#v (actual) != %#v (expected)", response, expected)
+	}
+}
+
+func TestFindMsgpack(t *testing.T) {
+	m := &clickhouseMock{
+		response: []byte("host.cpu0\nhost.dir.\n"),
+	}
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+
+	handler := NewHandler(cfg)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		"GET",
+		"http://localhost/metrics/find/?format=msgpack&query=host.%2A",
+		nil,
 	)
+	handler.ServeHTTP(w, r)
+
+	expected := []byte("\x92" +
+		"\x82\xabmetric_path\xa9host.cpu0\xa6isLeaf\xc3" +
+		"\x82\xabmetric_path\xa8host.dir\xa6isLeaf\xc2")
+
+	if !bytes.Equal(w.Body.Bytes(), expected) {
+		t.Fatalf("%q (actual) != %q (expected)", w.Body.Bytes(), expected)
+	}
+}
+
+func TestFindJSON(t *testing.T) {
+	m := &clickhouseMock{
+		response: []byte("host.web.\nhost.cpu0\nhost.web\nhost.a.\n"),
+	}
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+
+	testCase := func(args string, expectedStatus int, expectedType string, expected string) {
+		handler := NewHandler(cfg)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://localhost/metrics/find/?query=host.%2A&"+args, nil)
+		handler.ServeHTTP(w, r)
+
+		if w.Code != expectedStatus {
+			t.Fatalf("%s: status %d (actual) != %d (expected)", args, w.Code, expectedStatus)
+		}
+		if expectedStatus != http.StatusOK {
+			return
+		}
+		if w.Header().Get("Content-Type") != expectedType {
+			t.Fatalf("%s: %#v (actual) != %#v (expected)", args, w.Header().Get("Content-Type"), expectedType)
+		}
+		if w.Body.String() != expected {
+			t.Fatalf("%s: %s (actual) != %s (expected)", args, w.Body.String(), expected)
+		}
+	}
+
+	testCase("format=treejson", http.StatusOK, "application/json",
+		`[{"id":"host.a","text":"a","leaf":0,"expandable":1,"allowChildren":1,"context":{}},`+
+			`{"id":"host.web","text":"web","leaf":1,"expandable":1,"allowChildren":1,"context":{}},`+
+			`{"id":"host.cpu0","text":"cpu0","leaf":1,"expandable":0,"allowChildren":0,"context":{}}]`)
+
+	testCase("format=treejson&wildcards=1&jsonp=cb", http.StatusOK, "text/javascript",
+		`cb([{"id":"host.*","text":"*","leaf":0,"expandable":1,"allowChildren":1,"context":{}},`+
+			`{"id":"host.a","text":"a","leaf":0,"expandable":1,"allowChildren":1,"context":{}},`+
+			`{"id":"host.web","text":"web","leaf":1,"expandable":1,"allowChildren":1,"context":{}},`+
+			`{"id":"host.cpu0","text":"cpu0","leaf":1,"expandable":0,"allowChildren":0,"context":{}}])`)
+
+	testCase("format=completer&wildcards=1", http.StatusOK, "application/json",
+		`{"metrics":[{"path":"host.a.","name":"a","is_leaf":"0"},`+
+			`{"path":"host.cpu0","name":"cpu0","is_leaf":"1"},`+
+			`{"path":"host.web.","name":"web","is_leaf":"0"},`+
+			`{"path":"host.web","name":"web","is_leaf":"1"},`+
+			`{"name":"*"}]}`)
+
+	testCase("format=completer&jsonp=alert(1)", http.StatusBadRequest, "", "")
 }
@@ -3,11 +3,11 @@ package finder
 import (
 	"bytes"
 	"context"
-	"fmt"
 	"strings"
 	"time"
 
 	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
 )
 
 type BaseFinder struct {
@@ -27,54 +27,72 @@ func NewBase(ctx context.Context, url string, table string, timeout time.Duratio
 	}
 }
 
-func (b *BaseFinder) where(query string) string {
+func (b *BaseFinder) where(query string) sqlb.Cond {
 	level := strings.Count(query, ".") + 1
 
-	w := NewWhere()
-
-	w.Andf("Level = %d", level)
+	levelCond := sqlb.Eq("Level", uint32(level))
 
 	if query == "*" {
-		return w.String()
+		return levelCond
 	}
 
 	// simple metric
 	if !HasWildcard(query) {
-		w.Andf("Path = %s OR Path = %s", Q(query), Q(query+"."))
-		return w.String()
+		return sqlb.And(
+			levelCond,
+			sqlb.Or(sqlb.Eq("Path", query), sqlb.Eq("Path", query+".")),
+		)
 	}
 
 	// before any wildcard symbol
 	simplePrefix := query[:strings.IndexAny(query, "[]{}*")]
 
+	var prefixCond sqlb.Cond
 	if len(simplePrefix) > 0 {
-		w.Andf("Path LIKE %s", Q(simplePrefix+`%`))
+		prefixCond = sqlb.HasPrefix("Path", simplePrefix)
 	}
 
 	// prefix search like "metric.name.xx*"
 	if len(simplePrefix) == len(query)-1 && query[len(query)-1] == '*' {
-		return w.String()
+		return sqlb.And(levelCond, prefixCond)
 	}
 
-	// Q() replaces \ with \\, so using \. does not work here.
-	// work around with [.]
-	w.Andf("match(Path, %s)", Q(`^`+GlobToRegexp(query)+`[.]?$`))
-	return w.String()
+	return sqlb.And(
+		levelCond,
+		prefixCond,
+		sqlb.Match("Path", `^`+GlobToRegexp(query)+`[.]?$`),
+	)
 }
 
 func (b *BaseFinder) Execute(query string) (err error) {
-	where := b.where(query)
+	q, err := b.sql("Path", query).Build()
+	if err != nil {
+		return err
+	}
 
 	b.body, err = clickhouse.Query(
 		b.ctx,
 		b.url,
-		fmt.Sprintf("SELECT Path FROM %s WHERE %s GROUP BY Path HAVING argMax(Deleted, Version)==0", b.table, where),
+		q,
 		b.timeout,
 	)
 
 	return
 }
 
+// sql returns query selecting column expression for not deleted paths
+func (b *BaseFinder) sql(column string, query string) *sqlb.Select {
+	return sqlb.NewSelect(column).
+		From(b.table).
+		Where(b.where(query)).
+		GroupBy("Path").
+		Having(sqlb.Raw("argMax(Deleted, Version)==0"))
+}
+
+func (b *BaseFinder) SeriesSubquery(query string) (*sqlb.Select, bool) {
+	return b.sql("Path", query), true
+}
+
 func (b *BaseFinder) makeList(onlySeries bool) [][]byte {
 	if b.body == nil {
 		return [][]byte{}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
 )
 
 type Finder interface {
@@ -13,6 +14,13 @@ type Finder interface {
 	Abs([]byte) []byte
 }
 
+// SubqueryFinder returns SQL which selects series for query instead of executing it.
+// Render uses it for resolve series inside data query. ok is false if query can't be resolved by subquery
+type SubqueryFinder interface {
+	Finder
+	SeriesSubquery(query string) (sql *sqlb.Select, ok bool)
+}
+
 func New(ctx context.Context, config *config.Config) Finder {
 	f := NewBase(ctx, config.ClickHouse.Url, config.ClickHouse.TreeTable, config.ClickHouse.TreeTimeout.Value())
 
@@ -28,6 +36,11 @@ func New(ctx context.Context, config *config.Config) Finder {
 		f = WrapPrefix(f, config.ClickHouse.ExtraPrefix)
 	}
 
+	// tagged series names are not prefixed
+	if config.ClickHouse.TaggedTable != "" {
+		f = WrapTagged(f, ctx, config.ClickHouse.Url, config.ClickHouse.TaggedTable, config.ClickHouse.TreeTimeout.Value())
+	}
+
 	if len(config.Common.Blacklist) > 0 {
 		f = WrapBlacklist(f, config.Common.Blacklist)
 	}
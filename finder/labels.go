@@ -0,0 +1,62 @@
+package finder
+
+import (
+	"regexp"
+
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+)
+
+// tagKeysSQL returns query of tag names: "key" of Tag1 "key=value". Names are filtered by prefix if it is not empty
+func tagKeysSQL(table string, prefix string, conds ...sqlb.Cond) (*sqlb.Query, error) {
+	conds = append(conds, sqlb.Ne("Tag1", ""))
+	if prefix != "" {
+		conds = append(conds, sqlb.HasPrefix("Tag1", prefix))
+	}
+
+	return sqlb.NewSelect("splitByChar('=', Tag1)[1] AS Key").
+		From(table).
+		Where(conds...).
+		GroupBy("Key").
+		Build()
+}
+
+// tagValuesSQL returns query of Tag1 "key=value" with key and value prefix. Empty key is any tag except name of series
+func tagValuesSQL(table string, key string, prefix string, conds ...sqlb.Cond) (*sqlb.Query, error) {
+	if key != "" {
+		conds = append(conds, sqlb.HasPrefix("Tag1", key+"="+prefix))
+	} else {
+		conds = append(conds,
+			sqlb.Ne("Tag1", ""),
+			sqlb.Not(sqlb.HasPrefix("Tag1", "__name__=")),
+		)
+		if prefix != "" {
+			conds = append(conds, sqlb.Match("Tag1", "^[^=]*="+regexp.QuoteMeta(prefix)))
+		}
+	}
+
+	return sqlb.NewSelect("Tag1").
+		From(table).
+		Where(conds...).
+		GroupBy("Tag1").
+		Build()
+}
+
+// TagKeysSQL returns query of tag names with prefix of last tagger run
+func (t *TagFinder) TagKeysSQL(prefix string) (*sqlb.Query, error) {
+	return tagKeysSQL(t.table, prefix, t.versionCond(), sqlb.Eq("Level", uint32(1)))
+}
+
+// TagValuesSQL returns query of "key=value" tags with value prefix of last tagger run
+func (t *TagFinder) TagValuesSQL(key string, prefix string) (*sqlb.Query, error) {
+	return tagValuesSQL(t.table, key, prefix, t.versionCond(), sqlb.Eq("Level", uint32(1)))
+}
+
+// TagKeysSQL returns query of tag names with prefix of last index build, name of series is "__name__"
+func (t *TaggedFinder) TagKeysSQL(prefix string) (*sqlb.Query, error) {
+	return tagKeysSQL(t.table, prefix, t.versionCond())
+}
+
+// TagValuesSQL returns query of "key=value" tags with value prefix of last index build
+func (t *TaggedFinder) TagValuesSQL(key string, prefix string) (*sqlb.Query, error) {
+	return tagValuesSQL(t.table, key, prefix, t.versionCond())
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"strings"
 	"time"
+
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
 )
 
 type ReverseFinder struct {
@@ -50,13 +52,18 @@ func WrapReverse(f Finder, ctx context.Context, url string, table string, timeou
 	}
 }
 
-func (r *ReverseFinder) Execute(query string) error {
+// useReverse returns true if last node of query is without wildcards
+func useReverse(query string) bool {
 	p := strings.LastIndexByte(query, '.')
 	if p < 0 || p >= len(query)-1 {
-		return r.wrapped.Execute(query)
+		return false
 	}
 
-	if HasWildcard(query[p+1:]) {
+	return !HasWildcard(query[p+1:])
+}
+
+func (r *ReverseFinder) Execute(query string) error {
+	if !useReverse(query) {
 		return r.wrapped.Execute(query)
 	}
 
@@ -64,6 +71,22 @@ func (r *ReverseFinder) Execute(query string) error {
 	return r.baseFinder.Execute(ReverseString(query))
 }
 
+func (r *ReverseFinder) SeriesSubquery(query string) (*sqlb.Select, bool) {
+	if !useReverse(query) {
+		if s, ok := r.wrapped.(SubqueryFinder); ok {
+			return s.SeriesSubquery(query)
+		}
+		return nil, false
+	}
+
+	base, ok := r.baseFinder.(*BaseFinder)
+	if !ok {
+		return nil, false
+	}
+
+	return base.sql("arrayStringConcat(arrayReverse(splitByChar('.', Path)), '.')", ReverseString(query)), true
+}
+
 func (r *ReverseFinder) List() [][]byte {
 	if !r.isUsed {
 		return r.wrapped.List()
@@ -23,3 +23,26 @@ func TestReverse(t *testing.T) {
 		assert.Equal([]byte(table[i+1]), ReverseBytes([]byte(table[i])))
 	}
 }
+
+func TestReverseSeriesSubquery(t *testing.T) {
+	assert := assert.New(t)
+
+	f := WrapReverse(NewBase(nil, "", "graphite_tree", 0), nil, "", "graphite_reverse_tree", 0)
+
+	s, ok := f.SeriesSubquery("a.*.c")
+	assert.True(ok)
+	q, err := s.Build()
+	assert.NoError(err)
+	assert.Equal("SELECT arrayStringConcat(arrayReverse(splitByChar('.', Path)), '.') FROM graphite_reverse_tree WHERE (Level = {p0:UInt32}) AND (Path LIKE {p1:String}) AND (match(Path, {p2:String})) GROUP BY Path HAVING argMax(Deleted, Version)==0", q.String())
+	assert.Equal([]string{"3", "c.%", "^c.([^.]*?).a[.]?$"}, paramValues(q))
+
+	s, ok = f.SeriesSubquery("a.b.c*")
+	assert.True(ok)
+	q, err = s.Build()
+	assert.NoError(err)
+	assert.Equal("SELECT Path FROM graphite_tree WHERE (Level = {p0:UInt32}) AND (Path LIKE {p1:String}) GROUP BY Path HAVING argMax(Deleted, Version)==0", q.String())
+	assert.Equal([]string{"3", "a.b.c%"}, paramValues(q))
+
+	_, ok = WrapReverse(NewMockFinder(nil), nil, "", "graphite_reverse_tree", 0).SeriesSubquery("a.b.c*")
+	assert.False(ok)
+}
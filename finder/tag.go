@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
 )
 
 type TagState int
@@ -40,18 +41,18 @@ func (q TagQ) String() string {
 	return "{}"
 }
 
-func (q *TagQ) Where(field string) string {
+func (q *TagQ) Where(field string) sqlb.Cond {
 	if q.Param != nil && q.Value != nil && *q.Value != "*" {
-		return fmt.Sprintf("%s=%s", field, Q(*q.Param+*q.Value))
+		return sqlb.Eq(field, *q.Param+*q.Value)
 	}
 	if q.Param != nil {
-		return fmt.Sprintf("%s LIKE %s", field, Q(*q.Param+`%`))
+		return sqlb.HasPrefix(field, *q.Param)
 	}
 	if q.Value != nil && *q.Value != "*" {
-		return fmt.Sprintf("%s=%s", field, Q(*q.Value))
+		return sqlb.Eq(field, *q.Value)
 	}
 
-	return ""
+	return nil
 }
 
 type TagFinder struct {
@@ -80,68 +81,80 @@ func WrapTag(f Finder, ctx context.Context, url string, table string, timeout ti
 	}
 }
 
-func (t *TagFinder) tagListSQL() (string, error) {
+// versionCond selects rows of last tagger run
+func (t *TagFinder) versionCond() sqlb.Cond {
+	return sqlb.Ge("Version", sqlb.NewSelect("Max(Version)").From(t.table).Where(
+		sqlb.Eq("Tag1", ""),
+		sqlb.Eq("Level", uint32(0)),
+		sqlb.Eq("Path", ""),
+	))
+}
+
+func (t *TagFinder) tagListSQL() (*sqlb.Query, error) {
 	if len(t.tagQuery) == 0 {
-		return "", nil
+		return nil, nil
 	}
 
-	w := NewWhere()
-
-	w.Andf("Version>=(SELECT Max(Version) FROM %s WHERE Tag1='' AND Level=0 AND Path='')", t.table)
-
-	// first
-	w.And(t.tagQuery[0].Where("Tag1"))
-
 	if len(t.tagQuery) == 1 {
-		w.And("Level=1")
-		return fmt.Sprintf("SELECT Tag1 FROM %s WHERE %s GROUP BY Tag1", t.table, w), nil
+		return sqlb.NewSelect("Tag1").From(t.table).Where(
+			t.versionCond(),
+			t.tagQuery[0].Where("Tag1"),
+			sqlb.Eq("Level", uint32(1)),
+		).GroupBy("Tag1").Build()
 	}
 
+	s := sqlb.NewSelect("TagN").From(t.table).ArrayJoin("Tags AS TagN").Where(
+		t.versionCond(),
+		// first
+		t.tagQuery[0].Where("Tag1"),
+	)
+
 	// 1..(n-1)
 	for i := 1; i < len(t.tagQuery)-1; i++ {
 		cond := t.tagQuery[i].Where("x")
-		if cond != "" {
-			w.Andf("arrayExists((x) -> %s, Tags)", cond)
+		if cond != nil {
+			s.Where(sqlb.ArrayExists("x", "Tags", cond))
 		}
 	}
 
 	// last
-	w.And(t.tagQuery[len(t.tagQuery)-1].Where("TagN"))
+	s.Where(t.tagQuery[len(t.tagQuery)-1].Where("TagN"))
 
-	w.And("IsLeaf=1")
+	s.Where(sqlb.Eq("IsLeaf", uint8(1)))
 
-	return fmt.Sprintf("SELECT TagN FROM %s ARRAY JOIN Tags AS TagN WHERE %s GROUP BY TagN", t.table, w), nil
+	return s.GroupBy("TagN").Build()
 }
 
-func (t *TagFinder) seriesSQL() (string, error) {
+func (t *TagFinder) seriesSQL() (*sqlb.Query, error) {
 	if len(t.tagQuery) == 0 {
-		return "", nil
+		return nil, nil
 	}
 
-	w := NewWhere()
-
-	w.Andf("Version>=(SELECT Max(Version) FROM %s WHERE Tag1='' AND Level=0 AND Path='')", t.table)
-	// first
-	w.And(t.tagQuery[0].Where("Tag1"))
+	s := sqlb.NewSelect("Path").From(t.table).Where(
+		t.versionCond(),
+		// first
+		t.tagQuery[0].Where("Tag1"),
+	)
 
 	// 1..(n-1)
 	for i := 1; i < len(t.tagQuery); i++ {
 		cond := t.tagQuery[i].Where("x")
-		if cond != "" {
-			w.Andf("arrayExists((x) -> %s, Tags)", cond)
+		if cond != nil {
+			s.Where(sqlb.ArrayExists("x", "Tags", cond))
 		}
 	}
 
 	base := &BaseFinder{}
-	w.And(base.where(t.seriesQuery))
+	s.Where(base.where(t.seriesQuery))
 
-	return fmt.Sprintf("SELECT Path FROM %s WHERE %s GROUP BY Path", t.table, w), nil
+	return s.GroupBy("Path").Build()
 }
 
-func (t *TagFinder) MakeSQL(query string) (string, error) {
+// MakeSQL returns nil query if clickhouse request is not required
+func (t *TagFinder) MakeSQL(query string) (*sqlb.Query, error) {
 	if query == "_tag" {
 		t.state = TagInfoRoot
-		return "", nil
+		return nil, nil
 	}
 
 	qs0 := strings.Split(query, ".")
@@ -216,13 +229,13 @@ func (t *TagFinder) Execute(query string) error {
 		return t.wrapped.Execute(query)
 	}
 
-	sql, err := t.MakeSQL(query)
+	q, err := t.MakeSQL(query)
 	if err != nil {
 		return err
 	}
 
-	if sql != "" {
-		t.body, err = clickhouse.Query(t.ctx, t.url, sql, t.timeout)
+	if q != nil {
+		t.body, err = clickhouse.Query(t.ctx, t.url, q, t.timeout)
 	}
 
 	return err
@@ -9,31 +9,49 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
 )
 
+// paramValues returns query parameters in placeholder order
+func paramValues(q *sqlb.Query) []string {
+	values := make([]string, len(q.Params()))
+	for i := 0; i < len(values); i++ {
+		values[i] = q.Params().Get(fmt.Sprintf("param_p%d", i))
+	}
+	return values
+}
+
 func TestTagsMakeSQL(t *testing.T) {
 	assert := assert.New(t)
 
-	tag1Base := "SELECT Tag1 FROM table WHERE (Version>=(SELECT Max(Version) FROM table WHERE Tag1='' AND Level=0 AND Path=''))"
+	version := "(Version >= (SELECT Max(Version) FROM table WHERE (Tag1 = {p0:String}) AND (Level = {p1:UInt32}) AND (Path = {p2:String})))"
+	versionParams := []string{"", "0", ""}
+
+	tag1Base := "SELECT Tag1 FROM table WHERE " + version
 	tag1Group := " GROUP BY Tag1"
 
-	tagNBase := "SELECT TagN FROM table ARRAY JOIN Tags AS TagN WHERE (Version>=(SELECT Max(Version) FROM table WHERE Tag1='' AND Level=0 AND Path=''))"
+	tagNBase := "SELECT TagN FROM table ARRAY JOIN Tags AS TagN WHERE " + version
 	tagNGroup := " GROUP BY TagN"
 
+	type p []string
+
 	table := []struct {
-		query string
-		sql   string
-		error bool
+		query  string
+		sql    string
+		params []string
+		error  bool
 	}{
 		// SELECT Tag1 FROM graphite_tag WHERE Version >= (SELECT Max(Version) FROM graphite_tag WHERE Tag1='' AND Level=0 AND Path='') AND Level=1 GROUP BY Tag1;
-		{"_tag", "", false},
-		{"_tag.*", tag1Base + " AND (Level=1)" + tag1Group, false},
-		{"_tag.t1", tag1Base + " AND (Tag1='t1') AND (Level=1)" + tag1Group, false},
-		{"_tag.p1=", tag1Base + " AND (Tag1 LIKE 'p1=%') AND (Level=1)" + tag1Group, false},
-		{"_tag.p1=.*", tag1Base + " AND (Tag1 LIKE 'p1=%') AND (Level=1)" + tag1Group, false},
-		{"_tag.p1=.v1", tag1Base + " AND (Tag1='p1=v1') AND (Level=1)" + tag1Group, false},
-		{"_tag.t2._tag.*", tagNBase + " AND (Tag1='t2') AND (IsLeaf=1)" + tagNGroup, false},
-		{"_tag.t2._tag.t2._tag.p3=.*", tagNBase + " AND (Tag1='t2') AND (arrayExists((x) -> x='t2', Tags)) AND (TagN LIKE 'p3=%') AND (IsLeaf=1)" + tagNGroup, false},
+		{"_tag", "", nil, false},
+		{"_tag.*", tag1Base + " AND (Level = {p3:UInt32})" + tag1Group, p{"1"}, false},
+		{"_tag.t1", tag1Base + " AND (Tag1 = {p3:String}) AND (Level = {p4:UInt32})" + tag1Group, p{"t1", "1"}, false},
+		{"_tag.p1=", tag1Base + " AND (Tag1 LIKE {p3:String}) AND (Level = {p4:UInt32})" + tag1Group, p{"p1=%", "1"}, false},
+		{"_tag.p1=.*", tag1Base + " AND (Tag1 LIKE {p3:String}) AND (Level = {p4:UInt32})" + tag1Group, p{"p1=%", "1"}, false},
+		{"_tag.p1=.v1", tag1Base + " AND (Tag1 = {p3:String}) AND (Level = {p4:UInt32})" + tag1Group, p{"p1=v1", "1"}, false},
+		{"_tag.p_1=", tag1Base + " AND (Tag1 LIKE {p3:String}) AND (Level = {p4:UInt32})" + tag1Group, p{`p\\_1=%`, "1"}, false},
+		{"_tag.t2._tag.*", tagNBase + " AND (Tag1 = {p3:String}) AND (IsLeaf = {p4:UInt8})" + tagNGroup, p{"t2", "1"}, false},
+		{"_tag.t2._tag.t2._tag.p3=.*", tagNBase + " AND (Tag1 = {p3:String}) AND (arrayExists((x) -> x = {p4:String}, Tags)) AND (TagN LIKE {p5:String}) AND (IsLeaf = {p6:UInt8})" + tagNGroup, p{"t2", "t2", "p3=%", "1"}, false},
+		{"_tag.t1._tag.p2=.v2.host.*", "SELECT Path FROM table WHERE " + version + " AND (Tag1 = {p3:String}) AND (arrayExists((x) -> x = {p4:String}, Tags)) AND (Level = {p5:UInt32}) AND (Path LIKE {p6:String}) GROUP BY Path", p{"t1", "p2=v2", "2", "host.%"}, false},
 	}
 
 	for _, test := range table {
@@ -42,14 +60,21 @@ func TestTagsMakeSQL(t *testing.T) {
 		m := NewMockFinder([][]byte{[]byte("mock")})
 		f := WrapTag(m, context.Background(), "http://localhost:8123/", "table", time.Second)
 
-		sql, err := f.MakeSQL(test.query)
+		q, err := f.MakeSQL(test.query)
 
 		if test.error {
 			assert.Error(err)
 		} else {
 			assert.NoError(err)
 		}
-		assert.Equal(test.sql, sql, testName)
+
+		if test.sql == "" {
+			assert.Nil(q, testName)
+			continue
+		}
+
+		assert.Equal(test.sql, q.String(), testName)
+		assert.Equal(append(versionParams, test.params...), paramValues(q), testName)
 	}
 }
 
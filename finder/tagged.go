@@ -0,0 +1,259 @@
+package finder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+)
+
+// TaggedTerm is one expression of seriesByTag: tag=value, tag!=value, tag=~regexp, tag!=~regexp
+type TaggedTerm struct {
+	Key   string
+	Op    string
+	Value string
+}
+
+// tag returns "key=value" as it is stored in tagged table
+func (t *TaggedTerm) tag() string {
+	key := t.Key
+	if key == "name" {
+		key = "__name__"
+	}
+	return key + "=" + t.Value
+}
+
+func (t *TaggedTerm) key() string {
+	if t.Key == "name" {
+		return "__name__"
+	}
+	return t.Key
+}
+
+// cond returns condition on array item x
+func (t *TaggedTerm) cond() sqlb.Cond {
+	switch t.Op {
+	case "=~", "!=~":
+		return sqlb.Match("x", "^"+regexp.QuoteMeta(t.key())+"=(?:"+t.Value+")")
+	default:
+		if t.Value == "" {
+			// tag exists with any value
+			return sqlb.HasPrefix("x", t.key()+"=")
+		}
+		return sqlb.Eq("x", t.tag())
+	}
+}
+
+// Where returns condition on Tags array. Empty value of = and != checks absence and presence of tag as in graphite
+func (t *TaggedTerm) Where() sqlb.Cond {
+	exists := sqlb.ArrayExists("x", "Tags", t.cond())
+	negative := t.Op == "!=" || t.Op == "!=~"
+	if t.Value == "" && (t.Op == "=" || t.Op == "!=") {
+		negative = !negative
+	}
+	if negative {
+		return sqlb.Not(exists)
+	}
+	return exists
+}
+
+// SeriesByTag returns seriesByTag query of expressions. Each expression is quoted by quote character it does not contain
+func SeriesByTag(exprs []string) (string, error) {
+	args := make([]string, 0, len(exprs))
+
+	for _, expr := range exprs {
+		switch {
+		case !strings.Contains(expr, "'"):
+			args = append(args, "'"+expr+"'")
+		case !strings.Contains(expr, "\""):
+			args = append(args, "\""+expr+"\"")
+		default:
+			return "", fmt.Errorf("seriesByTag expression %#v contains both quote characters", expr)
+		}
+	}
+
+	return "seriesByTag(" + strings.Join(args, ",") + ")", nil
+}
+
+// ParseSeriesByTag parses seriesByTag('name=cpu','dc=~us.*') query
+func ParseSeriesByTag(query string) ([]TaggedTerm, error) {
+	if !strings.HasPrefix(query, "seriesByTag(") || !strings.HasSuffix(query, ")") {
+		return nil, fmt.Errorf("invalid seriesByTag query %#v", query)
+	}
+	args := query[len("seriesByTag(") : len(query)-1]
+
+	var terms []TaggedTerm
+
+	for len(args) > 0 {
+		args = strings.TrimLeft(args, " ,")
+		if len(args) == 0 {
+			break
+		}
+
+		quote := args[0]
+		if quote != '\'' && quote != '"' {
+			return nil, fmt.Errorf("seriesByTag argument must be quoted: %#v", args)
+		}
+		end := strings.IndexByte(args[1:], quote)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated seriesByTag argument: %#v", args)
+		}
+		expr := args[1 : end+1]
+		args = args[end+2:]
+
+		term, err := parseTaggedTerm(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		terms = append(terms, term)
+	}
+
+	return terms, nil
+}
+
+// parseTaggedTerm splits expression by first operator, so value may contain "=" and "!="
+func parseTaggedTerm(expr string) (TaggedTerm, error) {
+	eq := strings.IndexByte(expr, '=')
+	if eq <= 0 {
+		return TaggedTerm{}, fmt.Errorf("invalid seriesByTag expression %#v", expr)
+	}
+
+	keyEnd, op := eq, "="
+	if expr[eq-1] == '!' {
+		keyEnd, op = eq-1, "!="
+	}
+
+	valueStart := eq + 1
+	if valueStart < len(expr) && expr[valueStart] == '~' {
+		op += "~"
+		valueStart++
+	}
+
+	key := strings.TrimSpace(expr[:keyEnd])
+	if key == "" {
+		return TaggedTerm{}, fmt.Errorf("invalid seriesByTag expression %#v", expr)
+	}
+
+	return TaggedTerm{Key: key, Op: op, Value: strings.TrimSpace(expr[valueStart:])}, nil
+}
+
+type TaggedFinder struct {
+	wrapped Finder
+	ctx     context.Context // for clickhouse.Query
+	url     string          // clickhouse dsn
+	table   string          // graphite_tagged table
+	timeout time.Duration   // clickhouse query timeout
+	handled bool            // query is seriesByTag
+	body    []byte          // clickhouse response
+}
+
+func WrapTagged(f Finder, ctx context.Context, url string, table string, timeout time.Duration) *TaggedFinder {
+	return &TaggedFinder{
+		wrapped: f,
+		ctx:     ctx,
+		url:     url,
+		table:   table,
+		timeout: timeout,
+	}
+}
+
+// versionCond selects rows of last index build
+func (t *TaggedFinder) versionCond() sqlb.Cond {
+	return sqlb.Ge("Version", sqlb.NewSelect("Max(Version)").From(t.table).Where(
+		sqlb.Eq("Tag1", ""),
+		sqlb.Eq("Path", ""),
+	))
+}
+
+// MakeSQL returns query of series matched all terms. First tag=value term is used for primary key (Tag1)
+func (t *TaggedFinder) MakeSQL(terms []TaggedTerm) (*sqlb.Query, error) {
+	first := -1
+	for i := 0; i < len(terms); i++ {
+		if terms[i].Op == "=" && terms[i].Value != "" {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return nil, fmt.Errorf("seriesByTag requires at least one tag=value expression")
+	}
+
+	s := sqlb.NewSelect("Path").From(t.table).Where(
+		t.versionCond(),
+		sqlb.Eq("Tag1", terms[first].tag()),
+	)
+
+	for i := 0; i < len(terms); i++ {
+		if i != first {
+			s.Where(terms[i].Where())
+		}
+	}
+
+	return s.GroupBy("Path").Build()
+}
+
+func (t *TaggedFinder) Execute(query string) error {
+	t.handled = strings.HasPrefix(query, "seriesByTag(")
+	if !t.handled {
+		return t.wrapped.Execute(query)
+	}
+
+	terms, err := ParseSeriesByTag(query)
+	if err != nil {
+		return err
+	}
+
+	q, err := t.MakeSQL(terms)
+	if err != nil {
+		return err
+	}
+
+	t.body, err = clickhouse.Query(t.ctx, t.url, q, t.timeout)
+	return err
+}
+
+func (t *TaggedFinder) List() [][]byte {
+	if !t.handled {
+		return t.wrapped.List()
+	}
+
+	if t.body == nil {
+		return [][]byte{}
+	}
+
+	rows := bytes.Split(t.body, []byte{'\n'})
+
+	skip := 0
+	for i := 0; i < len(rows); i++ {
+		if len(rows[i]) == 0 {
+			skip++
+			continue
+		}
+		if skip > 0 {
+			rows[i-skip] = rows[i]
+		}
+	}
+
+	return rows[:len(rows)-skip]
+}
+
+// Series are all rows, tagged series are always leafs
+func (t *TaggedFinder) Series() [][]byte {
+	if !t.handled {
+		return t.wrapped.Series()
+	}
+	return t.List()
+}
+
+func (t *TaggedFinder) Abs(v []byte) []byte {
+	if !t.handled {
+		return t.wrapped.Abs(v)
+	}
+	return v
+}
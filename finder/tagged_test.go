@@ -0,0 +1,144 @@
+package finder
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaggedSQL(t *testing.T) {
+	assert := assert.New(t)
+
+	version := "(Version >= (SELECT Max(Version) FROM graphite_tagged WHERE (Tag1 = {p0:String}) AND (Path = {p1:String})))"
+
+	table := []struct {
+		query  string
+		sql    string
+		params []string
+	}{
+		{
+			"seriesByTag('name=cpu')",
+			"SELECT Path FROM graphite_tagged WHERE " + version + " AND (Tag1 = {p2:String}) GROUP BY Path",
+			[]string{"", "", "__name__=cpu"},
+		},
+		{
+			"seriesByTag('dc!=us', 'name=cpu', \"host=~web[0-9]+\", 'rack=', 'env!=', 'x!=~test')",
+			"SELECT Path FROM graphite_tagged WHERE " + version + " AND (Tag1 = {p2:String})" +
+				" AND (NOT (arrayExists((x) -> x = {p3:String}, Tags)))" +
+				" AND (arrayExists((x) -> match(x, {p4:String}), Tags))" +
+				" AND (NOT (arrayExists((x) -> x LIKE {p5:String}, Tags)))" +
+				" AND (arrayExists((x) -> x LIKE {p6:String}, Tags))" +
+				" AND (NOT (arrayExists((x) -> match(x, {p7:String}), Tags)))" +
+				" GROUP BY Path",
+			[]string{"", "", "__name__=cpu", "dc=us", "^host=(?:web[0-9]+)", "rack=%", "env=%", "^x=(?:test)"},
+		},
+		{
+			"seriesByTag('name=cpu', 'dc.zone+1=~us.*')",
+			"SELECT Path FROM graphite_tagged WHERE " + version + " AND (Tag1 = {p2:String})" +
+				" AND (arrayExists((x) -> match(x, {p3:String}), Tags))" +
+				" GROUP BY Path",
+			[]string{"", "", "__name__=cpu", `^dc\\.zone\\+1=(?:us.*)`},
+		},
+	}
+
+	for _, test := range table {
+		terms, err := ParseSeriesByTag(test.query)
+		if !assert.NoError(err, test.query) {
+			continue
+		}
+
+		f := WrapTagged(nil, context.Background(), "", "graphite_tagged", time.Second)
+		q, err := f.MakeSQL(terms)
+		if !assert.NoError(err, test.query) {
+			continue
+		}
+
+		assert.Equal(test.sql, q.String(), test.query)
+		for i, p := range test.params {
+			assert.Equal(p, q.Params().Get(fmt.Sprintf("param_p%d", i)), test.query)
+		}
+	}
+}
+
+func TestTaggedErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, query := range []string{"seriesByTag(name=cpu)", "seriesByTag('name=cpu", "seriesByTag('cpu')"} {
+		_, err := ParseSeriesByTag(query)
+		assert.Error(err, query)
+	}
+
+	terms, err := ParseSeriesByTag("seriesByTag('name!=cpu')")
+	assert.NoError(err)
+	_, err = WrapTagged(nil, context.Background(), "", "graphite_tagged", time.Second).MakeSQL(terms)
+	assert.Error(err)
+}
+
+func TestParseSeriesByTag(t *testing.T) {
+	assert := assert.New(t)
+
+	terms, err := ParseSeriesByTag("seriesByTag('name=cpu', 'q=a!=b', 'r!=~x=~y', 's =~ z')")
+	assert.NoError(err)
+	assert.Equal([]TaggedTerm{
+		{Key: "name", Op: "=", Value: "cpu"},
+		{Key: "q", Op: "=", Value: "a!=b"},
+		{Key: "r", Op: "!=~", Value: "x=~y"},
+		{Key: "s", Op: "=~", Value: "z"},
+	}, terms)
+
+	for _, query := range []string{"seriesByTag('=cpu')", "seriesByTag('!=cpu')"} {
+		_, err := ParseSeriesByTag(query)
+		assert.Error(err, query)
+	}
+}
+
+func TestSeriesByTag(t *testing.T) {
+	assert := assert.New(t)
+
+	query, err := SeriesByTag([]string{"name=cpu", "host=~we'b", `dc="us"`})
+	assert.NoError(err)
+	assert.Equal(`seriesByTag('name=cpu',"host=~we'b",'dc="us"')`, query)
+
+	terms, err := ParseSeriesByTag(query)
+	assert.NoError(err)
+	assert.Equal([]TaggedTerm{
+		{Key: "name", Op: "=", Value: "cpu"},
+		{Key: "host", Op: "=~", Value: "we'b"},
+		{Key: "dc", Op: "=", Value: `"us"`},
+	}, terms)
+
+	_, err = SeriesByTag([]string{`a='"'`})
+	assert.Error(err)
+}
+
+func TestTagKeysSQL(t *testing.T) {
+	assert := assert.New(t)
+
+	version := "(Version >= (SELECT Max(Version) FROM graphite_tagged WHERE (Tag1 = {p0:String}) AND (Path = {p1:String})))"
+	f := WrapTagged(nil, context.Background(), "", "graphite_tagged", time.Second)
+
+	q, err := f.TagKeysSQL("")
+	assert.NoError(err)
+	assert.Equal("SELECT splitByChar('=', Tag1)[1] AS Key FROM graphite_tagged WHERE "+version+" AND (Tag1 != {p2:String}) GROUP BY Key", q.String())
+
+	q, err = f.TagKeysSQL("h")
+	assert.NoError(err)
+	assert.Equal("SELECT splitByChar('=', Tag1)[1] AS Key FROM graphite_tagged WHERE "+version+" AND (Tag1 != {p2:String}) AND (Tag1 LIKE {p3:String}) GROUP BY Key", q.String())
+	assert.Equal("h%", q.Params().Get("param_p3"))
+
+	q, err = f.TagValuesSQL("dc", "")
+	assert.NoError(err)
+	assert.Equal("SELECT Tag1 FROM graphite_tagged WHERE "+version+" AND (Tag1 LIKE {p2:String}) GROUP BY Tag1", q.String())
+	assert.Equal("dc=%", q.Params().Get("param_p2"))
+
+	// values of all tags
+	q, err = f.TagValuesSQL("", "us.")
+	assert.NoError(err)
+	assert.Equal("SELECT Tag1 FROM graphite_tagged WHERE "+version+" AND (Tag1 != {p2:String}) AND (NOT (Tag1 LIKE {p3:String})) "+
+		"AND (match(Tag1, {p4:String})) GROUP BY Tag1", q.String())
+	// backslash is escaped in query parameter
+	assert.Equal(`^[^=]*=us\\.`, q.Params().Get("param_p4"))
+}
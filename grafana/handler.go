@@ -0,0 +1,200 @@
+package grafana
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/finder"
+	"github.com/lomik/graphite-clickhouse/helper/limit"
+	"github.com/lomik/graphite-clickhouse/render"
+)
+
+// Handler implements API of Grafana JSON (SimpleJSON) datasource: /search, /query, /annotations and /tag-keys
+type Handler struct {
+	config *config.Config
+	render *render.Handler
+}
+
+func NewHandler(config *config.Config, render *render.Handler) *Handler {
+	return &Handler{
+		config: config,
+		render: render,
+	}
+}
+
+type searchRequest struct {
+	Target string `json:"target"`
+}
+
+type queryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	IntervalMs    int64 `json:"intervalMs"`
+	MaxDataPoints int   `json:"maxDataPoints"`
+	Targets       []struct {
+		Target string `json:"target"`
+		RefID  string `json:"refId"`
+		Hide   bool   `json:"hide"`
+	} `json:"targets"`
+}
+
+// datapoint is [value, unix time in ms]. Absent value is null
+type datapoint [2]interface{}
+
+type timeserie struct {
+	Target     string      `json:"target"`
+	Datapoints []datapoint `json:"datapoints"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch path.Base(r.URL.Path) {
+	case "search":
+		h.ServeSearch(w, r)
+	case "query":
+		h.ServeQuery(w, r)
+	case "annotations", "tag-keys":
+		// no annotations and tags for ad hoc filters
+		writeJSON(w, []struct{}{})
+	default:
+		// datasource connection test
+		if strings.TrimSuffix(r.URL.Path, "/") != "/grafana" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("OK"))
+	}
+}
+
+// ServeSearch returns paths matched glob target. Empty target lists first level
+func (h *Handler) ServeSearch(w http.ResponseWriter, r *http.Request) {
+	var request searchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := request.Target
+	if query == "" {
+		query = "*"
+	}
+
+	f := finder.New(r.Context(), h.config)
+	if err := f.Execute(query); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	list := f.List()
+
+	if err := limit.Check("max-metrics-in-find-answer", len(list), h.config.Common.MaxMetricsInFindAnswer); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	result := make([]string, 0, len(list))
+	for _, row := range list {
+		if len(row) == 0 {
+			continue
+		}
+		p, _ := finder.Leaf(row)
+		result = append(result, string(p))
+	}
+
+	writeJSON(w, result)
+}
+
+// ServeQuery returns timeserie of each metric matched targets.
+// Rolled up series are consolidated by average to intervalMs and maxDataPoints
+func (h *Handler) ServeQuery(w http.ResponseWriter, r *http.Request) {
+	var request queryRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from := request.Range.From.Unix()
+	until := request.Range.To.Unix()
+	if from <= 0 || until < from {
+		http.Error(w, "Bad request (invalid range)", http.StatusBadRequest)
+		return
+	}
+
+	result := make([]timeserie, 0)
+
+	for _, target := range request.Targets {
+		if target.Target == "" || target.Hide {
+			continue
+		}
+
+		data, err := h.render.Fetch(r.Context(), target.Target, from, until)
+		if err != nil {
+			http.Error(w, err.Error(), limit.Status(err))
+			return
+		}
+
+		for _, s := range h.render.Series(data, int32(from), int32(until)) {
+			result = append(result, timeserie{
+				Target:     s.Name,
+				Datapoints: datapoints(s, request.IntervalMs, request.MaxDataPoints),
+			})
+		}
+	}
+
+	writeJSON(w, result)
+}
+
+// datapoints consolidates values by average so step is not less than intervalMs and count is not more than maxDataPoints
+func datapoints(s render.Series, intervalMs int64, maxDataPoints int) []datapoint {
+	n := len(s.Values)
+	k := 1
+
+	if s.Step > 0 && intervalMs/1000 > int64(s.Step) {
+		k = int(intervalMs / 1000 / int64(s.Step))
+	}
+
+	if maxDataPoints > 0 {
+		if m := (n + maxDataPoints - 1) / maxDataPoints; m > k {
+			k = m
+		}
+	}
+
+	result := make([]datapoint, 0, (n+k-1)/k)
+
+	for i := 0; i < n; i += k {
+		var sum float64
+		var count int
+		for j := i; j < i+k && j < n; j++ {
+			if !math.IsNaN(s.Values[j]) {
+				sum += s.Values[j]
+				count++
+			}
+		}
+
+		ts := (int64(s.Start) + int64(i)*int64(s.Step)) * 1000
+		if count == 0 {
+			result = append(result, datapoint{nil, ts})
+		} else {
+			result = append(result, datapoint{sum / float64(count), ts})
+		}
+	}
+
+	return result
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
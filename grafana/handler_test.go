@@ -0,0 +1,89 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/tests"
+	"github.com/lomik/graphite-clickhouse/render"
+)
+
+func testHandler(t *testing.T) (*Handler, func()) {
+	srv := httptest.NewServer(&tests.ClickHouse{
+		Index: []byte("a.b\na.c.\n"),
+		Data:  tests.Points("a.b", [2]float64{1000, 1}, [2]float64{1010, 3}, [2]float64{1030, 5}),
+	})
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.Rollup = tests.Rollup(t, tests.RollupXML)
+
+	return NewHandler(cfg, render.NewHandler(cfg)), srv.Close
+}
+
+func request(h *Handler, path string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "http://localhost"+path, strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), "logger", zap.NewNop()))
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestSearch(t *testing.T) {
+	assert := assert.New(t)
+
+	h, stop := testHandler(t)
+	defer stop()
+
+	w := request(h, "/grafana/search", `{"target":"a.*"}`)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal(`["a.b","a.c"]`, w.Body.String())
+
+	w = request(h, "/grafana/search", `{`)
+	assert.Equal(http.StatusBadRequest, w.Code)
+}
+
+func TestQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	h, stop := testHandler(t)
+	defer stop()
+
+	// 1000..1030 unix time
+	query := func(intervalMs int, maxDataPoints int) string {
+		w := request(h, "/grafana/query", fmt.Sprintf(`{
+			"range": {"from": "1970-01-01T00:16:40Z", "to": "1970-01-01T00:17:10Z"},
+			"intervalMs": %d,
+			"maxDataPoints": %d,
+			"targets": [{"target": "a.*", "refId": "A"}, {"target": "", "refId": "B"}]
+		}`, intervalMs, maxDataPoints))
+		assert.Equal(http.StatusOK, w.Code, w.Body.String())
+		return w.Body.String()
+	}
+
+	assert.Equal(`[{"target":"a.b","datapoints":[[1,1000000],[3,1010000],[null,1020000],[5,1030000]]}]`, query(0, 0))
+	// consolidated by intervalMs
+	assert.Equal(`[{"target":"a.b","datapoints":[[2,1000000],[5,1020000]]}]`, query(20000, 0))
+	// consolidated by maxDataPoints
+	assert.Equal(`[{"target":"a.b","datapoints":[[3,1000000]]}]`, query(0, 1))
+}
+
+func TestOther(t *testing.T) {
+	assert := assert.New(t)
+
+	h, stop := testHandler(t)
+	defer stop()
+
+	assert.Equal("OK", request(h, "/grafana/", "").Body.String())
+	assert.Equal("[]", request(h, "/grafana/annotations", `{}`).Body.String())
+	assert.Equal("[]", request(h, "/grafana/tag-keys", `{}`).Body.String())
+	assert.Equal(http.StatusNotFound, request(h, "/grafana/unknown", `{}`).Code)
+}
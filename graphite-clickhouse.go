@@ -6,12 +6,20 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"runtime"
 	"sync/atomic"
 	"time"
 
 	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/expand"
 	"github.com/lomik/graphite-clickhouse/find"
+	"github.com/lomik/graphite-clickhouse/grafana"
+	"github.com/lomik/graphite-clickhouse/index"
+	"github.com/lomik/graphite-clickhouse/info"
+	"github.com/lomik/graphite-clickhouse/opentsdb"
+	"github.com/lomik/graphite-clickhouse/prometheus"
+	"github.com/lomik/graphite-clickhouse/receiver"
 	"github.com/lomik/graphite-clickhouse/render"
 	"github.com/lomik/graphite-clickhouse/tagger"
 	"github.com/lomik/zapwriter"
@@ -83,6 +91,9 @@ func main() {
 	printDefaultConfig := flag.Bool("config-print-default", false, "Print default config")
 	checkConfig := flag.Bool("check-config", false, "Check config and exit")
 	tags := flag.Bool("tags", false, "Build tags table")
+	tagsTagged := flag.Bool("tags-tagged", false, "Build index of tagged series (name;tag=value) in tagged-table")
+	tagsExplain := flag.String("tags-explain", "", "Print tags of metric path and rules matched it")
+	tagsExplainFile := flag.String("tags-explain-file", "", "Print tags of each path from file (one per line)")
 
 	printVersion := flag.Bool("version", false, "Print version")
 
@@ -126,10 +137,60 @@ func main() {
 		return
 	}
 
+	if *tagsTagged {
+		if _, err := tagger.MakeTagged(cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *tagsExplain != "" || *tagsExplainFile != "" {
+		var paths []string
+		if *tagsExplain != "" {
+			paths = append(paths, *tagsExplain)
+		}
+		if err := tagger.ExplainPaths(cfg, paths, *tagsExplainFile, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	/* CONSOLE COMMANDS end */
 
+	renderHandler := render.NewHandler(cfg)
+
+	writer, err := receiver.NewWriter(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	http.Handle("/metrics/find/", Handler(zapwriter.Default(), find.NewHandler(cfg)))
-	http.Handle("/render/", Handler(zapwriter.Default(), render.NewHandler(cfg)))
+	http.Handle("/metrics/expand", Handler(zapwriter.Default(), expand.NewHandler(cfg)))
+	http.Handle("/metrics/index.json", Handler(zapwriter.Default(), index.NewHandler(cfg)))
+	http.Handle("/render/", Handler(zapwriter.Default(), renderHandler))
+	http.Handle("/info/", Handler(zapwriter.Default(), info.NewHandler(cfg)))
+	http.Handle("/grafana/", Handler(zapwriter.Default(), grafana.NewHandler(cfg, renderHandler)))
+	http.Handle("/api/v1/", Handler(zapwriter.Default(), prometheus.NewHandler(cfg, renderHandler, writer)))
+
+	opentsdbHandler := opentsdb.NewHandler(cfg, renderHandler)
+	http.Handle("/api/query", Handler(zapwriter.Default(), opentsdbHandler))
+	http.Handle("/api/suggest", Handler(zapwriter.Default(), opentsdbHandler))
+
+	scheduler := tagger.NewScheduler(cfg)
+	if cfg.Tags.Interval.Value() > 0 {
+		scheduler.Start()
+	}
+	http.Handle("/admin/tagger/", Handler(zapwriter.Default(), scheduler))
+
+	if cfg.Receiver.Enabled() || cfg.Receiver.PrometheusWrite {
+		writer.Start()
+	}
+
+	if cfg.Receiver.Enabled() {
+		if err = receiver.New(cfg, writer).Start(); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	http.Handle("/", Handler(zapwriter.Default(), http.HandlerFunc(http.NotFound)))
 
@@ -3,6 +3,7 @@ package RowBinary
 import (
 	"encoding/binary"
 	"io"
+	"math"
 	"time"
 )
 
@@ -74,3 +75,9 @@ func (w *Encoder) StringList(value []string) error {
 
 	return nil
 }
+
+func (w *Encoder) Float64(value float64) error {
+	binary.LittleEndian.PutUint64(w.buffer, math.Float64bits(value))
+	_, err := w.wrapped.Write(w.buffer[:8])
+	return err
+}
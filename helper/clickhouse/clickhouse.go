@@ -1,16 +1,19 @@
 package clickhouse
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
 	"github.com/lomik/zapwriter"
 
 	"go.uber.org/zap"
@@ -29,32 +32,64 @@ func formatSQL(q string) string {
 	return strings.Join(s, " ")
 }
 
-func Escape(s string) string {
-	s = strings.Replace(s, `\`, `\\`, -1)
-	s = strings.Replace(s, `'`, `\'`, -1)
-	return s
-}
-
-func Query(ctx context.Context, dsn string, query string, timeout time.Duration) ([]byte, error) {
+func Query(ctx context.Context, dsn string, query *sqlb.Query, timeout time.Duration) ([]byte, error) {
 	return Post(ctx, dsn, query, nil, timeout)
 }
 
-func Post(ctx context.Context, dsn string, query string, postBody io.Reader, timeout time.Duration) ([]byte, error) {
-	return do(ctx, dsn, query, postBody, false, timeout)
+func Post(ctx context.Context, dsn string, query *sqlb.Query, postBody io.Reader, timeout time.Duration) ([]byte, error) {
+	return do(ctx, dsn, query.String(), query.Params(), postBody, "", false, timeout)
 }
 
-func PostGzip(ctx context.Context, dsn string, query string, postBody io.Reader, timeout time.Duration) ([]byte, error) {
-	return do(ctx, dsn, query, postBody, true, timeout)
+func PostGzip(ctx context.Context, dsn string, query *sqlb.Query, postBody io.Reader, timeout time.Duration) ([]byte, error) {
+	return do(ctx, dsn, query.String(), query.Params(), postBody, "", true, timeout)
 }
 
-func do(ctx context.Context, dsn string, query string, postBody io.Reader, gzip bool, timeout time.Duration) (body []byte, err error) {
-	start := time.Now()
+// QueryWithExternalData sends tables as temporary tables (multipart/form-data) with query.
+// Tables are available in query by name: SELECT ... WHERE Path IN _paths
+func QueryWithExternalData(ctx context.Context, dsn string, query *sqlb.Query, tables []*ExternalTable, timeout time.Duration) ([]byte, error) {
+	postBody := new(bytes.Buffer)
+	writer := multipart.NewWriter(postBody)
+	params := url.Values{}
+
+	for k, v := range query.Params() {
+		params[k] = v
+	}
+
+	for _, t := range tables {
+		part, err := writer.CreateFormFile(t.Name, t.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err = part.Write(t.body.Bytes()); err != nil {
+			return nil, err
+		}
 
+		params.Set(t.Name+"_structure", t.Structure)
+		params.Set(t.Name+"_format", t.Format)
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return do(ctx, dsn, query.String(), params, postBody, writer.FormDataContentType(), false, timeout)
+}
+
+// queryLogger returns logger with shortened query
+func queryLogger(query string) *zap.Logger {
 	queryForLogger := query
 	if len(queryForLogger) > 500 {
 		queryForLogger = queryForLogger[:495] + "<...>"
 	}
-	logger := zapwriter.Logger("query").With(zap.String("query", formatSQL(queryForLogger)))
+	return zapwriter.Logger("query").With(zap.String("query", formatSQL(queryForLogger)))
+}
+
+// do sends query. Query is passed in URL if postBody is set, otherwise in body. params are always passed in URL
+func do(ctx context.Context, dsn string, query string, params url.Values, postBody io.Reader, contentType string, gzip bool, timeout time.Duration) (body []byte, err error) {
+	start := time.Now()
+
+	logger := queryLogger(query)
 
 	defer func() {
 		d := time.Since(start)
@@ -69,45 +104,92 @@ func do(ctx context.Context, dsn string, query string, postBody io.Reader, gzip
 		}
 	}()
 
-	p, err := url.Parse(dsn)
+	resp, err := send(ctx, dsn, query, params, postBody, contentType, gzip, timeout)
 	if err != nil {
 		return
 	}
+	defer resp.Body.Close()
+
+	body, _ = ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode != 200 {
+		err = fmt.Errorf("clickhouse response status %d: %s", resp.StatusCode, string(body))
+		return
+	}
+
+	return
+}
+
+// Reader sends query and returns response body for streaming read of large results. Caller must close it.
+// Timeout limits whole read, not only response headers
+func Reader(ctx context.Context, dsn string, query *sqlb.Query, timeout time.Duration) (body io.ReadCloser, err error) {
+	start := time.Now()
+
+	logger := queryLogger(query.String())
+
+	defer func() {
+		log := logger.With(
+			zap.Duration("time", time.Since(start)),
+		)
+		if err != nil {
+			log.Error("query", zap.Error(err))
+		} else {
+			log.Info("query")
+		}
+	}()
+
+	resp, err := send(ctx, dsn, query.String(), query.Params(), nil, "", false, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		message, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("clickhouse response status %d: %s", resp.StatusCode, string(message))
+	}
+
+	return resp.Body, nil
+}
+
+// send makes http request to clickhouse. Request is aborted when ctx is canceled. See do for arguments
+func send(ctx context.Context, dsn string, query string, params url.Values, postBody io.Reader, contentType string, gzip bool, timeout time.Duration) (*http.Response, error) {
+	p, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	q := p.Query()
+	for k, v := range params {
+		q[k] = v
+	}
 
 	if postBody != nil {
-		q := p.Query()
 		q.Set("query", query)
-		p.RawQuery = q.Encode()
 	} else {
 		postBody = strings.NewReader(query)
 	}
 
+	p.RawQuery = q.Encode()
+
 	url := p.String()
 
 	req, err := http.NewRequest("POST", url, postBody)
 	if err != nil {
-		return
+		return nil, err
 	}
+	req = req.WithContext(ctx)
 
 	if gzip {
 		req.Header.Add("Content-Encoding", "gzip")
 	}
 
-	client := &http.Client{Timeout: timeout}
-	resp, err := client.Do(req)
-	if err != nil {
-		return
-	}
-	defer resp.Body.Close()
-
-	body, _ = ioutil.ReadAll(resp.Body)
-
-	if resp.StatusCode != 200 {
-		err = fmt.Errorf("clickhouse response status %d: %s", resp.StatusCode, string(body))
-		return
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
 	}
 
-	return
+	client := &http.Client{Timeout: timeout}
+	return client.Do(req)
 }
 
 func ReadUvarint(array []byte) (uint64, int, error) {
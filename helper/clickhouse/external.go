@@ -0,0 +1,47 @@
+package clickhouse
+
+import (
+	"bytes"
+)
+
+// ExternalTable is temporary table for sending with query.
+// See https://clickhouse.yandex/docs/en/table_engines/external_data.html
+type ExternalTable struct {
+	Name      string // table name in query
+	Structure string // columns, "Path String"
+	Format    string // TabSeparated
+	body      bytes.Buffer
+	rows      int
+}
+
+// NewExternalTable creates one column TabSeparated table
+func NewExternalTable(name string, structure string) *ExternalTable {
+	return &ExternalTable{
+		Name:      name,
+		Structure: structure,
+		Format:    "TabSeparated",
+	}
+}
+
+// Append adds row with single value
+func (t *ExternalTable) Append(value []byte) {
+	for _, c := range value {
+		switch c {
+		case '\\':
+			t.body.WriteString(`\\`)
+		case '\t':
+			t.body.WriteString(`\t`)
+		case '\n':
+			t.body.WriteString(`\n`)
+		default:
+			t.body.WriteByte(c)
+		}
+	}
+	t.body.WriteByte('\n')
+	t.rows++
+}
+
+// Len returns rows count
+func (t *ExternalTable) Len() int {
+	return t.rows
+}
@@ -0,0 +1,80 @@
+package clickhouse
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+)
+
+func TestExternalTableAppend(t *testing.T) {
+	assert := assert.New(t)
+
+	table := NewExternalTable("_paths", "Path String")
+	table.Append([]byte("hello.world"))
+	table.Append([]byte("tab\there"))
+	table.Append([]byte(`back\slash`))
+
+	assert.Equal(3, table.Len())
+	assert.Equal("hello.world\ntab\\there\nback\\\\slash\n", table.body.String())
+}
+
+func TestQueryWithExternalData(t *testing.T) {
+	assert := assert.New(t)
+
+	type request struct {
+		query     string
+		structure string
+		format    string
+		data      string
+	}
+
+	requests := make(chan request, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{
+			query:     r.URL.Query().Get("query"),
+			structure: r.URL.Query().Get("_paths_structure"),
+			format:    r.URL.Query().Get("_paths_format"),
+		}
+
+		file, _, err := r.FormFile("_paths")
+		if err == nil {
+			body, _ := ioutil.ReadAll(file)
+			req.data = string(body)
+		}
+
+		requests <- req
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	table := NewExternalTable("_paths", "Path String")
+	table.Append([]byte("hello.world"))
+
+	q, err := sqlb.NewSelect("Path").From("graphite").Where(sqlb.InTable("Path", "_paths")).Build()
+	assert.NoError(err)
+
+	body, err := QueryWithExternalData(
+		context.Background(),
+		srv.URL,
+		q,
+		[]*ExternalTable{table},
+		time.Second,
+	)
+
+	assert.NoError(err)
+	assert.Equal("ok", string(body))
+
+	req := <-requests
+	assert.Equal("SELECT Path FROM graphite WHERE (Path IN _paths)", req.query)
+	assert.Equal("Path String", req.structure)
+	assert.Equal("TabSeparated", req.format)
+	assert.Equal("hello.world\n", req.data)
+}
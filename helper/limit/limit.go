@@ -0,0 +1,44 @@
+package limit
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+)
+
+// Rejected counts requests rejected by limits. Key is limit name
+var Rejected = expvar.NewMap("limit_rejected")
+
+// Error is returned when value exceeds limit
+type Error struct {
+	Name  string // config option name
+	Value int
+	Max   int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s limit exceeded: %d > %d", e.Name, e.Value, e.Max)
+}
+
+// Check returns *Error and increments Rejected counter if value > max. Zero max means unlimited
+func Check(name string, value int, max int) error {
+	if max <= 0 || value <= max {
+		return nil
+	}
+
+	Rejected.Add(name, 1)
+
+	return &Error{
+		Name:  name,
+		Value: value,
+		Max:   max,
+	}
+}
+
+// Status returns HTTP status of error: 403 for rejected by limits requests and 500 for other errors
+func Status(err error) int {
+	if _, ok := err.(*Error); ok {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
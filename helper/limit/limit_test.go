@@ -0,0 +1,50 @@
+package limit
+
+import (
+	"errors"
+	"expvar"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	table := []struct {
+		value       int
+		max         int
+		expectedErr bool
+	}{
+		{100, 0, false},
+		{10, 10, false},
+		{11, 10, true},
+		{0, 1, false},
+	}
+
+	// counter is global, compare with value before test
+	var before int64
+	if v, ok := Rejected.Get("test-limit").(*expvar.Int); ok {
+		before = v.Value()
+	}
+
+	for _, test := range table {
+		err := Check("test-limit", test.value, test.max)
+		if test.expectedErr {
+			assert.Error(err)
+			assert.Equal("test-limit limit exceeded: 11 > 10", err.Error())
+		} else {
+			assert.NoError(err)
+		}
+	}
+
+	assert.Equal(before+1, Rejected.Get("test-limit").(*expvar.Int).Value())
+}
+
+func TestStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(http.StatusForbidden, Status(Check("test-status", 2, 1)))
+	assert.Equal(http.StatusInternalServerError, Status(errors.New("clickhouse is down")))
+}
@@ -0,0 +1,104 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+var EmptyArray = []byte{0x90}
+
+// Msgpack encoder. Arrays and maps are prefixed with length, so count of items must be known before write
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (p *Writer) header(fix byte, fixMax int, code16 byte, code32 byte, n int) {
+	if n <= fixMax {
+		p.w.Write([]byte{fix | byte(n)})
+		return
+	}
+
+	if n <= math.MaxUint16 {
+		var b [3]byte
+		b[0] = code16
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		p.w.Write(b[:])
+		return
+	}
+
+	var b [5]byte
+	b[0] = code32
+	binary.BigEndian.PutUint32(b[1:], uint32(n))
+	p.w.Write(b[:])
+}
+
+// Array starts array of n items
+func (p *Writer) Array(n int) {
+	p.header(0x90, 15, 0xdc, 0xdd, n)
+}
+
+// Map starts map of n key-value pairs
+func (p *Writer) Map(n int) {
+	p.header(0x80, 15, 0xde, 0xdf, n)
+}
+
+// Bytes writes byt as str, graphite-web decodes it to unicode
+func (p *Writer) Bytes(byt []byte) {
+	l := len(byt)
+
+	if l > 31 && l <= math.MaxUint8 {
+		// str 8, arrays and maps have no 8-bit length form
+		p.w.Write([]byte{0xd9, byte(l)})
+	} else {
+		p.header(0xa0, 31, 0xda, 0xdb, l)
+	}
+
+	p.w.Write(byt)
+}
+
+func (p *Writer) String(v string) {
+	p.Bytes([]byte(v))
+}
+
+func (p *Writer) Uint32(v uint32) {
+	switch {
+	case v < 128:
+		p.w.Write([]byte{byte(v)})
+	case v <= math.MaxUint8:
+		p.w.Write([]byte{0xcc, byte(v)})
+	case v <= math.MaxUint16:
+		var b [3]byte
+		b[0] = 0xcd
+		binary.BigEndian.PutUint16(b[1:], uint16(v))
+		p.w.Write(b[:])
+	default:
+		var b [5]byte
+		b[0] = 0xce
+		binary.BigEndian.PutUint32(b[1:], v)
+		p.w.Write(b[:])
+	}
+}
+
+func (p *Writer) Float64(v float64) {
+	var b [9]byte
+	b[0] = 0xcb
+	binary.BigEndian.PutUint64(b[1:], math.Float64bits(v))
+	p.w.Write(b[:])
+}
+
+func (p *Writer) Nil() {
+	p.w.Write([]byte{0xc0})
+}
+
+func (p *Writer) Bool(b bool) {
+	if b {
+		p.w.Write([]byte{0xc3})
+	} else {
+		p.w.Write([]byte{0xc2})
+	}
+}
@@ -0,0 +1,41 @@
+package msgpack
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	testCase := func(expected []byte, write func(p *Writer)) {
+		buf := new(bytes.Buffer)
+		write(NewWriter(buf))
+		assert.Equal(expected, buf.Bytes())
+	}
+
+	testCase([]byte{0x93}, func(p *Writer) { p.Array(3) })
+	testCase([]byte{0xdc, 0x01, 0x00}, func(p *Writer) { p.Array(256) })
+	testCase([]byte{0xdd, 0x00, 0x01, 0x00, 0x00}, func(p *Writer) { p.Array(65536) })
+	testCase([]byte{0x82}, func(p *Writer) { p.Map(2) })
+	testCase([]byte{0xde, 0x00, 0x10}, func(p *Writer) { p.Map(16) })
+
+	testCase([]byte{0xa3, 'a', 'b', 'c'}, func(p *Writer) { p.String("abc") })
+	testCase(append([]byte{0xd9, 32}, strings.Repeat("x", 32)...), func(p *Writer) { p.String(strings.Repeat("x", 32)) })
+	testCase(append([]byte{0xda, 0x01, 0x00}, strings.Repeat("x", 256)...), func(p *Writer) { p.String(strings.Repeat("x", 256)) })
+
+	testCase([]byte{0x7f}, func(p *Writer) { p.Uint32(127) })
+	testCase([]byte{0xcc, 0x80}, func(p *Writer) { p.Uint32(128) })
+	testCase([]byte{0xcd, 0x01, 0x00}, func(p *Writer) { p.Uint32(256) })
+	testCase([]byte{0xce, 0x5a, 0x00, 0x00, 0x00}, func(p *Writer) { p.Uint32(1509949440) })
+
+	testCase([]byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, func(p *Writer) { p.Float64(1.5) })
+	testCase([]byte{0xc0, 0xc3, 0xc2}, func(p *Writer) {
+		p.Nil()
+		p.Bool(true)
+		p.Bool(false)
+	})
+}
@@ -0,0 +1,422 @@
+package pickle
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var ErrTruncated = errors.New("pickle: unexpected end of data")
+
+// Tuple is decoded python tuple, list is decoded to []interface{}
+type Tuple []interface{}
+
+// mark is stack marker of '(' opcode
+type mark struct{}
+
+// Unpickle decodes data of protocols 0-4 with python values used by carbon pickle protocol: lists, tuples,
+// str, unicode, bytes, int, long, float, bool and None. Other values (dicts, objects) are rejected.
+// Ints are decoded to int64, floats to float64, all strings to string
+func Unpickle(data []byte) (interface{}, error) {
+	u := &unpickler{data: data, memo: make(map[uint32]interface{})}
+	return u.run()
+}
+
+type unpickler struct {
+	data  []byte
+	pos   int
+	stack []interface{}
+	memo  map[uint32]interface{}
+}
+
+func (u *unpickler) read(n int) ([]byte, error) {
+	if n < 0 || len(u.data)-u.pos < n {
+		return nil, ErrTruncated
+	}
+	b := u.data[u.pos : u.pos+n]
+	u.pos += n
+	return b, nil
+}
+
+// readLine returns bytes before '\n'
+func (u *unpickler) readLine() (string, error) {
+	i := strings.IndexByte(string(u.data[u.pos:]), '\n')
+	if i < 0 {
+		return "", ErrTruncated
+	}
+	line := string(u.data[u.pos : u.pos+i])
+	u.pos += i + 1
+	return line, nil
+}
+
+func (u *unpickler) push(v interface{}) {
+	u.stack = append(u.stack, v)
+}
+
+func (u *unpickler) pop() (interface{}, error) {
+	if len(u.stack) == 0 {
+		return nil, errors.New("pickle: stack underflow")
+	}
+	v := u.stack[len(u.stack)-1]
+	u.stack = u.stack[:len(u.stack)-1]
+	if _, ok := v.(mark); ok {
+		return nil, errors.New("pickle: unexpected mark")
+	}
+	return v, nil
+}
+
+func (u *unpickler) top() (interface{}, error) {
+	if len(u.stack) == 0 {
+		return nil, errors.New("pickle: stack underflow")
+	}
+	return u.stack[len(u.stack)-1], nil
+}
+
+// popMark returns items after last mark and removes them with mark from stack
+func (u *unpickler) popMark() ([]interface{}, error) {
+	for i := len(u.stack) - 1; i >= 0; i-- {
+		if _, ok := u.stack[i].(mark); ok {
+			items := make([]interface{}, len(u.stack)-i-1)
+			copy(items, u.stack[i+1:])
+			u.stack = u.stack[:i]
+			return items, nil
+		}
+	}
+	return nil, errors.New("pickle: mark not found")
+}
+
+func (u *unpickler) popN(n int) ([]interface{}, error) {
+	if len(u.stack) < n {
+		return nil, errors.New("pickle: stack underflow")
+	}
+	items := make([]interface{}, n)
+	copy(items, u.stack[len(u.stack)-n:])
+	u.stack = u.stack[:len(u.stack)-n]
+	return items, nil
+}
+
+// appendTo appends items to list on top of stack
+func (u *unpickler) appendTo(items ...interface{}) error {
+	v, err := u.top()
+	if err != nil {
+		return err
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("pickle: append to %T", v)
+	}
+	u.stack[len(u.stack)-1] = append(list, items...)
+	return nil
+}
+
+func (u *unpickler) memoize(key uint32) error {
+	v, err := u.top()
+	if err != nil {
+		return err
+	}
+	u.memo[key] = v
+	return nil
+}
+
+func (u *unpickler) get(key uint32) error {
+	v, ok := u.memo[key]
+	if !ok {
+		return fmt.Errorf("pickle: memo key %d not found", key)
+	}
+	u.push(v)
+	return nil
+}
+
+func (u *unpickler) uint(n int) (uint64, error) {
+	b, err := u.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for i := n - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, nil
+}
+
+// decodeLong decodes little-endian two's complement integer of LONG1 and LONG4
+func decodeLong(b []byte) (int64, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if len(b) > 8 {
+		return 0, errors.New("pickle: long is out of int64 range")
+	}
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	if b[len(b)-1]&0x80 != 0 && len(b) < 8 {
+		// sign extension
+		v |= math.MaxUint64 << (8 * uint(len(b)))
+	}
+	return int64(v), nil
+}
+
+// parseInt parses text of INT and LONG opcodes. "01" and "00" of INT are True and False
+func parseInt(s string) (interface{}, error) {
+	switch s {
+	case "01":
+		return true, nil
+	case "00":
+		return false, nil
+	}
+	s = strings.TrimSuffix(s, "L")
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return v, nil
+	}
+	if _, ok := new(big.Int).SetString(s, 10); ok {
+		return nil, errors.New("pickle: long is out of int64 range")
+	}
+	return nil, fmt.Errorf("pickle: invalid int %#v", s)
+}
+
+// unquote decodes python repr of str: 'text' or "text" with backslash escapes
+func unquote(s string) (string, error) {
+	if len(s) < 2 || (s[0] != '\'' && s[0] != '"') || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("pickle: invalid string %#v", s)
+	}
+
+	inner := s[1 : len(s)-1]
+	if s[0] == '\'' {
+		inner = strings.Replace(inner, "\\'", "'", -1)
+		inner = strings.Replace(inner, "\"", "\\\"", -1)
+	}
+
+	v, err := strconv.Unquote("\"" + inner + "\"")
+	if err != nil {
+		return "", fmt.Errorf("pickle: invalid string %#v", s)
+	}
+	return v, nil
+}
+
+func (u *unpickler) run() (interface{}, error) {
+	for {
+		b, err := u.read(1)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op := b[0]; op {
+		case 0x80: // PROTO
+			if _, err = u.read(1); err != nil {
+				return nil, err
+			}
+		case 0x95: // FRAME
+			if _, err = u.read(8); err != nil {
+				return nil, err
+			}
+		case '.': // STOP
+			return u.pop()
+
+		case '(': // MARK
+			u.push(mark{})
+		case ']': // EMPTY_LIST
+			u.push([]interface{}{})
+		case ')': // EMPTY_TUPLE
+			u.push(Tuple{})
+		case 'l': // LIST
+			items, err := u.popMark()
+			if err != nil {
+				return nil, err
+			}
+			u.push(items)
+		case 't': // TUPLE
+			items, err := u.popMark()
+			if err != nil {
+				return nil, err
+			}
+			u.push(Tuple(items))
+		case 0x85, 0x86, 0x87: // TUPLE1, TUPLE2, TUPLE3
+			items, err := u.popN(int(op - 0x84))
+			if err != nil {
+				return nil, err
+			}
+			u.push(Tuple(items))
+		case 'a': // APPEND
+			v, err := u.pop()
+			if err != nil {
+				return nil, err
+			}
+			if err = u.appendTo(v); err != nil {
+				return nil, err
+			}
+		case 'e': // APPENDS
+			items, err := u.popMark()
+			if err != nil {
+				return nil, err
+			}
+			if err = u.appendTo(items...); err != nil {
+				return nil, err
+			}
+
+		case 'N': // NONE
+			u.push(nil)
+		case 0x88: // NEWTRUE
+			u.push(true)
+		case 0x89: // NEWFALSE
+			u.push(false)
+
+		case 'I', 'L': // INT, LONG
+			line, err := u.readLine()
+			if err != nil {
+				return nil, err
+			}
+			v, err := parseInt(line)
+			if err != nil {
+				return nil, err
+			}
+			u.push(v)
+		case 'J': // BININT
+			v, err := u.uint(4)
+			if err != nil {
+				return nil, err
+			}
+			u.push(int64(int32(v)))
+		case 'K': // BININT1
+			v, err := u.uint(1)
+			if err != nil {
+				return nil, err
+			}
+			u.push(int64(v))
+		case 'M': // BININT2
+			v, err := u.uint(2)
+			if err != nil {
+				return nil, err
+			}
+			u.push(int64(v))
+		case 0x8a, 0x8b: // LONG1, LONG4
+			size := 1
+			if op == 0x8b {
+				size = 4
+			}
+			n, err := u.uint(size)
+			if err != nil {
+				return nil, err
+			}
+			s, err := u.read(int(n))
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeLong(s)
+			if err != nil {
+				return nil, err
+			}
+			u.push(v)
+
+		case 'F': // FLOAT
+			line, err := u.readLine()
+			if err != nil {
+				return nil, err
+			}
+			v, err := strconv.ParseFloat(line, 64)
+			if err != nil {
+				return nil, fmt.Errorf("pickle: invalid float %#v", line)
+			}
+			u.push(v)
+		case 'G': // BINFLOAT
+			s, err := u.read(8)
+			if err != nil {
+				return nil, err
+			}
+			u.push(math.Float64frombits(binary.BigEndian.Uint64(s)))
+
+		case 'S', 'V': // STRING, UNICODE
+			line, err := u.readLine()
+			if err != nil {
+				return nil, err
+			}
+			if op == 'S' {
+				if line, err = unquote(line); err != nil {
+					return nil, err
+				}
+			}
+			u.push(line)
+		case 'U', 0x8c, 'C': // SHORT_BINSTRING, SHORT_BINUNICODE, SHORT_BINBYTES
+			n, err := u.uint(1)
+			if err != nil {
+				return nil, err
+			}
+			s, err := u.read(int(n))
+			if err != nil {
+				return nil, err
+			}
+			u.push(string(s))
+		case 'T', 'X', 'B': // BINSTRING, BINUNICODE, BINBYTES
+			n, err := u.uint(4)
+			if err != nil {
+				return nil, err
+			}
+			s, err := u.read(int(n))
+			if err != nil {
+				return nil, err
+			}
+			u.push(string(s))
+
+		case 'p': // PUT
+			line, err := u.readLine()
+			if err != nil {
+				return nil, err
+			}
+			key, err := strconv.ParseUint(line, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("pickle: invalid memo key %#v", line)
+			}
+			if err = u.memoize(uint32(key)); err != nil {
+				return nil, err
+			}
+		case 'q', 'r': // BINPUT, LONG_BINPUT
+			size := 1
+			if op == 'r' {
+				size = 4
+			}
+			key, err := u.uint(size)
+			if err != nil {
+				return nil, err
+			}
+			if err = u.memoize(uint32(key)); err != nil {
+				return nil, err
+			}
+		case 0x94: // MEMOIZE
+			if err = u.memoize(uint32(len(u.memo))); err != nil {
+				return nil, err
+			}
+		case 'g': // GET
+			line, err := u.readLine()
+			if err != nil {
+				return nil, err
+			}
+			key, err := strconv.ParseUint(line, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("pickle: invalid memo key %#v", line)
+			}
+			if err = u.get(uint32(key)); err != nil {
+				return nil, err
+			}
+		case 'h', 'j': // BINGET, LONG_BINGET
+			size := 1
+			if op == 'j' {
+				size = 4
+			}
+			key, err := u.uint(size)
+			if err != nil {
+				return nil, err
+			}
+			if err = u.get(uint32(key)); err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, fmt.Errorf("pickle: unsupported opcode 0x%02x at %d", op, u.pos-1)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package pickle
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnpickle(t *testing.T) {
+	assert := assert.New(t)
+
+	// pickle.dumps([('a.b.c', (1500000000, 1.5)), ('x.y', (1500000001, 2)), ('z', (1500000002.0, '3')), ('n', (-5, -70000))], protocol=N)
+	expected := []interface{}{
+		Tuple{"a.b.c", Tuple{int64(1500000000), 1.5}},
+		Tuple{"x.y", Tuple{int64(1500000001), int64(2)}},
+		Tuple{"z", Tuple{1500000002.0, "3"}},
+		Tuple{"n", Tuple{int64(-5), int64(-70000)}},
+	}
+
+	table := []string{
+		// protocol 0
+		"286c70300a2856612e622e630a70310a2849313530303030303030300a46312e350a7470320a7470330a612856782e790a70340a2849313530303030303030310a49320a7470350a7470360a6128567a0a70370a2846313530303030303030322e300a56330a70380a7470390a747031300a6128566e0a7031310a28492d350a492d37303030300a747031320a747031330a612e",
+		// protocol 1
+		"5d710028285805000000612e622e637101284a002f6859473ff8000000000000747102747103285803000000782e797104284a012f68594b027471057471062858010000007a7107284741d65a0bc0800000580100000033710874710974710a2858010000006e710b284afbffffff4a90eefeff74710c74710d652e",
+		// protocol 2
+		"80025d7100285805000000612e622e6371014a002f6859473ff80000000000008671028671035803000000782e7971044a012f68594b0286710586710658010000007a71074741d65a0bc0800000580100000033710886710986710a58010000006e710b4afbffffff4a90eefeff86710c86710d652e",
+		// protocol 4
+		"80049557000000000000005d94288c05612e622e63944a002f6859473ff8000000000000869486948c03782e79944a012f68594b02869486948c017a944741d65a0bc08000008c013394869486948c016e944afbffffff4a90eefeff86948694652e",
+	}
+
+	for i, h := range table {
+		data, err := hex.DecodeString(h)
+		assert.NoError(err)
+
+		v, err := Unpickle(data)
+		if assert.NoError(err, i) {
+			assert.Equal(expected, v, i)
+		}
+	}
+
+	// python 2 protocol 0 with str and memo get
+	v, err := Unpickle([]byte("(lp0\n(S'a.b'\np1\n(L10L\nI01\ntp2\ntp3\nag3\na."))
+	assert.NoError(err)
+	assert.Equal([]interface{}{
+		Tuple{"a.b", Tuple{int64(10), true}},
+		Tuple{"a.b", Tuple{int64(10), true}},
+	}, v)
+
+	_, err = Unpickle([]byte("(lp0\n(S'a.b'\n"))
+	assert.Equal(ErrTruncated, err)
+
+	// dict
+	_, err = Unpickle([]byte("}."))
+	assert.Error(err)
+}
@@ -47,11 +47,12 @@ type Retention struct {
 }
 
 type Pattern struct {
-	Regexp    string                      `xml:"regexp"`
-	Function  string                      `xml:"function"`
-	Retention []*Retention                `xml:"retention"`
-	aggr      func([]point.Point) float64 `xml:"-"`
-	re        *regexp.Regexp              `xml:"-"`
+	Regexp       string                      `xml:"regexp"`
+	Function     string                      `xml:"function"`
+	Retention    []*Retention                `xml:"retention"`
+	XFilesFactor float32                     `xml:"xFilesFactor"` // graphite-clickhouse only, reported to carbonapi
+	aggr         func([]point.Point) float64 `xml:"-"`
+	re           *regexp.Regexp              `xml:"-"`
 }
 
 type Rollup struct {
@@ -150,15 +151,33 @@ func (r *Rollup) Match(metric string) *Pattern {
 }
 
 func (r *Rollup) Step(metric string, from int32) int32 {
-	pattern := r.Match(metric)
+	return r.Match(metric).step(from, int32(time.Now().Unix()))
+}
+
+// MaxStep returns max step of all patterns. Upper bound of Step for any metric
+func (r *Rollup) MaxStep(from int32) int32 {
 	now := int32(time.Now().Unix())
+	maxStep := r.Default.step(from, now)
+
+	for _, pattern := range r.Pattern {
+		if len(pattern.Retention) == 0 {
+			continue
+		}
+		if step := pattern.step(from, now); step > maxStep {
+			maxStep = step
+		}
+	}
+
+	return maxStep
+}
 
-	for i := range pattern.Retention {
-		if i == len(pattern.Retention)-1 || from > now-pattern.Retention[i+1].Age {
-			return pattern.Retention[i].Precision
+func (rr *Pattern) step(from int32, now int32) int32 {
+	for i := range rr.Retention {
+		if i == len(rr.Retention)-1 || from > now-rr.Retention[i+1].Age {
+			return rr.Retention[i].Precision
 		}
 	}
-	return pattern.Retention[len(pattern.Retention)-1].Precision
+	return rr.Retention[len(rr.Retention)-1].Precision
 }
 
 func doMetricPrecision(points []point.Point, precision int32, aggr func([]point.Point) float64) []point.Point {
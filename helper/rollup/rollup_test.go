@@ -186,3 +186,54 @@ func TestMetricStep(t *testing.T) {
 		})
 	}
 }
+
+func TestMaxStep(t *testing.T) {
+	config := `
+<graphite_rollup>
+ 	<pattern>
+ 		<regexp>^metric\.</regexp>
+ 		<function>any</function>
+ 		<retention>
+ 			<age>0</age>
+ 			<precision>1</precision>
+ 		</retention>
+ 		<retention>
+ 			<age>3600</age>
+ 			<precision>600</precision>
+ 		</retention>
+ 	</pattern>
+ 	<default>
+ 		<function>max</function>
+ 		<retention>
+ 			<age>0</age>
+ 			<precision>60</precision>
+ 		</retention>
+ 		<retention>
+ 			<age>86400</age>
+ 			<precision>3600</precision>
+ 		</retention>
+ 	</default>
+</graphite_rollup>
+`
+	r, err := ParseXML([]byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := int32(time.Now().Unix())
+
+	tests := []struct {
+		from         int32
+		expectedStep int32
+	}{
+		{now - 500, 60},
+		{now - 3700, 600},
+		{now - 87000, 3600},
+	}
+
+	for _, test := range tests {
+		step := r.MaxStep(test.from)
+		if step != test.expectedStep {
+			t.Fatalf("from=now-%v, expected step=%v, actual step=%v", now-test.from, test.expectedStep, step)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Package snappy implements snappy block format (https://github.com/google/snappy/blob/master/format_description.txt)
+// used by Prometheus remote read and write. Framing format is not supported
+package snappy
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var ErrCorrupt = errors.New("snappy: corrupt input")
+var ErrTooLarge = errors.New("snappy: decoded block is too large")
+
+const (
+	tagLiteral = 0x00
+	tagCopy1   = 0x01
+	tagCopy2   = 0x02
+	tagCopy4   = 0x03
+)
+
+const (
+	// maxBlockSize is size of independently compressed input chunk, so offset of copy fits 2 bytes
+	maxBlockSize = 65536
+	// maxDecodedLen protects against huge allocations by malformed input. Same as max pickle message of receiver
+	maxDecodedLen = 64 * 1024 * 1024
+
+	hashTableBits = 14
+	minMatch      = 4
+)
+
+// DecodedLen returns length of decoded block
+func DecodedLen(src []byte) (int, error) {
+	v, n := binary.Uvarint(src)
+	if n <= 0 || v > 0xffffffff {
+		return 0, ErrCorrupt
+	}
+	if v > maxDecodedLen {
+		return 0, ErrTooLarge
+	}
+	return int(v), nil
+}
+
+// Decode returns decoded block
+func Decode(src []byte) ([]byte, error) {
+	dLen, err := DecodedLen(src)
+	if err != nil {
+		return nil, err
+	}
+	_, n := binary.Uvarint(src)
+	src = src[n:]
+
+	dst := make([]byte, 0, dLen)
+
+	for len(src) > 0 {
+		var length, offset int
+
+		switch src[0] & 0x03 {
+		case tagLiteral:
+			x := int(src[0] >> 2)
+			s := 1
+			if x >= 60 {
+				// length-1 is stored in next x-59 bytes
+				s += x - 59
+				if len(src) < s {
+					return nil, ErrCorrupt
+				}
+				x = 0
+				for i := s - 1; i > 0; i-- {
+					x = x<<8 | int(src[i])
+				}
+			}
+			length = x + 1
+			if length <= 0 || len(src)-s < length || len(dst)+length > dLen {
+				return nil, ErrCorrupt
+			}
+			dst = append(dst, src[s:s+length]...)
+			src = src[s+length:]
+			continue
+
+		case tagCopy1:
+			if len(src) < 2 {
+				return nil, ErrCorrupt
+			}
+			length = 4 + int(src[0]>>2)&0x07
+			offset = int(src[0]&0xe0)<<3 | int(src[1])
+			src = src[2:]
+
+		case tagCopy2:
+			if len(src) < 3 {
+				return nil, ErrCorrupt
+			}
+			length = 1 + int(src[0]>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:3]))
+			src = src[3:]
+
+		case tagCopy4:
+			if len(src) < 5 {
+				return nil, ErrCorrupt
+			}
+			length = 1 + int(src[0]>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:5]))
+			src = src[5:]
+		}
+
+		if offset <= 0 || offset > len(dst) || len(dst)+length > dLen {
+			return nil, ErrCorrupt
+		}
+
+		// byte by byte: copy may overlap its own output
+		pos := len(dst) - offset
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[pos+i])
+		}
+	}
+
+	if len(dst) != dLen {
+		return nil, ErrCorrupt
+	}
+
+	return dst, nil
+}
+
+// Encode returns encoded block of src. Input is split by 64KB blocks, matches are found with hash table of 4-byte sequences
+func Encode(src []byte) []byte {
+	dst := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(src)+len(src)/6+32)
+	n := binary.PutUvarint(dst, uint64(len(src)))
+	dst = dst[:n]
+
+	var table [1 << hashTableBits]int32
+
+	for len(src) > 0 {
+		block := src
+		if len(block) > maxBlockSize {
+			block = block[:maxBlockSize]
+		}
+		src = src[len(block):]
+
+		for i := range table {
+			table[i] = -1
+		}
+		dst = encodeBlock(dst, block, &table)
+	}
+
+	return dst
+}
+
+func hash(u uint32) uint32 {
+	return (u * 0x1e35a7bd) >> (32 - hashTableBits)
+}
+
+func encodeBlock(dst []byte, src []byte, table *[1 << hashTableBits]int32) []byte {
+	// start of pending literal
+	lit := 0
+
+	for i := 0; i+minMatch <= len(src); {
+		u := binary.LittleEndian.Uint32(src[i:])
+		h := hash(u)
+		candidate := int(table[h])
+		table[h] = int32(i)
+
+		if candidate < 0 || binary.LittleEndian.Uint32(src[candidate:]) != u {
+			i++
+			continue
+		}
+
+		length := minMatch
+		for i+length < len(src) && src[candidate+length] == src[i+length] {
+			length++
+		}
+
+		dst = emitLiteral(dst, src[lit:i])
+		dst = emitCopy(dst, i-candidate, length)
+		i += length
+		lit = i
+	}
+
+	return emitLiteral(dst, src[lit:])
+}
+
+func emitLiteral(dst []byte, lit []byte) []byte {
+	if len(lit) == 0 {
+		return dst
+	}
+
+	n := len(lit) - 1
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2|tagLiteral)
+	case n < 1<<8:
+		dst = append(dst, 60<<2|tagLiteral, byte(n))
+	case n < 1<<16:
+		dst = append(dst, 61<<2|tagLiteral, byte(n), byte(n>>8))
+	case n < 1<<24:
+		dst = append(dst, 62<<2|tagLiteral, byte(n), byte(n>>8), byte(n>>16))
+	default:
+		dst = append(dst, 63<<2|tagLiteral, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+	}
+
+	return append(dst, lit...)
+}
+
+// emitCopy writes copy of length bytes from offset back. offset < 65536
+func emitCopy(dst []byte, offset int, length int) []byte {
+	// copy2 is up to 64 bytes, keep at least 4 bytes for last copy
+	for length >= 68 {
+		dst = append(dst, 63<<2|tagCopy2, byte(offset), byte(offset>>8))
+		length -= 64
+	}
+	if length > 64 {
+		dst = append(dst, 59<<2|tagCopy2, byte(offset), byte(offset>>8))
+		length -= 60
+	}
+
+	if length >= 4 && length < 12 && offset < 2048 {
+		return append(dst, byte(offset>>8)<<5|byte(length-4)<<2|tagCopy1, byte(offset))
+	}
+
+	return append(dst, byte(length-1)<<2|tagCopy2, byte(offset), byte(offset>>8))
+}
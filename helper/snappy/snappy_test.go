@@ -0,0 +1,63 @@
+package snappy
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	// literal
+	d, err := Decode([]byte("\x05\x10hello"))
+	assert.NoError(err)
+	assert.Equal("hello", string(d))
+
+	// literal and overlapping copy1
+	d, err = Decode([]byte("\x0c\x0cabcd\x11\x04"))
+	assert.NoError(err)
+	assert.Equal("abcdabcdabcd", string(d))
+
+	// copy2
+	d, err = Decode([]byte("\x06\x08abc\x0a\x03\x00"))
+	assert.NoError(err)
+	assert.Equal("abcabc", string(d))
+
+	for _, corrupt := range []string{"", "\x05\x10hell", "\x05\x10hello!", "\x04\x11\x04", "\x0c\x0cabcd\x11\x05"} {
+		_, err = Decode([]byte(corrupt))
+		assert.Error(err, "%q", corrupt)
+	}
+
+	// decoded length 64MB+1
+	_, err = Decode([]byte("\x81\x80\x80\x20"))
+	assert.Equal(ErrTooLarge, err)
+}
+
+func TestEncode(t *testing.T) {
+	assert := assert.New(t)
+
+	random := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	for _, src := range [][]byte{
+		{},
+		[]byte("a"),
+		[]byte("hello"),
+		bytes.Repeat([]byte("abcd"), 1000),
+		bytes.Repeat([]byte("x"), 200000),
+		[]byte(`{"metric":"cpu","tags":{"host":"web1"}}{"metric":"cpu","tags":{"host":"web2"}}`),
+		random,
+	} {
+		e := Encode(src)
+		d, err := Decode(e)
+		if assert.NoError(err) {
+			assert.True(bytes.Equal(src, d), "%d bytes", len(src))
+		}
+	}
+
+	// repeated input is compressed
+	assert.True(len(Encode(bytes.Repeat([]byte("abcd"), 1000))) < 200)
+}
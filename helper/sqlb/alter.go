@@ -0,0 +1,61 @@
+package sqlb
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var partitionIDRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Delete is lightweight DELETE query builder
+type Delete struct {
+	table string
+	where []Cond
+}
+
+// NewDelete starts DELETE FROM table query
+func NewDelete(table string) *Delete {
+	return &Delete{table: table}
+}
+
+// Where adds conditions to WHERE. Conditions are joined with AND
+func (s *Delete) Where(conds ...Cond) *Delete {
+	s.where = append(s.where, conds...)
+	return s
+}
+
+// Build returns query or first error. DELETE without conditions is error
+func (s *Delete) Build() (*Query, error) {
+	b := newBuilder()
+
+	sql := "DELETE FROM " + b.table(s.table)
+
+	w := And(s.where...).sql(b)
+	if w == "" {
+		b.fail(fmt.Errorf("DELETE without WHERE"))
+	}
+	sql += " WHERE " + w
+
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return &Query{sql: sql, params: b.params}, nil
+}
+
+// DropPartition returns ALTER TABLE table DROP PARTITION ID 'id' query.
+// ClickHouse does not accept parameters here, so id is validated and quoted
+func DropPartition(table string, id string) (*Query, error) {
+	b := newBuilder()
+
+	sql := "ALTER TABLE " + b.table(table) + " DROP PARTITION ID '" + id + "'"
+	if !partitionIDRe.MatchString(id) {
+		b.fail(fmt.Errorf("invalid partition id %#v", id))
+	}
+
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return &Query{sql: sql, params: b.params}, nil
+}
@@ -0,0 +1,214 @@
+package sqlb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cond is condition for WHERE, PREWHERE and HAVING. nil Cond is skipped by And and Or
+type Cond interface {
+	sql(b *builder) string
+}
+
+type raw string
+
+// Raw returns condition from trusted SQL. Never pass user input here
+func Raw(expr string) Cond {
+	return raw(expr)
+}
+
+func (c raw) sql(b *builder) string {
+	return string(c)
+}
+
+type join struct {
+	op    string
+	conds []Cond
+}
+
+func (c *join) sql(b *builder) string {
+	parts := make([]string, 0, len(c.conds))
+	for _, cond := range c.conds {
+		if cond == nil {
+			continue
+		}
+		s := cond.sql(b)
+		if s == "" {
+			continue
+		}
+		// (a AND b) AND c is a AND b AND c
+		if j, ok := cond.(*join); ok && j.op == c.op {
+			parts = append(parts, s)
+			continue
+		}
+		parts = append(parts, "("+s+")")
+	}
+	return strings.Join(parts, " "+c.op+" ")
+}
+
+// And joins conditions with AND. Each condition is wrapped in brackets
+func And(conds ...Cond) Cond {
+	return &join{op: "AND", conds: conds}
+}
+
+// Or joins conditions with OR. Each condition is wrapped in brackets
+func Or(conds ...Cond) Cond {
+	return &join{op: "OR", conds: conds}
+}
+
+type not struct {
+	cond Cond
+}
+
+// Not negates condition. Not of nil or empty condition is error of Build
+func Not(cond Cond) Cond {
+	return &not{cond: cond}
+}
+
+func (c *not) sql(b *builder) string {
+	var s string
+	if c.cond != nil {
+		s = c.cond.sql(b)
+	}
+	if s == "" {
+		b.fail(fmt.Errorf("NOT of empty condition"))
+		return ""
+	}
+	return "NOT (" + s + ")"
+}
+
+type cmp struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+func (c *cmp) sql(b *builder) string {
+	return fmt.Sprintf("%s %s %s", b.ident(c.column), c.op, b.value(c.value))
+}
+
+// Eq is column = value. Value is string, []byte, integer, Date or *Select
+func Eq(column string, value interface{}) Cond {
+	return &cmp{column: column, op: "=", value: value}
+}
+
+// Ne is column != value
+func Ne(column string, value interface{}) Cond {
+	return &cmp{column: column, op: "!=", value: value}
+}
+
+// Lt is column < value
+func Lt(column string, value interface{}) Cond {
+	return &cmp{column: column, op: "<", value: value}
+}
+
+// Le is column <= value
+func Le(column string, value interface{}) Cond {
+	return &cmp{column: column, op: "<=", value: value}
+}
+
+// Gt is column > value
+func Gt(column string, value interface{}) Cond {
+	return &cmp{column: column, op: ">", value: value}
+}
+
+// Ge is column >= value
+func Ge(column string, value interface{}) Cond {
+	return &cmp{column: column, op: ">=", value: value}
+}
+
+// Like is column LIKE pattern. Pattern is passed as is, see HasPrefix for escaped prefix search
+func Like(column string, pattern string) Cond {
+	return &cmp{column: column, op: "LIKE", value: pattern}
+}
+
+// EscapeLike escapes LIKE special symbols
+func EscapeLike(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `%`, `\%`, -1)
+	s = strings.Replace(s, `_`, `\_`, -1)
+	return s
+}
+
+// HasPrefix is column LIKE 'prefix%' with escaped prefix
+func HasPrefix(column string, prefix string) Cond {
+	return Like(column, EscapeLike(prefix)+"%")
+}
+
+type match struct {
+	column string
+	re     string
+}
+
+// Match is match(column, re)
+func Match(column string, re string) Cond {
+	return &match{column: column, re: re}
+}
+
+func (c *match) sql(b *builder) string {
+	return fmt.Sprintf("match(%s, %s)", b.ident(c.column), b.value(c.re))
+}
+
+type in struct {
+	column string
+	values []string
+	table  string
+	query  *Select
+}
+
+func (c *in) sql(b *builder) string {
+	column := b.ident(c.column)
+
+	if c.query != nil {
+		return fmt.Sprintf("%s IN (%s)", column, c.query.sql(b))
+	}
+
+	if c.table != "" {
+		return fmt.Sprintf("%s IN %s", column, b.table(c.table))
+	}
+
+	if len(c.values) == 0 {
+		return "0"
+	}
+
+	list := make([]string, len(c.values))
+	for i, v := range c.values {
+		list[i] = b.value(v)
+	}
+
+	return fmt.Sprintf("%s IN (%s)", column, strings.Join(list, ","))
+}
+
+// In is column IN (values...). Empty list is false
+func In(column string, values ...string) Cond {
+	return &in{column: column, values: values}
+}
+
+// InTable is column IN table. For external data tables
+func InTable(column string, table string) Cond {
+	return &in{column: column, table: table}
+}
+
+// InSelect is column IN (subquery)
+func InSelect(column string, query *Select) Cond {
+	return &in{column: column, query: query}
+}
+
+type arrayExists struct {
+	x     string
+	array string
+	cond  Cond
+}
+
+// ArrayExists is arrayExists((x) -> cond, array). Use x as column name in cond. nil cond is error of Build
+func ArrayExists(x string, array string, cond Cond) Cond {
+	return &arrayExists{x: x, array: array, cond: cond}
+}
+
+func (c *arrayExists) sql(b *builder) string {
+	if c.cond == nil {
+		b.fail(fmt.Errorf("arrayExists with empty condition"))
+		return ""
+	}
+	return fmt.Sprintf("arrayExists((%s) -> %s, %s)", b.ident(c.x), c.cond.sql(b), b.ident(c.array))
+}
@@ -0,0 +1,178 @@
+package sqlb
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Select is SELECT query builder
+type Select struct {
+	columns   []string
+	from      string
+	arrayJoin string
+	prewhere  []Cond
+	where     []Cond
+	groupBy   []string
+	having    Cond
+	orderBy   []string
+	format    string
+}
+
+// NewSelect starts SELECT query. Columns are trusted expressions
+func NewSelect(columns ...string) *Select {
+	return &Select{columns: columns}
+}
+
+// From sets table. Table name is validated
+func (s *Select) From(table string) *Select {
+	s.from = table
+	return s
+}
+
+// ArrayJoin adds ARRAY JOIN clause. Expression is trusted
+func (s *Select) ArrayJoin(expr string) *Select {
+	s.arrayJoin = expr
+	return s
+}
+
+// Prewhere adds conditions to PREWHERE. Conditions are joined with AND
+func (s *Select) Prewhere(conds ...Cond) *Select {
+	s.prewhere = append(s.prewhere, conds...)
+	return s
+}
+
+// Where adds conditions to WHERE. Conditions are joined with AND
+func (s *Select) Where(conds ...Cond) *Select {
+	s.where = append(s.where, conds...)
+	return s
+}
+
+// GroupBy sets GROUP BY columns
+func (s *Select) GroupBy(columns ...string) *Select {
+	s.groupBy = columns
+	return s
+}
+
+// Having sets HAVING condition
+func (s *Select) Having(cond Cond) *Select {
+	s.having = cond
+	return s
+}
+
+// OrderBy sets ORDER BY columns
+func (s *Select) OrderBy(columns ...string) *Select {
+	s.orderBy = columns
+	return s
+}
+
+// Format sets output format
+func (s *Select) Format(format string) *Select {
+	s.format = format
+	return s
+}
+
+func (s *Select) sql(b *builder) string {
+	buf := new(bytes.Buffer)
+
+	buf.WriteString("SELECT ")
+	buf.WriteString(strings.Join(s.columns, ", "))
+	buf.WriteString(" FROM ")
+	buf.WriteString(b.table(s.from))
+
+	if s.arrayJoin != "" {
+		buf.WriteString(" ARRAY JOIN ")
+		buf.WriteString(s.arrayJoin)
+	}
+
+	if w := And(s.prewhere...).sql(b); w != "" {
+		buf.WriteString(" PREWHERE ")
+		buf.WriteString(w)
+	}
+
+	if w := And(s.where...).sql(b); w != "" {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(w)
+	}
+
+	if len(s.groupBy) > 0 {
+		buf.WriteString(" GROUP BY ")
+		for i, c := range s.groupBy {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(b.ident(c))
+		}
+	}
+
+	if s.having != nil {
+		buf.WriteString(" HAVING ")
+		buf.WriteString(s.having.sql(b))
+	}
+
+	if len(s.orderBy) > 0 {
+		buf.WriteString(" ORDER BY ")
+		for i, c := range s.orderBy {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(b.ident(c))
+		}
+	}
+
+	if s.format != "" {
+		buf.WriteString(" FORMAT ")
+		buf.WriteString(b.ident(s.format))
+	}
+
+	return buf.String()
+}
+
+// Build returns query or first error of identifier validation
+func (s *Select) Build() (*Query, error) {
+	b := newBuilder()
+	sql := s.sql(b)
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return &Query{sql: sql, params: b.params}, nil
+}
+
+// Insert is INSERT query builder
+type Insert struct {
+	table   string
+	columns []string
+	format  string
+}
+
+// NewInsert starts INSERT INTO table (columns...) query
+func NewInsert(table string, columns ...string) *Insert {
+	return &Insert{table: table, columns: columns}
+}
+
+// Format sets input format
+func (s *Insert) Format(format string) *Insert {
+	s.format = format
+	return s
+}
+
+// Build returns query or first error of identifier validation
+func (s *Insert) Build() (*Query, error) {
+	b := newBuilder()
+
+	columns := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		columns[i] = b.ident(c)
+	}
+
+	sql := "INSERT INTO " + b.table(s.table) + " (" + strings.Join(columns, ",") + ")"
+	if s.format != "" {
+		sql += " FORMAT " + b.ident(s.format)
+	}
+
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return &Query{sql: sql, params: b.params}, nil
+}
@@ -0,0 +1,111 @@
+// Package sqlb builds ClickHouse queries with typed conditions.
+// Values are never formatted into SQL text. They are passed as query parameters
+// ({name:Type} placeholders and param_name HTTP arguments)
+package sqlb
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var identRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+var tableRe = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*\.)?[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Query is SQL text with parameter values
+type Query struct {
+	sql    string
+	params url.Values
+}
+
+// String returns SQL text with placeholders
+func (q *Query) String() string {
+	return q.sql
+}
+
+// Params returns parameters for ClickHouse HTTP interface (param_<name>=<value>)
+func (q *Query) Params() url.Values {
+	return q.params
+}
+
+// Date is value of ClickHouse Date type
+type Date time.Time
+
+// builder collects parameters and first error while conditions are rendered
+type builder struct {
+	params url.Values
+	n      int
+	err    error
+}
+
+func newBuilder() *builder {
+	return &builder{params: url.Values{}}
+}
+
+func (b *builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// ident validates column name
+func (b *builder) ident(name string) string {
+	if !identRe.MatchString(name) {
+		b.fail(fmt.Errorf("invalid identifier %#v", name))
+	}
+	return name
+}
+
+// table validates table name with optional database
+func (b *builder) table(name string) string {
+	if !tableRe.MatchString(name) {
+		b.fail(fmt.Errorf("invalid table name %#v", name))
+	}
+	return name
+}
+
+// escapeParam escapes value as ClickHouse parses parameters in escaped (TabSeparated) format
+func escapeParam(v string) string {
+	v = strings.Replace(v, `\`, `\\`, -1)
+	v = strings.Replace(v, "\t", `\t`, -1)
+	v = strings.Replace(v, "\n", `\n`, -1)
+	return v
+}
+
+func (b *builder) param(typ string, value string) string {
+	name := fmt.Sprintf("p%d", b.n)
+	b.n++
+	b.params.Set("param_"+name, escapeParam(value))
+	return fmt.Sprintf("{%s:%s}", name, typ)
+}
+
+// value returns placeholder for value or subquery
+func (b *builder) value(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return b.param("String", x)
+	case []byte:
+		return b.param("String", string(x))
+	case int:
+		return b.param("Int64", fmt.Sprintf("%d", x))
+	case int64:
+		return b.param("Int64", fmt.Sprintf("%d", x))
+	case int32:
+		return b.param("Int32", fmt.Sprintf("%d", x))
+	case uint8:
+		return b.param("UInt8", fmt.Sprintf("%d", x))
+	case uint32:
+		return b.param("UInt32", fmt.Sprintf("%d", x))
+	case uint64:
+		return b.param("UInt64", fmt.Sprintf("%d", x))
+	case Date:
+		return b.param("Date", time.Time(x).Format("2006-01-02"))
+	case *Select:
+		return "(" + x.sql(b) + ")"
+	}
+
+	b.fail(fmt.Errorf("unsupported value type %T", v))
+	return ""
+}
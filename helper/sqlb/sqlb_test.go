@@ -0,0 +1,142 @@
+package sqlb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect(t *testing.T) {
+	assert := assert.New(t)
+
+	date := Date(time.Date(2018, 1, 30, 12, 0, 0, 0, time.Local))
+
+	table := []struct {
+		query  *Select
+		sql    string
+		params map[string]string
+	}{
+		{
+			NewSelect("Path").From("graphite_tree"),
+			"SELECT Path FROM graphite_tree",
+			map[string]string{},
+		},
+		{
+			NewSelect("Path").From("default.graphite_tree").Where(Eq("Level", uint32(2)), nil, HasPrefix("Path", "a_b%c.")),
+			"SELECT Path FROM default.graphite_tree WHERE (Level = {p0:UInt32}) AND (Path LIKE {p1:String})",
+			map[string]string{"p0": "2", "p1": `a\\_b\\%c.%`},
+		},
+		{
+			NewSelect("Path").From("t").Where(Or(Eq("Path", "a"), Eq("Path", "a.")), Not(Match("Path", `^a\.b$`))),
+			"SELECT Path FROM t WHERE ((Path = {p0:String}) OR (Path = {p1:String})) AND (NOT (match(Path, {p2:String})))",
+			map[string]string{"p0": "a", "p1": "a.", "p2": `^a\\.b$`},
+		},
+		{
+			NewSelect("Path", "Time").From("graphite").Prewhere(Ge("Date", date)).Where(InTable("Path", "_paths"), Le("Time", int64(100))).Format("RowBinary"),
+			"SELECT Path, Time FROM graphite PREWHERE (Date >= {p0:Date}) WHERE (Path IN _paths) AND (Time <= {p1:Int64}) FORMAT RowBinary",
+			map[string]string{"p0": "2018-01-30", "p1": "100"},
+		},
+		{
+			NewSelect("Path").From("t").Where(In("Path", "a", "b'c"), In("Path")),
+			"SELECT Path FROM t WHERE (Path IN ({p0:String},{p1:String})) AND (0)",
+			map[string]string{"p0": "a", "p1": "b'c"},
+		},
+		{
+			NewSelect("TagN").From("tag").ArrayJoin("Tags AS TagN").
+				Where(
+					Ge("Version", NewSelect("Max(Version)").From("tag").Where(Eq("Tag1", ""))),
+					ArrayExists("x", "Tags", Eq("x", "t\t1")),
+					InSelect("Path", NewSelect("Path").From("tree").Where(Ne("Level", 0))),
+				).
+				GroupBy("TagN").
+				Having(Raw("count() > 1")),
+			"SELECT TagN FROM tag ARRAY JOIN Tags AS TagN WHERE (Version >= (SELECT Max(Version) FROM tag WHERE (Tag1 = {p0:String}))) AND (arrayExists((x) -> x = {p1:String}, Tags)) AND (Path IN (SELECT Path FROM tree WHERE (Level != {p2:Int64}))) GROUP BY TagN HAVING count() > 1",
+			map[string]string{"p0": "", "p1": `t\t1`, "p2": "0"},
+		},
+		{
+			NewSelect("Path").From("tree").GroupBy("Path").Having(Raw("argMax(Deleted, Version)==0")).OrderBy("Path").Format("RowBinary"),
+			"SELECT Path FROM tree GROUP BY Path HAVING argMax(Deleted, Version)==0 ORDER BY Path FORMAT RowBinary",
+			map[string]string{},
+		},
+	}
+
+	for i, test := range table {
+		testName := fmt.Sprintf("#%d", i)
+
+		q, err := test.query.Build()
+		if !assert.NoError(err, testName) {
+			continue
+		}
+
+		assert.Equal(test.sql, q.String(), testName)
+
+		params := make(map[string]string)
+		for k := range q.Params() {
+			params[k[len("param_"):]] = q.Params().Get(k)
+		}
+		assert.Equal(test.params, params, testName)
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	assert := assert.New(t)
+
+	table := []struct {
+		query   *Select
+		invalid bool
+	}{
+		{NewSelect("Path").From("graphite"), false},
+		{NewSelect("Path").From("db.graphite"), false},
+		{NewSelect("Path").From("graphite; DROP TABLE graphite"), true},
+		{NewSelect("Path").From("db.graphite.x"), true},
+		{NewSelect("Path").From(""), true},
+		{NewSelect("Path").From("t").Where(Eq("Path OR 1", "a")), true},
+		{NewSelect("Path").From("t").Where(InTable("Path", "_paths) OR (1")), true},
+		{NewSelect("Path").From("t").GroupBy("Path, 1"), true},
+		{NewSelect("Path").From("t").Format("RowBinary SETTINGS x=1"), true},
+		{NewSelect("Path").From("t").Where(Eq("Path", 1.5)), true},
+		{NewSelect("Path").From("t").Where(Not(nil)), true},
+		{NewSelect("Path").From("t").Where(Not(Or())), true},
+		{NewSelect("Path").From("t").Where(ArrayExists("x", "Tags", nil)), true},
+	}
+
+	for i, test := range table {
+		_, err := test.query.Build()
+		if test.invalid {
+			assert.Error(err, fmt.Sprintf("#%d", i))
+		} else {
+			assert.NoError(err, fmt.Sprintf("#%d", i))
+		}
+	}
+}
+
+func TestInsert(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := NewInsert("graphite_tag", "Date", "Path").Format("RowBinary").Build()
+	assert.NoError(err)
+	assert.Equal("INSERT INTO graphite_tag (Date,Path) FORMAT RowBinary", q.String())
+
+	_, err = NewInsert("graphite_tag (Date) SELECT", "Date").Build()
+	assert.Error(err)
+}
+
+func TestDelete(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := NewDelete("graphite_tag").Where(Lt("Version", uint32(10))).Build()
+	assert.NoError(err)
+	assert.Equal("DELETE FROM graphite_tag WHERE (Version < {p0:UInt32})", q.String())
+
+	_, err = NewDelete("graphite_tag").Build()
+	assert.Error(err)
+
+	q, err = DropPartition("graphite_tag", "20180130")
+	assert.NoError(err)
+	assert.Equal("ALTER TABLE graphite_tag DROP PARTITION ID '20180130'", q.String())
+
+	_, err = DropPartition("graphite_tag", "1' OR '1")
+	assert.Error(err)
+}
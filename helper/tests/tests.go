@@ -0,0 +1,77 @@
+// Package tests contains ClickHouse mock and response encoders shared by handler tests
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/lomik/graphite-clickhouse/helper/RowBinary"
+	"github.com/lomik/graphite-clickhouse/helper/rollup"
+)
+
+// RollupXML is rollup with avg function and 10 seconds precision for all metrics
+const RollupXML = `
+<graphite_rollup>
+	<default>
+		<function>avg</function>
+		<retention>
+			<age>0</age>
+			<precision>10</precision>
+		</retention>
+	</default>
+</graphite_rollup>`
+
+// Rollup parses rollup xml and fails test on error
+func Rollup(t testing.TB, xml string) *rollup.Rollup {
+	r, err := rollup.ParseXML([]byte(xml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// Points makes RowBinary response of data table with points (time, value) of path: Path, Time, Value, Timestamp
+func Points(path string, points ...[2]float64) []byte {
+	buf := new(bytes.Buffer)
+	e := RowBinary.NewEncoder(buf)
+	for _, p := range points {
+		e.String(path)
+		e.Uint32(uint32(p[0]))
+		e.Float64(p[1])
+		e.Uint32(1)
+	}
+	return buf.Bytes()
+}
+
+// Paths makes RowBinary response with Path column
+func Paths(paths ...string) []byte {
+	buf := new(bytes.Buffer)
+	e := RowBinary.NewEncoder(buf)
+	for _, p := range paths {
+		e.String(p)
+	}
+	return buf.Bytes()
+}
+
+// ClickHouse is ClickHouse server mock. Data table queries (with _paths external table) are answered with Data,
+// other queries (tree, tagged) with Index. If Queries is set, text and url arguments of other queries are sent to it
+type ClickHouse struct {
+	Index   []byte
+	Data    []byte
+	Queries chan string
+}
+
+func (c *ClickHouse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, _, err := r.FormFile("_paths"); err == nil {
+		w.Write(c.Data)
+		return
+	}
+
+	if c.Queries != nil {
+		body, _ := ioutil.ReadAll(r.Body)
+		c.Queries <- string(body) + " " + r.URL.RawQuery
+	}
+	w.Write(c.Index)
+}
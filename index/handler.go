@@ -0,0 +1,113 @@
+package index
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/log"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+)
+
+// maxPathLen limits path read from tree response. Tagged paths may exceed default 64KB token of bufio.Scanner
+const maxPathLen = 16 * 1024 * 1024
+
+// Handler serves /metrics/index.json: JSON array of all leaf metrics.
+// Tree table is read as stream, so response size is not limited by memory
+type Handler struct {
+	config *config.Config
+}
+
+func NewHandler(config *config.Config) *Handler {
+	return &Handler{
+		config: config,
+	}
+}
+
+// sql returns query selecting not deleted leafs. Leaf path has no trailing dot
+func (h *Handler) sql() (*sqlb.Query, error) {
+	return sqlb.NewSelect("Path").
+		From(h.config.ClickHouse.TreeTable).
+		Where(sqlb.Not(sqlb.Like("Path", "%."))).
+		GroupBy("Path").
+		Having(sqlb.Raw("argMax(Deleted, Version)==0")).
+		Build()
+}
+
+// blacklisted checks path by target-blacklist
+func (h *Handler) blacklisted(path []byte) bool {
+	for _, re := range h.config.Common.Blacklist {
+		if re.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := log.FromContext(r.Context())
+
+	q, err := h.sql()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := clickhouse.Reader(r.Context(), h.config.ClickHouse.Url, q, h.config.ClickHouse.TreeTimeout.Value())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	var prefix []byte
+	if h.config.ClickHouse.ExtraPrefix != "" {
+		prefix = []byte(h.config.ClickHouse.ExtraPrefix + ".")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	writer := bufio.NewWriterSize(w, 1024*1024)
+	defer writer.Flush()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxPathLen)
+	path := make([]byte, 0, 1024)
+	count := 0
+
+	writer.WriteByte('[')
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		path = append(append(path[:0], prefix...), scanner.Bytes()...)
+		if h.blacklisted(path) {
+			continue
+		}
+
+		value, err := json.Marshal(string(path))
+		if err != nil {
+			logger.Error("index", zap.Error(err))
+			return
+		}
+
+		if count > 0 {
+			writer.WriteByte(',')
+		}
+		writer.Write(value)
+		count++
+	}
+
+	// status is already sent, broken response is rejected by json parser of client
+	if err := scanner.Err(); err != nil {
+		logger.Error("index", zap.Error(err))
+		return
+	}
+
+	writer.WriteByte(']')
+}
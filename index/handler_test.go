@@ -0,0 +1,81 @@
+package index
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/config"
+)
+
+func TestIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	requestLog := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requestLog <- string(body)
+		w.Write([]byte("a.b\nhidden.c\na.\"quoted\"\n"))
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+
+	w := httptest.NewRecorder()
+	NewHandler(cfg).ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/metrics/index.json", nil))
+
+	assert.Equal(
+		"SELECT Path FROM graphite_tree WHERE (NOT (Path LIKE {p0:String})) GROUP BY Path HAVING argMax(Deleted, Version)==0",
+		<-requestLog,
+	)
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+	assert.Equal(`["a.b","hidden.c","a.\"quoted\""]`, w.Body.String())
+
+	// prefix is added before blacklist check
+	cfg.ClickHouse.ExtraPrefix = "prefix"
+	cfg.Common.Blacklist = []*regexp.Regexp{regexp.MustCompile(`^prefix\.hidden\.`)}
+
+	w = httptest.NewRecorder()
+	NewHandler(cfg).ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/metrics/index.json", nil))
+	<-requestLog
+
+	assert.Equal(`["prefix.a.b","prefix.a.\"quoted\""]`, w.Body.String())
+}
+
+func TestIndexError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "fail", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+
+	w := httptest.NewRecorder()
+	NewHandler(cfg).ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/metrics/index.json", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestIndexLongPath(t *testing.T) {
+	longPath := "a." + strings.Repeat("b", 100*1024)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(longPath + "\nc.d\n"))
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+
+	w := httptest.NewRecorder()
+	NewHandler(cfg).ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/metrics/index.json", nil))
+
+	assert.Equal(t, `["`+longPath+`","c.d"]`, w.Body.String())
+}
@@ -0,0 +1,123 @@
+package info
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/lomik/graphite-clickhouse/carbonapi_v3_pb"
+	"github.com/lomik/graphite-clickhouse/carbonzipperpb"
+	"github.com/lomik/graphite-clickhouse/config"
+)
+
+// Handler answers storage schema of metric. ClickHouse has no per-metric schema, so it is taken from rollup rules
+type Handler struct {
+	config *config.Config
+}
+
+func NewHandler(config *config.Config) *Handler {
+	return &Handler{
+		config: config,
+	}
+}
+
+// Info returns aggregation and retentions of rollup pattern matched metric
+func (h *Handler) Info(metric string) carbonapi_v3_pb.MetricsInfoResponse {
+	pattern := h.config.Rollup.Match(metric)
+
+	info := carbonapi_v3_pb.MetricsInfoResponse{
+		Name:              metric,
+		ConsolidationFunc: pattern.Function,
+		XFilesFactor:      pattern.XFilesFactor,
+		Retentions:        make([]carbonapi_v3_pb.Retention, len(pattern.Retention)),
+	}
+
+	for i, r := range pattern.Retention {
+		info.Retentions[i].SecondsPerPoint = int64(r.Precision)
+		// last retention is kept forever
+		if i+1 < len(pattern.Retention) {
+			info.Retentions[i].NumberOfPoints = int64((pattern.Retention[i+1].Age - r.Age) / r.Precision)
+		}
+	}
+
+	if len(pattern.Retention) > 0 {
+		info.MaxRetention = int64(pattern.Retention[len(pattern.Retention)-1].Age)
+	}
+
+	return info
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Query().Get("format") {
+	case "carbonapi_v3_pb":
+		h.ServeV3(w, r)
+	case "json":
+		body, err := json.Marshal(h.Info(r.URL.Query().Get("target")))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	case "protobuf":
+		info := h.Info(r.URL.Query().Get("target"))
+
+		response := carbonzipperpb.InfoResponse{
+			Name:              proto.String(info.Name),
+			AggregationMethod: proto.String(info.ConsolidationFunc),
+			MaxRetention:      proto.Int32(int32(info.MaxRetention)),
+			XFilesFactor:      proto.Float32(info.XFilesFactor),
+		}
+		for _, r := range info.Retentions {
+			response.Retentions = append(response.Retentions, &carbonzipperpb.Retention{
+				SecondsPerPoint: proto.Int32(int32(r.SecondsPerPoint)),
+				NumberOfPoints:  proto.Int32(int32(r.NumberOfPoints)),
+			})
+		}
+
+		body, err := proto.Marshal(&response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(body)
+	default:
+		http.Error(w, "Bad request (unsupported format)", http.StatusBadRequest)
+	}
+}
+
+// ServeV3 handles carbonapi_v3_pb request: MultiMetricsInfoRequest in POST body or target arguments
+func (h *Handler) ServeV3(w http.ResponseWriter, r *http.Request) {
+	var request carbonapi_v3_pb.MultiMetricsInfoRequest
+
+	if r.Method == http.MethodPost {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := proto.Unmarshal(body, &request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		request.Names = r.URL.Query()["target"]
+	}
+
+	var response carbonapi_v3_pb.MultiMetricsInfoResponse
+	for _, name := range request.Names {
+		response.Metrics = append(response.Metrics, h.Info(name))
+	}
+
+	body, err := proto.Marshal(&response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(body)
+}
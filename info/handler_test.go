@@ -0,0 +1,106 @@
+package info
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/carbonapi_v3_pb"
+	"github.com/lomik/graphite-clickhouse/carbonzipperpb"
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/tests"
+)
+
+func testHandler(t *testing.T) *Handler {
+	cfg := config.New()
+
+	cfg.Rollup = tests.Rollup(t, `
+<graphite_rollup>
+	<pattern>
+		<regexp>^click_cost</regexp>
+		<function>sum</function>
+		<xFilesFactor>0.3</xFilesFactor>
+		<retention>
+			<age>0</age>
+			<precision>60</precision>
+		</retention>
+		<retention>
+			<age>86400</age>
+			<precision>3600</precision>
+		</retention>
+	</pattern>
+	<default>
+		<function>avg</function>
+		<retention>
+			<age>0</age>
+			<precision>10</precision>
+		</retention>
+	</default>
+</graphite_rollup>`)
+
+	return NewHandler(cfg)
+}
+
+func TestInfo(t *testing.T) {
+	assert := assert.New(t)
+	h := testHandler(t)
+
+	assert.Equal(carbonapi_v3_pb.MetricsInfoResponse{
+		Name:              "click_cost.a",
+		ConsolidationFunc: "sum",
+		XFilesFactor:      0.3,
+		MaxRetention:      86400,
+		Retentions: []carbonapi_v3_pb.Retention{
+			{SecondsPerPoint: 60, NumberOfPoints: 1440},
+			{SecondsPerPoint: 3600},
+		},
+	}, h.Info("click_cost.a"))
+
+	assert.Equal(carbonapi_v3_pb.MetricsInfoResponse{
+		Name:              "host.cpu",
+		ConsolidationFunc: "avg",
+		Retentions: []carbonapi_v3_pb.Retention{
+			{SecondsPerPoint: 10},
+		},
+	}, h.Info("host.cpu"))
+}
+
+func TestServeHTTP(t *testing.T) {
+	assert := assert.New(t)
+	h := testHandler(t)
+
+	// carbonzipper protobuf
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/info/?format=protobuf&target=click_cost.a", nil))
+	assert.Equal(http.StatusOK, w.Code)
+
+	var v2 carbonzipperpb.InfoResponse
+	assert.NoError(proto.Unmarshal(w.Body.Bytes(), &v2))
+	assert.Equal("sum", v2.GetAggregationMethod())
+	assert.Equal(int32(86400), v2.GetMaxRetention())
+	assert.Len(v2.Retentions, 2)
+
+	// carbonapi_v3_pb
+	body, err := proto.Marshal(&carbonapi_v3_pb.MultiMetricsInfoRequest{Names: []string{"click_cost.a", "host.cpu"}})
+	assert.NoError(err)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "http://localhost/info/?format=carbonapi_v3_pb", bytes.NewReader(body)))
+	assert.Equal(http.StatusOK, w.Code)
+
+	var v3 carbonapi_v3_pb.MultiMetricsInfoResponse
+	assert.NoError(proto.Unmarshal(w.Body.Bytes(), &v3))
+	if assert.Len(v3.Metrics, 2) {
+		assert.Equal("click_cost.a", v3.Metrics[0].Name)
+		assert.Equal("avg", v3.Metrics[1].ConsolidationFunc)
+	}
+
+	// unknown format
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/info/?target=host.cpu", nil))
+	assert.Equal(http.StatusBadRequest, w.Code)
+}
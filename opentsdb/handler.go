@@ -0,0 +1,74 @@
+package opentsdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/limit"
+	"github.com/lomik/graphite-clickhouse/render"
+)
+
+// Handler implements OpenTSDB /api/query and /api/suggest over tagged series index and render fetch
+type Handler struct {
+	config *config.Config
+	render *render.Handler
+}
+
+func NewHandler(config *config.Config, render *render.Handler) *Handler {
+	return &Handler{
+		config: config,
+		render: render,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/query":
+		h.ServeQuery(w, r)
+	case "/api/suggest":
+		h.ServeSuggest(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Error is OpenTSDB error with HTTP status code
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func badRequest(format string, a ...interface{}) *Error {
+	return &Error{Code: http.StatusBadRequest, Message: fmt.Sprintf(format, a...)}
+}
+
+// writeError writes {"error": {"code": ..., "message": ...}}. Rejected by limits requests are 403
+func writeError(w http.ResponseWriter, err error) {
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{Code: limit.Status(err), Message: err.Error()}
+	}
+
+	body, _ := json.Marshal(map[string]*Error{"error": e})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Code)
+	w.Write(body)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
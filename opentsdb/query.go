@@ -0,0 +1,550 @@
+package opentsdb
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lomik/graphite-clickhouse/finder"
+	"github.com/lomik/graphite-clickhouse/helper/point"
+	"github.com/lomik/graphite-clickhouse/helper/rollup"
+)
+
+// maxFilledPoints limits intervals of downsample with fill policy per series, as maxPointsPerSeries of prometheus query_range
+const maxFilledPoints = 11000
+
+// Query is sub query of /api/query
+type Query struct {
+	Aggregator string            `json:"aggregator"`
+	Metric     string            `json:"metric"`
+	Downsample string            `json:"downsample"`
+	Tags       map[string]string `json:"tags"`
+}
+
+// Request is body of POST /api/query. Start and end are numbers or strings
+type Request struct {
+	Start   interface{} `json:"start"`
+	End     interface{} `json:"end"`
+	Queries []Query     `json:"queries"`
+}
+
+// Result is series of group
+type Result struct {
+	Metric        string                 `json:"metric"`
+	Tags          map[string]string      `json:"tags"`
+	AggregateTags []string               `json:"aggregateTags"`
+	Dps           map[string]interface{} `json:"dps"`
+}
+
+func aggrCount(points []point.Point) float64 {
+	return float64(len(points))
+}
+
+// aggregators are functions of aggregator and downsample. Series are not interpolated,
+// so zimsum, mimmin and mimmax are the same as sum, min and max
+var aggregators = map[string]func([]point.Point) float64{
+	"sum":    rollup.AggrSum,
+	"zimsum": rollup.AggrSum,
+	"avg":    rollup.AggrAvg,
+	"min":    rollup.AggrMin,
+	"mimmin": rollup.AggrMin,
+	"max":    rollup.AggrMax,
+	"mimmax": rollup.AggrMax,
+	"first":  rollup.AggrAny,
+	"last":   rollup.AggrAnyLast,
+	"count":  aggrCount,
+}
+
+// units of relative time and downsample interval in seconds
+var units = map[string]int64{
+	"s": 1,
+	"m": 60,
+	"h": 3600,
+	"d": 86400,
+	"w": 7 * 86400,
+	"n": 30 * 86400,
+	"y": 365 * 86400,
+}
+
+var durationRe = regexp.MustCompile(`^(\d+)(ms|s|m|h|d|w|n|y)$`)
+
+// parseDuration parses "1m", "30s", "500ms" to seconds
+func parseDuration(s string) (int64, error) {
+	m := durationRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, badRequest("invalid duration %#v", s)
+	}
+
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, badRequest("invalid duration %#v", s)
+	}
+
+	if m[2] == "ms" {
+		return n / 1000, nil
+	}
+	return n * units[m[2]], nil
+}
+
+var absoluteFormats = []string{
+	"2006/01/02-15:04:05",
+	"2006/01/02 15:04:05",
+	"2006/01/02-15:04",
+	"2006/01/02 15:04",
+	"2006/01/02",
+}
+
+// parseTime parses timestamp in seconds or milliseconds, relative "1h-ago" or absolute "2006/01/02-15:04:05" time
+func parseTime(s string, now time.Time) (int64, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n > 9999999999 {
+			return n / 1000, nil
+		}
+		return n, nil
+	}
+
+	if strings.HasSuffix(s, "-ago") {
+		d, err := parseDuration(strings.TrimSuffix(s, "-ago"))
+		if err != nil {
+			return 0, err
+		}
+		return now.Unix() - d, nil
+	}
+
+	for _, f := range absoluteFormats {
+		if t, err := time.ParseInLocation(f, s, time.Local); err == nil {
+			return t.Unix(), nil
+		}
+	}
+
+	return 0, badRequest("invalid time %#v", s)
+}
+
+// timeString returns time of JSON request as string
+func timeString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	return ""
+}
+
+// parseM parses m argument of GET request: aggregator:[downsample:]metric[{tag=value,...}]
+func parseM(m string) (Query, error) {
+	q := Query{Tags: make(map[string]string)}
+
+	if i := strings.IndexByte(m, '{'); i >= 0 {
+		if !strings.HasSuffix(m, "}") {
+			return q, badRequest("missing '}' in %#v", m)
+		}
+		for _, tag := range strings.Split(m[i+1:len(m)-1], ",") {
+			if tag == "" {
+				continue
+			}
+			eq := strings.IndexByte(tag, '=')
+			if eq <= 0 {
+				return q, badRequest("invalid tag %#v", tag)
+			}
+			q.Tags[tag[:eq]] = tag[eq+1:]
+		}
+		m = m[:i]
+	}
+
+	parts := strings.Split(m, ":")
+	switch len(parts) {
+	case 2:
+		q.Aggregator, q.Metric = parts[0], parts[1]
+	case 3:
+		q.Aggregator, q.Downsample, q.Metric = parts[0], parts[1], parts[2]
+	default:
+		return q, badRequest("invalid m parameter %#v", m)
+	}
+
+	return q, nil
+}
+
+// parseRequest reads JSON body of POST or start, end and m arguments of GET
+func parseRequest(r *http.Request) (*Request, error) {
+	req := &Request{}
+
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return nil, badRequest("invalid request: %s", err.Error())
+		}
+		return req, nil
+	}
+
+	req.Start = r.URL.Query().Get("start")
+	req.End = r.URL.Query().Get("end")
+
+	for _, m := range r.URL.Query()["m"] {
+		q, err := parseM(m)
+		if err != nil {
+			return nil, err
+		}
+		req.Queries = append(req.Queries, q)
+	}
+
+	return req, nil
+}
+
+// tagFilter returns seriesByTag expression of tag value: "*" is any value, "a|b" is any of values, "web*" is wildcard
+func tagFilter(key string, value string) (string, error) {
+	if value == "*" {
+		return key + "!=", nil
+	}
+
+	if !strings.ContainsAny(value, "|*") {
+		return key + "=" + value, nil
+	}
+
+	alternatives := strings.Split(value, "|")
+	for i, a := range alternatives {
+		if a == "" {
+			return "", badRequest("empty value in filter %#v of %#v", value, key)
+		}
+		alternatives[i] = strings.Replace(regexp.QuoteMeta(a), `\*`, ".*", -1)
+	}
+
+	return key + "=~(?:" + strings.Join(alternatives, "|") + ")$", nil
+}
+
+// Target returns render target and sorted group by tags of query.
+// With tagged-table it is seriesByTag of metric and tags, all tags of query are group by tags.
+// Without tagged-table metric is graphite path or glob and tags are not supported
+func (h *Handler) Target(q *Query) (string, []string, error) {
+	if q.Metric == "" {
+		return "", nil, badRequest("missing metric")
+	}
+
+	keys := make([]string, 0, len(q.Tags))
+	for k := range q.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if h.config.ClickHouse.TaggedTable == "" {
+		if len(keys) > 0 {
+			return "", nil, badRequest("tags are not supported without tagged-table")
+		}
+		return q.Metric, nil, nil
+	}
+
+	exprs := []string{"name=" + q.Metric}
+
+	for _, k := range keys {
+		expr, err := tagFilter(k, q.Tags[k])
+		if err != nil {
+			return "", nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+
+	target, err := finder.SeriesByTag(exprs)
+	if err != nil {
+		return "", nil, badRequest("%s", err.Error())
+	}
+
+	return target, keys, nil
+}
+
+// downsampler aggregates points by interval. Empty intervals are skipped or filled with NaN (null) or zero
+type downsampler struct {
+	interval int64
+	aggr     func([]point.Point) float64
+	fill     string
+}
+
+// parseDownsample parses "1m-avg" or "1m-avg-zero"
+func parseDownsample(s string) (*downsampler, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, badRequest("invalid downsample %#v", s)
+	}
+
+	interval, err := parseDuration(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		return nil, badRequest("downsample interval must be at least 1s")
+	}
+
+	aggr, ok := aggregators[parts[1]]
+	if !ok {
+		return nil, badRequest("unknown downsample function %#v", parts[1])
+	}
+
+	d := &downsampler{interval: interval, aggr: aggr, fill: "none"}
+	if len(parts) == 3 {
+		d.fill = parts[2]
+	}
+
+	switch d.fill {
+	case "none", "nan", "null", "zero":
+	default:
+		return nil, badRequest("unknown fill policy %#v", d.fill)
+	}
+
+	return d, nil
+}
+
+// do returns aggregated points of intervals between start and end. points are sorted by time
+func (d *downsampler) do(points []point.Point, start int64, end int64) []point.Point {
+	var result []point.Point
+
+	var i, n int
+	for i = 1; i <= len(points); i++ {
+		bucket := int64(points[n].Time) - int64(points[n].Time)%d.interval
+		if i < len(points) && int64(points[i].Time)-int64(points[i].Time)%d.interval == bucket {
+			continue
+		}
+		result = append(result, point.Point{Time: int32(bucket), Value: d.aggr(points[n:i])})
+		n = i
+	}
+
+	if d.fill == "none" {
+		return result
+	}
+
+	value := math.NaN()
+	if d.fill == "zero" {
+		value = 0
+	}
+
+	filled := make([]point.Point, 0, (end-start)/d.interval+2)
+	index := 0
+	for t := start - start%d.interval; t <= end; t += d.interval {
+		if index < len(result) && int64(result[index].Time) == t {
+			filled = append(filled, result[index])
+			index++
+			continue
+		}
+		filled = append(filled, point.Point{Time: int32(t), Value: value})
+	}
+
+	return filled
+}
+
+// aggregate merges points of series by time. NaN values are skipped, time without values is NaN
+func aggregate(series [][]point.Point, aggr func([]point.Point) float64) []point.Point {
+	if len(series) == 1 {
+		return series[0]
+	}
+
+	byTime := make(map[int32][]point.Point)
+	for _, points := range series {
+		for _, p := range points {
+			if _, ok := byTime[p.Time]; !ok {
+				byTime[p.Time] = nil
+			}
+			if !math.IsNaN(p.Value) {
+				byTime[p.Time] = append(byTime[p.Time], p)
+			}
+		}
+	}
+
+	result := make([]point.Point, 0, len(byTime))
+	for t, points := range byTime {
+		v := math.NaN()
+		if len(points) > 0 {
+			v = aggr(points)
+		}
+		result = append(result, point.Point{Time: t, Value: v})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Time < result[j].Time })
+	return result
+}
+
+// parseName returns name and tags of tagged series "name;tag=value" or graphite path
+func parseName(name string) (string, map[string]string) {
+	parts := strings.Split(name, ";")
+	tags := make(map[string]string)
+
+	for _, p := range parts[1:] {
+		if eq := strings.IndexByte(p, '='); eq > 0 {
+			tags[p[:eq]] = p[eq+1:]
+		}
+	}
+
+	return parts[0], tags
+}
+
+type group struct {
+	metric string
+	tags   []map[string]string
+	points [][]point.Point
+}
+
+// query returns series of query grouped by metric and values of group by tags
+func (h *Handler) query(r *http.Request, q *Query, start int64, end int64) ([]Result, error) {
+	aggr, ok := aggregators[q.Aggregator]
+	if !ok && q.Aggregator != "none" {
+		return nil, badRequest("unknown aggregator %#v", q.Aggregator)
+	}
+
+	var ds *downsampler
+	if q.Downsample != "" {
+		var err error
+		if ds, err = parseDownsample(q.Downsample); err != nil {
+			return nil, err
+		}
+		if ds.fill != "none" && (end-start)/ds.interval > maxFilledPoints {
+			return nil, badRequest("downsample %#v exceeds maximum of %d filled points per series", q.Downsample, maxFilledPoints)
+		}
+	}
+
+	target, groupBy, err := h.Target(q)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := h.render.Fetch(r.Context(), target, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*group
+	index := make(map[string]*group)
+
+	for _, s := range h.render.Series(data, int32(start), int32(end)) {
+		points := make([]point.Point, 0, len(s.Values))
+		for i, v := range s.Values {
+			if !math.IsNaN(v) {
+				points = append(points, point.Point{Time: s.Start + int32(i)*s.Step, Value: v})
+			}
+		}
+		if ds != nil {
+			points = ds.do(points, start, end)
+		}
+		if len(points) == 0 {
+			continue
+		}
+
+		metric, tags := parseName(s.Name)
+
+		key := s.Name
+		if aggr != nil {
+			key = metric
+			for _, k := range groupBy {
+				key += ";" + k + "=" + tags[k]
+			}
+		}
+
+		g, ok := index[key]
+		if !ok {
+			g = &group{metric: metric}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.tags = append(g.tags, tags)
+		g.points = append(g.points, points)
+	}
+
+	result := make([]Result, 0, len(groups))
+
+	for _, g := range groups {
+		res := Result{
+			Metric:        g.metric,
+			Tags:          make(map[string]string),
+			AggregateTags: make([]string, 0),
+			Dps:           make(map[string]interface{}),
+		}
+
+		// tags with the same value in all series of group are tags of result, others are aggregated
+		values := make(map[string]map[string]bool)
+		count := make(map[string]int)
+		for _, tags := range g.tags {
+			for k, v := range tags {
+				if values[k] == nil {
+					values[k] = make(map[string]bool)
+				}
+				values[k][v] = true
+				count[k]++
+			}
+		}
+		for k, v := range values {
+			if len(v) > 1 || count[k] < len(g.tags) {
+				res.AggregateTags = append(res.AggregateTags, k)
+				continue
+			}
+			for value := range v {
+				res.Tags[k] = value
+			}
+		}
+		sort.Strings(res.AggregateTags)
+
+		for _, p := range aggregate(g.points, aggr) {
+			var v interface{}
+			if !math.IsNaN(p.Value) {
+				v = p.Value
+			}
+			res.Dps[strconv.FormatInt(int64(p.Time), 10)] = v
+		}
+
+		result = append(result, res)
+	}
+
+	return result, nil
+}
+
+// ServeQuery handles GET with m arguments and POST with JSON body
+func (h *Handler) ServeQuery(w http.ResponseWriter, r *http.Request) {
+	req, err := parseRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	now := time.Now()
+
+	startValue := timeString(req.Start)
+	if startValue == "" {
+		writeError(w, badRequest("missing start time"))
+		return
+	}
+	start, err := parseTime(startValue, now)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	end := now.Unix()
+	if endValue := timeString(req.End); endValue != "" {
+		if end, err = parseTime(endValue, now); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	if end < start {
+		writeError(w, badRequest("end time %d is before start time %d", end, start))
+		return
+	}
+
+	if len(req.Queries) == 0 {
+		writeError(w, badRequest("missing queries"))
+		return
+	}
+
+	result := make([]Result, 0)
+
+	for i := 0; i < len(req.Queries); i++ {
+		res, err := h.query(r, &req.Queries[i], start, end)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		result = append(result, res...)
+	}
+
+	writeJSON(w, result)
+}
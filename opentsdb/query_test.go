@@ -0,0 +1,192 @@
+package opentsdb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/point"
+	"github.com/lomik/graphite-clickhouse/helper/tests"
+	"github.com/lomik/graphite-clickhouse/render"
+)
+
+// testHandler returns handler with clickhouse mock. Tagged table returns two series, data table returns their points
+func testHandler(t *testing.T) (*Handler, func()) {
+	srv := httptest.NewServer(&tests.ClickHouse{
+		Index: []byte("cpu;dc=us;host=web1\ncpu;dc=us;host=web2\n"),
+		Data: append(
+			tests.Points("cpu;dc=us;host=web1", [2]float64{1000, 1}, [2]float64{1020, 3}),
+			tests.Points("cpu;dc=us;host=web2", [2]float64{1000, 10}, [2]float64{1070, 20})...,
+		),
+	})
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.ClickHouse.TaggedTable = "graphite_tagged"
+	cfg.Rollup = tests.Rollup(t, tests.RollupXML)
+
+	return NewHandler(cfg, render.NewHandler(cfg)), srv.Close
+}
+
+func request(h *Handler, method string, url string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), "logger", zap.NewNop()))
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestParseTime(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1500000000, 0)
+
+	table := []struct {
+		value    string
+		expected int64
+	}{
+		{"1400000000", 1400000000},
+		{"1400000000123", 1400000000},
+		{"1h-ago", 1500000000 - 3600},
+		{"2d-ago", 1500000000 - 2*86400},
+		{"5000ms-ago", 1500000000 - 5},
+		{"2017/07/14-02:40:00", time.Date(2017, 7, 14, 2, 40, 0, 0, time.Local).Unix()},
+		{"2017/07/14", time.Date(2017, 7, 14, 0, 0, 0, 0, time.Local).Unix()},
+	}
+
+	for _, test := range table {
+		v, err := parseTime(test.value, now)
+		if assert.NoError(err, test.value) {
+			assert.Equal(test.expected, v, test.value)
+		}
+	}
+
+	for _, value := range []string{"", "1x-ago", "yesterday", "2017-07-14"} {
+		_, err := parseTime(value, now)
+		assert.Error(err, value)
+	}
+}
+
+func TestParseM(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := parseM("sum:1m-avg:sys.cpu.user{host=web*,dc=us}")
+	assert.NoError(err)
+	assert.Equal(Query{
+		Aggregator: "sum",
+		Downsample: "1m-avg",
+		Metric:     "sys.cpu.user",
+		Tags:       map[string]string{"host": "web*", "dc": "us"},
+	}, q)
+
+	q, err = parseM("avg:cpu")
+	assert.NoError(err)
+	assert.Equal(Query{Aggregator: "avg", Metric: "cpu", Tags: map[string]string{}}, q)
+
+	for _, m := range []string{"cpu", "sum:rate:1m-avg:cpu", "sum:cpu{host=web1", "sum:cpu{host}"} {
+		_, err = parseM(m)
+		assert.Error(err, m)
+	}
+}
+
+func TestTarget(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := config.New()
+	h := NewHandler(cfg, nil)
+
+	// graphite path
+	target, groupBy, err := h.Target(&Query{Metric: "a.b.*"})
+	assert.NoError(err)
+	assert.Equal("a.b.*", target)
+	assert.Len(groupBy, 0)
+
+	_, _, err = h.Target(&Query{Metric: "a.b.*", Tags: map[string]string{"host": "*"}})
+	assert.Error(err)
+
+	cfg.ClickHouse.TaggedTable = "graphite_tagged"
+
+	target, groupBy, err = h.Target(&Query{
+		Metric: "cpu",
+		Tags:   map[string]string{"host": "*", "dc": "us", "env": "prod|dev", "rack": "r1.*"},
+	})
+	assert.NoError(err)
+	assert.Equal(`seriesByTag('name=cpu','dc=us','env=~(?:prod|dev)$','host!=','rack=~(?:r1\..*)$')`, target)
+	assert.Equal([]string{"dc", "env", "host", "rack"}, groupBy)
+
+	_, _, err = h.Target(&Query{Metric: "cpu", Tags: map[string]string{"env": "prod|"}})
+	assert.Error(err)
+}
+
+func TestDownsample(t *testing.T) {
+	assert := assert.New(t)
+
+	points := []point.Point{
+		{Time: 1000, Value: 1},
+		{Time: 1010, Value: 3},
+		{Time: 1100, Value: 5},
+	}
+
+	d, err := parseDownsample("1m-sum")
+	assert.NoError(err)
+	assert.Equal([]point.Point{
+		{Time: 960, Value: 4},
+		{Time: 1080, Value: 5},
+	}, d.do(points, 1000, 1200))
+
+	d, err = parseDownsample("1m-count-zero")
+	assert.NoError(err)
+	assert.Equal([]point.Point{
+		{Time: 960, Value: 2},
+		{Time: 1020, Value: 0},
+		{Time: 1080, Value: 1},
+		{Time: 1140, Value: 0},
+		{Time: 1200, Value: 0},
+	}, d.do(points, 1000, 1200))
+
+	for _, s := range []string{"1m", "1m-median", "0s-avg", "1m-avg-linear", "avg-1m"} {
+		_, err = parseDownsample(s)
+		assert.Error(err, s)
+	}
+}
+
+func TestServeQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	h, stop := testHandler(t)
+	defer stop()
+
+	// group by dc: one series with aggregated host
+	w := request(h, "GET", "http://localhost/api/query?start=1000&end=1100&m=sum:cpu{dc=us}", "")
+	assert.Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(`[{"metric":"cpu","tags":{"dc":"us"},"aggregateTags":["host"],"dps":{"1000":11,"1020":3,"1070":20}}]`, w.Body.String())
+
+	// group by host with downsample
+	w = request(h, "POST", "http://localhost/api/query", `{"start":1000,"end":"1100","queries":[
+		{"aggregator":"sum","metric":"cpu","downsample":"1m-avg","tags":{"host":"*"}}
+	]}`)
+	assert.Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(`[{"metric":"cpu","tags":{"dc":"us","host":"web1"},"aggregateTags":[],"dps":{"1020":3,"960":1}},`+
+		`{"metric":"cpu","tags":{"dc":"us","host":"web2"},"aggregateTags":[],"dps":{"1020":20,"960":10}}]`, w.Body.String())
+
+	for _, url := range []string{
+		"http://localhost/api/query?m=sum:cpu",
+		"http://localhost/api/query?start=1000",
+		"http://localhost/api/query?start=1100&end=1000&m=sum:cpu",
+		"http://localhost/api/query?start=1000&m=median:cpu",
+		"http://localhost/api/query?start=1000&end=100000&m=sum:1s-avg-zero:cpu",
+	} {
+		w = request(h, "GET", url, "")
+		assert.Equal(http.StatusBadRequest, w.Code, url)
+	}
+
+	w = request(h, "GET", "http://localhost/api/query?start=1000&m=median:cpu", "")
+	assert.Equal(`{"error":{"code":400,"message":"unknown aggregator \"median\""}}`, w.Body.String())
+}
@@ -0,0 +1,121 @@
+package opentsdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/lomik/graphite-clickhouse/finder"
+	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+)
+
+// defaultSuggestMax is count of suggestions if max is not set, as in OpenTSDB
+const defaultSuggestMax = 25
+
+// SuggestRequest is body of POST /api/suggest or arguments of GET
+type SuggestRequest struct {
+	Type string `json:"type"`
+	Q    string `json:"q"`
+	Max  int    `json:"max"`
+}
+
+// Suggest returns sorted metric names (type "metrics"), tag names ("tagk") or tag values ("tagv") starting with q
+func (h *Handler) Suggest(r *http.Request, req *SuggestRequest) ([]string, error) {
+	cfg := h.config.ClickHouse
+	if cfg.TaggedTable == "" {
+		return nil, badRequest("tagged-table is required for suggest")
+	}
+
+	f := finder.WrapTagged(nil, r.Context(), cfg.Url, cfg.TaggedTable, cfg.TreeTimeout.Value())
+
+	var q *sqlb.Query
+	var err error
+
+	switch req.Type {
+	case "metrics":
+		q, err = f.TagValuesSQL("__name__", req.Q)
+	case "tagk":
+		q, err = f.TagKeysSQL(req.Q)
+	case "tagv":
+		q, err = f.TagValuesSQL("", req.Q)
+	default:
+		return nil, badRequest("invalid type %#v", req.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := clickhouse.Query(r.Context(), cfg.Url, q, cfg.TreeTimeout.Value())
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	result := make([]string, 0)
+
+	for _, line := range bytes.Split(body, []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+
+		value := string(line)
+		if req.Type == "tagk" {
+			if value == "__name__" {
+				continue
+			}
+		} else {
+			// "key=value"
+			value = value[bytes.IndexByte(line, '=')+1:]
+		}
+
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+
+	sort.Strings(result)
+
+	max := req.Max
+	if max <= 0 {
+		max = defaultSuggestMax
+	}
+	if len(result) > max {
+		result = result[:max]
+	}
+
+	return result, nil
+}
+
+// ServeSuggest handles GET with type, q, max arguments and POST with JSON body
+func (h *Handler) ServeSuggest(w http.ResponseWriter, r *http.Request) {
+	req := &SuggestRequest{}
+
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			writeError(w, badRequest("invalid request: %s", err.Error()))
+			return
+		}
+	} else {
+		req.Type = r.URL.Query().Get("type")
+		req.Q = r.URL.Query().Get("q")
+		if max := r.URL.Query().Get("max"); max != "" {
+			var err error
+			if req.Max, err = strconv.Atoi(max); err != nil {
+				writeError(w, badRequest("invalid max %#v", max))
+				return
+			}
+		}
+	}
+
+	result, err := h.Suggest(r, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, result)
+}
@@ -0,0 +1,54 @@
+package opentsdb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/config"
+)
+
+func TestServeSuggest(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		query := string(body) + r.URL.RawQuery
+		switch {
+		case strings.Contains(query, "splitByChar"):
+			w.Write([]byte("host\n__name__\ndc\n"))
+		case strings.Contains(query, "match"):
+			w.Write([]byte("host=web1\ndc=us\nrack=web1\n"))
+		default:
+			w.Write([]byte("__name__=cpu.user\n__name__=cpu.system\n"))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+
+	h := NewHandler(cfg, nil)
+
+	w := request(h, "GET", "http://localhost/api/suggest?type=metrics&q=cpu", "")
+	assert.Equal(http.StatusBadRequest, w.Code)
+
+	cfg.ClickHouse.TaggedTable = "graphite_tagged"
+
+	w = request(h, "GET", "http://localhost/api/suggest?type=metrics&q=cpu", "")
+	assert.Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(`["cpu.system","cpu.user"]`, w.Body.String())
+
+	w = request(h, "GET", "http://localhost/api/suggest?type=tagk&max=1", "")
+	assert.Equal(`["dc"]`, w.Body.String())
+
+	w = request(h, "POST", "http://localhost/api/suggest", `{"type":"tagv","q":"u"}`)
+	assert.Equal(`["us","web1"]`, w.Body.String())
+
+	w = request(h, "GET", "http://localhost/api/suggest?type=tags", "")
+	assert.Equal(http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,339 @@
+package prometheus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lomik/graphite-clickhouse/finder"
+	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/limit"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+)
+
+const (
+	// lookbackDelta is max age of sample returned for step, as in prometheus
+	lookbackDelta = 5 * 60
+	// maxPointsPerSeries limits resolution of query_range, as in prometheus
+	maxPointsPerSeries = 11000
+)
+
+type apiError struct {
+	status    int
+	errorType string
+	err       error
+}
+
+func badData(format string, a ...interface{}) *apiError {
+	return &apiError{status: http.StatusBadRequest, errorType: "bad_data", err: fmt.Errorf(format, a...)}
+}
+
+// fetchError returns 403 for rejected by limits requests and 500 for other errors
+func fetchError(err error) *apiError {
+	status := limit.Status(err)
+	if status == http.StatusForbidden {
+		return &apiError{status: status, errorType: "execution", err: err}
+	}
+	return &apiError{status: status, errorType: "internal", err: err}
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	body, err := json.Marshal(map[string]interface{}{"status": "success", "data": data})
+	if err != nil {
+		writeError(w, &apiError{status: http.StatusInternalServerError, errorType: "internal", err: err})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+func writeError(w http.ResponseWriter, e *apiError) {
+	body, _ := json.Marshal(map[string]string{"status": "error", "errorType": e.errorType, "error": e.err.Error()})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.status)
+	w.Write(body)
+}
+
+// parseTime parses unix timestamp with fraction or RFC3339 time
+func parseTime(s string) (float64, error) {
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return float64(t.UnixNano()) / 1e9, nil
+	}
+	return 0, fmt.Errorf("cannot parse %#v to a valid timestamp", s)
+}
+
+// parseStep parses step in seconds or duration (15s, 1m)
+func parseStep(s string) (float64, error) {
+	if d, err := strconv.ParseFloat(s, 64); err == nil {
+		return d, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d.Seconds(), nil
+	}
+	return 0, fmt.Errorf("cannot parse %#v to a valid duration", s)
+}
+
+// tagLister is tag index of labels metadata: tagged-table or tag-table
+type tagLister interface {
+	TagKeysSQL(prefix string) (*sqlb.Query, error)
+	TagValuesSQL(key string, prefix string) (*sqlb.Query, error)
+}
+
+func (h *Handler) tagLister(r *http.Request) (tagLister, error) {
+	cfg := h.config.ClickHouse
+	switch {
+	case cfg.TaggedTable != "":
+		return finder.WrapTagged(nil, r.Context(), cfg.Url, cfg.TaggedTable, cfg.TreeTimeout.Value()), nil
+	case cfg.TagTable != "":
+		return finder.WrapTag(nil, r.Context(), cfg.Url, cfg.TagTable, cfg.TreeTimeout.Value()), nil
+	}
+	return nil, fmt.Errorf("tagged-table or tag-table is required for labels")
+}
+
+// queryLines runs query and returns non-empty lines of response
+func (h *Handler) queryLines(r *http.Request, q *sqlb.Query) ([]string, error) {
+	body, err := clickhouse.Query(r.Context(), h.config.ClickHouse.Url, q, h.config.ClickHouse.TreeTimeout.Value())
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0)
+	for _, line := range bytes.Split(body, []byte{'\n'}) {
+		if len(line) > 0 {
+			lines = append(lines, string(line))
+		}
+	}
+	return lines, nil
+}
+
+// ServeLabels returns sorted label names of tag index
+func (h *Handler) ServeLabels(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.tagLister(r)
+	if err != nil {
+		writeError(w, badData(err.Error()))
+		return
+	}
+
+	q, err := tags.TagKeysSQL("")
+	if err != nil {
+		writeError(w, fetchError(err))
+		return
+	}
+
+	keys, err := h.queryLines(r, q)
+	if err != nil {
+		writeError(w, fetchError(err))
+		return
+	}
+
+	sort.Strings(keys)
+	writeData(w, keys)
+}
+
+// ServeLabelValues returns sorted values of label from /api/v1/label/<name>/values
+func (h *Handler) ServeLabelValues(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/label/"), "/values")
+	if name == "" || strings.Contains(name, "/") {
+		writeError(w, badData("invalid label name %#v", name))
+		return
+	}
+
+	tags, err := h.tagLister(r)
+	if err != nil {
+		writeError(w, badData(err.Error()))
+		return
+	}
+
+	q, err := tags.TagValuesSQL(name, "")
+	if err != nil {
+		writeError(w, fetchError(err))
+		return
+	}
+
+	rows, err := h.queryLines(r, q)
+	if err != nil {
+		writeError(w, fetchError(err))
+		return
+	}
+
+	values := make([]string, len(rows))
+	for i, row := range rows {
+		values[i] = strings.TrimPrefix(row, name+"=")
+	}
+
+	sort.Strings(values)
+	writeData(w, values)
+}
+
+// ServeSeries returns label sets of series matched any of match[] selectors
+func (h *Handler) ServeSeries(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	selectors := r.Form["match[]"]
+	if len(selectors) == 0 {
+		writeError(w, badData("no match[] parameter provided"))
+		return
+	}
+
+	seen := make(map[string]bool)
+	result := make([]map[string]string, 0)
+
+	for _, selector := range selectors {
+		matchers, err := ParseSelector(selector)
+		if err != nil {
+			writeError(w, badData(err.Error()))
+			return
+		}
+
+		target, err := h.Target(matchers)
+		if err != nil {
+			writeError(w, badData(err.Error()))
+			return
+		}
+
+		f := finder.New(r.Context(), h.config)
+		if err := f.Execute(target); err != nil {
+			writeError(w, fetchError(err))
+			return
+		}
+
+		series := f.Series()
+		if err := limit.Check("max-metrics-in-find-answer", len(series), h.config.Common.MaxMetricsInFindAnswer); err != nil {
+			writeError(w, fetchError(err))
+			return
+		}
+
+		for _, s := range series {
+			name := string(f.Abs(s))
+			if len(s) == 0 || seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			labels := make(map[string]string)
+			for _, l := range Labels(name) {
+				labels[l.Name] = l.Value
+			}
+			result = append(result, labels)
+		}
+	}
+
+	writeData(w, result)
+}
+
+type matrixSeries struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]interface{}  `json:"values"`
+}
+
+// ServeQueryRange evaluates vector selector at each step between start and end.
+// Value at step is the last rolled up point not older than 5 minutes
+func (h *Handler) ServeQueryRange(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	start, err := parseTime(r.Form.Get("start"))
+	if err != nil {
+		writeError(w, badData(err.Error()))
+		return
+	}
+	end, err := parseTime(r.Form.Get("end"))
+	if err != nil {
+		writeError(w, badData(err.Error()))
+		return
+	}
+	if end < start {
+		writeError(w, badData("end timestamp must not be before start time"))
+		return
+	}
+	step, err := parseStep(r.Form.Get("step"))
+	if err != nil {
+		writeError(w, badData(err.Error()))
+		return
+	}
+	if step <= 0 {
+		writeError(w, badData("zero or negative query resolution step widths are not accepted"))
+		return
+	}
+	if (end-start)/step > maxPointsPerSeries {
+		writeError(w, badData("exceeded maximum resolution of %d points per timeseries", maxPointsPerSeries))
+		return
+	}
+
+	matchers, err := ParseSelector(r.Form.Get("query"))
+	if err != nil {
+		writeError(w, badData(err.Error()))
+		return
+	}
+
+	target, err := h.Target(matchers)
+	if err != nil {
+		writeError(w, badData(err.Error()))
+		return
+	}
+
+	from := int64(start) - lookbackDelta
+	until := int64(math.Ceil(end))
+
+	data, err := h.render.Fetch(r.Context(), target, from, until)
+	if err != nil {
+		writeError(w, fetchError(err))
+		return
+	}
+
+	result := make([]matrixSeries, 0)
+
+	for _, s := range h.render.Series(data, int32(from), int32(until)) {
+		values := stepValues(s.Values, int64(s.Start), int64(s.Step), start, end, step)
+		if len(values) == 0 {
+			continue
+		}
+
+		labels := make(map[string]string)
+		for _, l := range Labels(s.Name) {
+			labels[l.Name] = l.Value
+		}
+
+		result = append(result, matrixSeries{Metric: labels, Values: values})
+	}
+
+	writeData(w, map[string]interface{}{"resultType": "matrix", "result": result})
+}
+
+// stepValues returns [time, "value"] for each step. Value is the last not NaN point in (t - lookbackDelta, t]
+func stepValues(points []float64, pointsStart int64, pointsStep int64, start float64, end float64, step float64) [][2]interface{} {
+	var result [][2]interface{}
+
+	// index of last point not after t
+	last := -1
+	for i := 0; start+step*float64(i) <= end; i++ {
+		t := start + step*float64(i)
+
+		for last+1 < len(points) && float64(pointsStart+int64(last+1)*pointsStep) <= t {
+			last++
+		}
+
+		for j := last; j >= 0; j-- {
+			pt := float64(pointsStart + int64(j)*pointsStep)
+			if pt <= t-lookbackDelta {
+				break
+			}
+			if !math.IsNaN(points[j]) {
+				result = append(result, [2]interface{}{t, strconv.FormatFloat(points[j], 'f', -1, 64)})
+				break
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,107 @@
+package prometheus
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+)
+
+func apiRequest(h *Handler, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", url, nil)
+	r = r.WithContext(context.WithValue(r.Context(), "logger", zap.NewNop()))
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestServeSeries(t *testing.T) {
+	assert := assert.New(t)
+
+	h, stop := testHandler(t, nil)
+	defer stop()
+
+	w := apiRequest(h, `http://localhost/api/v1/series?match[]=cpu{dc="us"}&match[]=cpu`)
+	assert.Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(`{"data":[{"__name__":"cpu","dc":"us","host":"web1"}],"status":"success"}`, w.Body.String())
+
+	w = apiRequest(h, "http://localhost/api/v1/series")
+	assert.Equal(http.StatusBadRequest, w.Code)
+	assert.Equal(`{"error":"no match[] parameter provided","errorType":"bad_data","status":"error"}`, w.Body.String())
+}
+
+func TestServeLabels(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !strings.Contains(string(body)+r.URL.RawQuery, "splitByChar") {
+			w.Write([]byte("host=web2\nhost=web1\n"))
+			return
+		}
+		w.Write([]byte("host\ndc\n"))
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+
+	// no tag index
+	h := NewHandler(cfg, nil, nil)
+	w := apiRequest(h, "http://localhost/api/v1/labels")
+	assert.Equal(http.StatusBadRequest, w.Code)
+
+	cfg.ClickHouse.TaggedTable = "graphite_tagged"
+
+	w = apiRequest(h, "http://localhost/api/v1/labels")
+	assert.Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(`{"data":["dc","host"],"status":"success"}`, w.Body.String())
+
+	w = apiRequest(h, "http://localhost/api/v1/label/host/values")
+	assert.Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(`{"data":["web1","web2"],"status":"success"}`, w.Body.String())
+}
+
+func TestServeQueryRange(t *testing.T) {
+	assert := assert.New(t)
+
+	h, stop := testHandler(t, nil)
+	defer stop()
+
+	w := apiRequest(h, `http://localhost/api/v1/query_range?query=cpu{dc="us"}&start=1000&end=1030&step=10s`)
+	assert.Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(`{"data":{"result":[{"metric":{"__name__":"cpu","dc":"us","host":"web1"},`+
+		`"values":[[1000,"1"],[1010,"1"],[1020,"3"],[1030,"3"]]}],"resultType":"matrix"},"status":"success"}`, w.Body.String())
+
+	for _, url := range []string{
+		"http://localhost/api/v1/query_range?query=cpu&start=1000&end=1030",
+		"http://localhost/api/v1/query_range?query=cpu&start=1030&end=1000&step=10",
+		"http://localhost/api/v1/query_range?query=cpu&start=0&end=1000000&step=1",
+		"http://localhost/api/v1/query_range?query=cpu{&start=1000&end=1030&step=10",
+	} {
+		w = apiRequest(h, url)
+		assert.Equal(http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestStepValues(t *testing.T) {
+	assert := assert.New(t)
+
+	points := []float64{1, math.NaN(), 3}
+
+	// no points before 1000, the last point is visible till 1020+300
+	values := stepValues(points, 1000, 10, 995, 1355, 20)
+	if assert.Len(values, 16) {
+		assert.Equal([2]interface{}{float64(1015), "1"}, values[0])
+		assert.Equal([2]interface{}{float64(1035), "3"}, values[1])
+		assert.Equal([2]interface{}{float64(1315), "3"}, values[15])
+	}
+}
@@ -0,0 +1,44 @@
+package prometheus
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/receiver"
+	"github.com/lomik/graphite-clickhouse/render"
+)
+
+// Handler implements Prometheus remote read, remote write and HTTP query API over graphite data table
+type Handler struct {
+	config *config.Config
+	render *render.Handler
+	writer *receiver.Writer
+}
+
+func NewHandler(config *config.Config, render *render.Handler, writer *receiver.Writer) *Handler {
+	return &Handler{
+		config: config,
+		render: render,
+		writer: writer,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/api/v1/read":
+		h.ServeRead(w, r)
+	case r.URL.Path == "/api/v1/write" && h.config.Receiver.PrometheusWrite:
+		h.ServeWrite(w, r)
+	case r.URL.Path == "/api/v1/series":
+		h.ServeSeries(w, r)
+	case r.URL.Path == "/api/v1/labels":
+		h.ServeLabels(w, r)
+	case strings.HasPrefix(r.URL.Path, "/api/v1/label/") && strings.HasSuffix(r.URL.Path, "/values"):
+		h.ServeLabelValues(w, r)
+	case r.URL.Path == "/api/v1/query_range":
+		h.ServeQueryRange(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,159 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/snappy"
+	"github.com/lomik/graphite-clickhouse/helper/tests"
+	"github.com/lomik/graphite-clickhouse/prompb"
+	"github.com/lomik/graphite-clickhouse/render"
+)
+
+// testHandler returns handler with clickhouse mock. Tagged table returns one series, data table returns its points
+func testHandler(t *testing.T, queries chan string) (*Handler, func()) {
+	srv := httptest.NewServer(&tests.ClickHouse{
+		Index:   []byte("cpu;dc=us;host=web1\n"),
+		Data:    tests.Points("cpu;dc=us;host=web1", [2]float64{1000, 1}, [2]float64{1020, 3}),
+		Queries: queries,
+	})
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.ClickHouse.TaggedTable = "graphite_tagged"
+	cfg.Rollup = tests.Rollup(t, tests.RollupXML)
+
+	return NewHandler(cfg, render.NewHandler(cfg), nil), srv.Close
+}
+
+func TestTarget(t *testing.T) {
+	assert := assert.New(t)
+
+	h := NewHandler(config.New(), nil, nil)
+
+	target, err := h.Target([]*prompb.LabelMatcher{{Name: "__name__", Value: "a.b.*"}})
+	assert.NoError(err)
+	assert.Equal("a.b.*", target)
+
+	_, err = h.Target([]*prompb.LabelMatcher{{Name: "__name__", Value: "a.b.*"}, {Name: "dc", Value: "us"}})
+	assert.Error(err)
+
+	h.config.ClickHouse.TaggedTable = "graphite_tagged"
+
+	target, err = h.Target([]*prompb.LabelMatcher{
+		{Name: "__name__", Value: "cpu"},
+		{Type: prompb.LabelMatcher_NEQ, Name: "env", Value: "test"},
+		{Type: prompb.LabelMatcher_RE, Name: "dc", Value: "us|eu"},
+		{Type: prompb.LabelMatcher_NRE, Name: "host", Value: "db'.*"},
+	})
+	assert.NoError(err)
+	assert.Equal(`seriesByTag('__name__=cpu','env!=test','dc=~(?:us|eu)$',"host!=~(?:db'.*)$")`, target)
+
+	_, err = h.Target([]*prompb.LabelMatcher{{Type: prompb.LabelMatcher_RE, Name: "__name__", Value: "cpu"}})
+	assert.Error(err)
+}
+
+func TestLabels(t *testing.T) {
+	assert.Equal(t, []prompb.Label{{Name: "__name__", Value: "a.b"}}, Labels("a.b"))
+	assert.Equal(t, []prompb.Label{
+		{Name: "Zone", Value: "1"},
+		{Name: "__name__", Value: "cpu"},
+		{Name: "host", Value: "web1"},
+	}, Labels("cpu;host=web1;Zone=1"))
+}
+
+func TestRead(t *testing.T) {
+	assert := assert.New(t)
+
+	queries := make(chan string, 1)
+	h, stop := testHandler(t, queries)
+	defer stop()
+
+	request := &prompb.ReadRequest{
+		Queries: []*prompb.Query{
+			{
+				StartTimestampMs: 1000000,
+				EndTimestampMs:   1030000,
+				Matchers: []*prompb.LabelMatcher{
+					{Name: "__name__", Value: "cpu"},
+					{Type: prompb.LabelMatcher_RE, Name: "dc", Value: "us"},
+				},
+			},
+		},
+	}
+	body, err := proto.Marshal(request)
+	assert.NoError(err)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "http://localhost/api/v1/read", bytes.NewReader(snappy.Encode(body)))
+	r = r.WithContext(context.WithValue(r.Context(), "logger", zap.NewNop()))
+	h.ServeHTTP(w, r)
+
+	if !assert.Equal(http.StatusOK, w.Code, w.Body.String()) {
+		return
+	}
+	assert.Equal("snappy", w.Header().Get("Content-Encoding"))
+
+	query := <-queries
+	assert.True(strings.HasPrefix(query, "SELECT Path FROM graphite_tagged"), query)
+	assert.Contains(query, "param_p3=%5Edc%3D%28%3F%3A%28%3F%3Aus%29%24%29")
+
+	body, err = snappy.Decode(w.Body.Bytes())
+	assert.NoError(err)
+
+	var response prompb.ReadResponse
+	assert.NoError(proto.Unmarshal(body, &response))
+
+	assert.Equal(prompb.ReadResponse{
+		Results: []*prompb.QueryResult{
+			{
+				Timeseries: []*prompb.TimeSeries{
+					{
+						Labels: []prompb.Label{
+							{Name: "__name__", Value: "cpu"},
+							{Name: "dc", Value: "us"},
+							{Name: "host", Value: "web1"},
+						},
+						Samples: []prompb.Sample{
+							{Value: 1, Timestamp: 1000000},
+							{Value: 3, Timestamp: 1020000},
+						},
+					},
+				},
+			},
+		},
+	}, response)
+}
+
+func TestReadErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	h, stop := testHandler(t, nil)
+	defer stop()
+
+	// not snappy
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "http://localhost/api/v1/read", strings.NewReader("\xff\xff\xff\xff\xff\xff")))
+	assert.Equal(http.StatusBadRequest, w.Code)
+
+	// no equality matcher
+	body, _ := proto.Marshal(&prompb.ReadRequest{
+		Queries: []*prompb.Query{{Matchers: []*prompb.LabelMatcher{{Type: prompb.LabelMatcher_RE, Name: "__name__", Value: ".*"}}}},
+	})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "http://localhost/api/v1/read", bytes.NewReader(snappy.Encode(body))))
+	assert.Equal(http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost/api/v1/unknown", nil))
+	assert.Equal(http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,76 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lomik/graphite-clickhouse/finder"
+	"github.com/lomik/graphite-clickhouse/prompb"
+)
+
+// matcherOps are seriesByTag operators of prometheus matchers
+var matcherOps = map[prompb.LabelMatcher_Type]string{
+	prompb.LabelMatcher_EQ:  "=",
+	prompb.LabelMatcher_NEQ: "!=",
+	prompb.LabelMatcher_RE:  "=~",
+	prompb.LabelMatcher_NRE: "!=~",
+}
+
+// Target converts matchers to render target.
+// With tagged-table it is seriesByTag of matchers, prometheus regexps are anchored at both ends.
+// Without tagged-table only __name__="graphite.path.glob" is supported
+func (h *Handler) Target(matchers []*prompb.LabelMatcher) (string, error) {
+	if h.config.ClickHouse.TaggedTable == "" {
+		if len(matchers) != 1 || matchers[0].Name != "__name__" || matchers[0].Type != prompb.LabelMatcher_EQ {
+			return "", fmt.Errorf("only __name__ equality matcher is supported without tagged-table")
+		}
+		return matchers[0].Value, nil
+	}
+
+	hasEqual := false
+	exprs := make([]string, 0, len(matchers))
+
+	for _, m := range matchers {
+		op, ok := matcherOps[m.Type]
+		if !ok {
+			return "", fmt.Errorf("unknown matcher type %d", m.Type)
+		}
+
+		value := m.Value
+		if m.Type == prompb.LabelMatcher_RE || m.Type == prompb.LabelMatcher_NRE {
+			value = "(?:" + value + ")$"
+		} else if m.Type == prompb.LabelMatcher_EQ && value != "" {
+			hasEqual = true
+		}
+
+		exprs = append(exprs, m.Name+op+value)
+	}
+
+	if !hasEqual {
+		return "", fmt.Errorf("at least one non-empty equality matcher is required")
+	}
+
+	return finder.SeriesByTag(exprs)
+}
+
+// Labels returns sorted labels of series name. Tagged name "cpu;host=web1" is __name__="cpu", host="web1",
+// graphite path is __name__ label only
+func Labels(name string) []prompb.Label {
+	parts := strings.Split(name, ";")
+
+	labels := make([]prompb.Label, 0, len(parts))
+	labels = append(labels, prompb.Label{Name: "__name__", Value: parts[0]})
+
+	for _, p := range parts[1:] {
+		eq := strings.IndexByte(p, '=')
+		if eq <= 0 {
+			continue
+		}
+		labels = append(labels, prompb.Label{Name: p[:eq], Value: p[eq+1:]})
+	}
+
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+	return labels
+}
@@ -0,0 +1,100 @@
+package prometheus
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/lomik/graphite-clickhouse/helper/limit"
+	"github.com/lomik/graphite-clickhouse/helper/snappy"
+	"github.com/lomik/graphite-clickhouse/prompb"
+)
+
+// maxRequestSize limits compressed body of remote read and write requests
+const maxRequestSize = 64 * 1024 * 1024
+
+// ServeRead handles snappy compressed ReadRequest. Samples are rolled up points, response type is always SAMPLES
+func (h *Handler) ServeRead(w http.ResponseWriter, r *http.Request) {
+	compressed, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := snappy.Decode(compressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request prompb.ReadRequest
+	if err := proto.Unmarshal(body, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := &prompb.ReadResponse{
+		Results: make([]*prompb.QueryResult, len(request.Queries)),
+	}
+
+	for i, q := range request.Queries {
+		target, err := h.Target(q.Matchers)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response.Results[i], err = h.read(r, target, q.StartTimestampMs, q.EndTimestampMs)
+		if err != nil {
+			http.Error(w, err.Error(), limit.Status(err))
+			return
+		}
+	}
+
+	body, err = proto.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Header().Set("Content-Encoding", "snappy")
+	w.Write(snappy.Encode(body))
+}
+
+// read returns samples of target between start and end (in milliseconds)
+func (h *Handler) read(r *http.Request, target string, startMs int64, endMs int64) (*prompb.QueryResult, error) {
+	from := startMs / 1000
+	until := endMs / 1000
+
+	data, err := h.render.Fetch(r.Context(), target, from, until)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &prompb.QueryResult{
+		Timeseries: make([]*prompb.TimeSeries, 0),
+	}
+
+	for _, s := range h.render.Series(data, int32(from), int32(until)) {
+		ts := &prompb.TimeSeries{
+			Labels: Labels(s.Name),
+		}
+
+		for i, v := range s.Values {
+			timestamp := (int64(s.Start) + int64(i)*int64(s.Step)) * 1000
+			if math.IsNaN(v) || timestamp < startMs || timestamp > endMs {
+				continue
+			}
+			ts.Samples = append(ts.Samples, prompb.Sample{Value: v, Timestamp: timestamp})
+		}
+
+		if len(ts.Samples) > 0 {
+			result.Timeseries = append(result.Timeseries, ts)
+		}
+	}
+
+	return result, nil
+}
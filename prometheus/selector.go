@@ -0,0 +1,114 @@
+package prometheus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lomik/graphite-clickhouse/prompb"
+)
+
+// ParseSelector parses vector selector: name{label="value", label!="value", label=~"re", label!~"re"}.
+// Name may be graphite glob (a.b.*) for use without tagged-table. PromQL functions and operators are not supported
+func ParseSelector(s string) ([]*prompb.LabelMatcher, error) {
+	s = strings.TrimSpace(s)
+
+	var matchers []*prompb.LabelMatcher
+
+	brace := strings.IndexByte(s, '{')
+	name := s
+	if brace >= 0 {
+		name = strings.TrimSpace(s[:brace])
+	}
+	if name != "" {
+		matchers = append(matchers, &prompb.LabelMatcher{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: name})
+	}
+
+	if brace >= 0 {
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("unterminated selector %#v", s)
+		}
+
+		body := s[brace+1 : len(s)-1]
+		for {
+			body = strings.TrimLeft(body, " \t,")
+			if body == "" {
+				break
+			}
+
+			m, rest, err := parseMatcher(body)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector %#v: %s", s, err.Error())
+			}
+			matchers = append(matchers, m)
+			body = rest
+		}
+	}
+
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+
+	return matchers, nil
+}
+
+// parseMatcher parses label, operator and quoted value from start of s
+func parseMatcher(s string) (*prompb.LabelMatcher, string, error) {
+	i := 0
+	for i < len(s) && (s[i] == '_' || s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z' || i > 0 && s[i] >= '0' && s[i] <= '9') {
+		i++
+	}
+	if i == 0 {
+		return nil, "", fmt.Errorf("label name expected")
+	}
+
+	m := &prompb.LabelMatcher{Name: s[:i]}
+	s = strings.TrimLeft(s[i:], " \t")
+
+	switch {
+	case strings.HasPrefix(s, "=~"):
+		m.Type, s = prompb.LabelMatcher_RE, s[2:]
+	case strings.HasPrefix(s, "!~"):
+		m.Type, s = prompb.LabelMatcher_NRE, s[2:]
+	case strings.HasPrefix(s, "!="):
+		m.Type, s = prompb.LabelMatcher_NEQ, s[2:]
+	case strings.HasPrefix(s, "="):
+		m.Type, s = prompb.LabelMatcher_EQ, s[1:]
+	default:
+		return nil, "", fmt.Errorf("operator expected after %#v", m.Name)
+	}
+
+	s = strings.TrimLeft(s, " \t")
+	if s == "" {
+		return nil, "", fmt.Errorf("value expected")
+	}
+
+	// find closing quote, skip escaped chars
+	q := s[0]
+	if q != '"' && q != '\'' && q != '`' {
+		return nil, "", fmt.Errorf("value of %#v must be quoted", m.Name)
+	}
+	end := 1
+	for ; end < len(s) && s[end] != q; end++ {
+		if s[end] == '\\' && q != '`' {
+			end++
+		}
+	}
+	if end >= len(s) {
+		return nil, "", fmt.Errorf("unterminated value of %#v", m.Name)
+	}
+
+	quoted := s[:end+1]
+	if q == '\'' {
+		// strconv.Unquote accepts single quotes only for one char
+		quoted = "\"" + strings.Replace(strings.Replace(quoted[1:end], `\'`, `'`, -1), `"`, `\"`, -1) + "\""
+	}
+
+	value, err := strconv.Unquote(quoted)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid value of %#v: %s", m.Name, err.Error())
+	}
+	m.Value = value
+
+	return m, s[end+1:], nil
+}
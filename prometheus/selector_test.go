@@ -0,0 +1,36 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/prompb"
+)
+
+func TestParseSelector(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := ParseSelector(`cpu{dc="us", host=~'web.*',env!="te\"st" , rack!~` + "`r\\d`" + `}`)
+	assert.NoError(err)
+	assert.Equal([]*prompb.LabelMatcher{
+		{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: "cpu"},
+		{Type: prompb.LabelMatcher_EQ, Name: "dc", Value: "us"},
+		{Type: prompb.LabelMatcher_RE, Name: "host", Value: "web.*"},
+		{Type: prompb.LabelMatcher_NEQ, Name: "env", Value: `te"st`},
+		{Type: prompb.LabelMatcher_NRE, Name: "rack", Value: `r\d`},
+	}, m)
+
+	m, err = ParseSelector(`{__name__="cpu"}`)
+	assert.NoError(err)
+	assert.Equal([]*prompb.LabelMatcher{{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: "cpu"}}, m)
+
+	m, err = ParseSelector(`a.b.*`)
+	assert.NoError(err)
+	assert.Equal([]*prompb.LabelMatcher{{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: "a.b.*"}}, m)
+
+	for _, s := range []string{``, `{}`, `cpu{dc="us"`, `cpu{dc=us}`, `cpu{dc~"us"}`, `cpu{="us"}`, `cpu{dc="us}`} {
+		_, err := ParseSelector(s)
+		assert.Error(err, s)
+	}
+}
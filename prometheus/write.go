@@ -0,0 +1,150 @@
+package prometheus
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/helper/log"
+	"github.com/lomik/graphite-clickhouse/helper/point"
+	"github.com/lomik/graphite-clickhouse/helper/snappy"
+	"github.com/lomik/graphite-clickhouse/prompb"
+)
+
+var templateLabel = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// pathNode replaces characters which split path or tags in label value
+var pathNode = strings.NewReplacer(".", "_", " ", "_", ";", "_")
+
+// tagNode replaces characters which split tags in tagged series
+var tagNode = strings.NewReplacer(";", "_", "=", "_")
+
+// TemplatePath returns graphite path of labels by template "{__name__}.{job}.{instance}".
+// Dots in label values are replaced with "_". Returns error if label of template is absent
+func TemplatePath(template string, labels []prompb.Label) (string, error) {
+	values := make(map[string]string, len(labels))
+	for _, l := range labels {
+		values[l.Name] = l.Value
+	}
+
+	var missing string
+	path := templateLabel.ReplaceAllStringFunc(template, func(s string) string {
+		name := s[1 : len(s)-1]
+		v := values[name]
+		if v == "" && missing == "" {
+			missing = name
+		}
+		return pathNode.Replace(v)
+	})
+
+	if missing != "" {
+		return "", fmt.Errorf("label %#v of path template is absent", missing)
+	}
+	return path, nil
+}
+
+// TaggedPath returns tagged series name;label=value with sorted labels. Labels with empty values are skipped
+func TaggedPath(labels []prompb.Label) (string, error) {
+	var name string
+	tags := make([]string, 0, len(labels))
+
+	for _, l := range labels {
+		if l.Value == "" {
+			continue
+		}
+		if l.Name == "__name__" {
+			name = tagNode.Replace(l.Value)
+			continue
+		}
+		tags = append(tags, tagNode.Replace(l.Name)+"="+strings.Replace(l.Value, ";", "_", -1))
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("label __name__ is absent")
+	}
+
+	sort.Strings(tags)
+	return strings.Join(append([]string{name}, tags...), ";"), nil
+}
+
+// ServeWrite handles snappy compressed WriteRequest. Samples are added to receiver batch.
+// Series which can't be converted to path, NaN samples (staleness markers) and samples with seconds
+// out of [0, MaxInt32] are skipped
+func (h *Handler) ServeWrite(w http.ResponseWriter, r *http.Request) {
+	logger := log.FromContext(r.Context())
+
+	compressed, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := snappy.Decode(compressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request prompb.WriteRequest
+	if err := proto.Unmarshal(body, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := int32(time.Now().Unix())
+	template := h.config.Receiver.PrometheusPathTemplate
+
+	var points []point.Point
+	var skipped, skippedSamples int
+
+	for i := 0; i < len(request.Timeseries); i++ {
+		ts := &request.Timeseries[i]
+
+		var path string
+		if template != "" {
+			path, err = TemplatePath(template, ts.Labels)
+		} else {
+			path, err = TaggedPath(ts.Labels)
+		}
+		if err != nil {
+			skipped++
+			logger.Debug("skip series", zap.Error(err))
+			continue
+		}
+
+		for _, s := range ts.Samples {
+			if math.IsNaN(s.Value) {
+				continue
+			}
+			sec := s.Timestamp / 1000
+			if sec < 0 || sec > math.MaxInt32 {
+				skippedSamples++
+				continue
+			}
+			points = append(points, point.Point{
+				Metric:    path,
+				Time:      int32(sec),
+				Value:     s.Value,
+				Timestamp: now,
+			})
+		}
+	}
+
+	if skipped > 0 {
+		logger.Warn("series skipped", zap.Int("count", skipped))
+	}
+	if skippedSamples > 0 {
+		logger.Warn("samples out of time range skipped", zap.Int("count", skippedSamples))
+	}
+
+	h.writer.Add(points...)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,129 @@
+package prometheus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/snappy"
+	"github.com/lomik/graphite-clickhouse/prompb"
+	"github.com/lomik/graphite-clickhouse/receiver"
+)
+
+func TestTemplatePath(t *testing.T) {
+	assert := assert.New(t)
+
+	labels := []prompb.Label{
+		{Name: "__name__", Value: "node_load1"},
+		{Name: "instance", Value: "10.0.0.1:9100"},
+		{Name: "job", Value: "node"},
+	}
+
+	path, err := TemplatePath("prom.{__name__}.{job}.{instance}", labels)
+	assert.NoError(err)
+	assert.Equal("prom.node_load1.node.10_0_0_1:9100", path)
+
+	_, err = TemplatePath("{__name__}.{env}", labels)
+	assert.EqualError(err, `label "env" of path template is absent`)
+}
+
+func TestTaggedPath(t *testing.T) {
+	assert := assert.New(t)
+
+	labels := []prompb.Label{
+		{Name: "job", Value: "node"},
+		{Name: "__name__", Value: "node_load1"},
+		{Name: "empty", Value: ""},
+		{Name: "instance", Value: "10.0.0.1:9100"},
+	}
+
+	path, err := TaggedPath(labels)
+	assert.NoError(err)
+	assert.Equal("node_load1;instance=10.0.0.1:9100;job=node", path)
+
+	// labels of stored series are the same
+	assert.Equal([]prompb.Label{labels[1], labels[3], labels[0]}, Labels(path))
+
+	_, err = TaggedPath(labels[2:])
+	assert.Error(err)
+}
+
+func TestServeWrite(t *testing.T) {
+	assert := assert.New(t)
+
+	var inserts [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(zr)
+		inserts = append(inserts, body)
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+
+	writer, err := receiver.NewWriter(cfg)
+	assert.NoError(err)
+
+	h := NewHandler(cfg, nil, writer)
+
+	request := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels: []prompb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "node"}},
+				Samples: []prompb.Sample{
+					{Value: 1, Timestamp: 1500000000123},
+					{Value: math.NaN(), Timestamp: 1500000015000},
+					// out of range of Time column
+					{Value: 2, Timestamp: -1000},
+					{Value: 3, Timestamp: (math.MaxInt32 + 1) * 1000},
+				},
+			},
+			{
+				// no name
+				Labels:  []prompb.Label{{Name: "job", Value: "node"}},
+				Samples: []prompb.Sample{{Value: 1, Timestamp: 1500000000000}},
+			},
+		},
+	}
+	body, err := proto.Marshal(request)
+	assert.NoError(err)
+
+	// disabled
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "http://localhost/api/v1/write", bytes.NewReader(snappy.Encode(body))))
+	assert.Equal(http.StatusNotFound, w.Code)
+
+	cfg.Receiver.PrometheusWrite = true
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "http://localhost/api/v1/write", bytes.NewReader(snappy.Encode(body))))
+	assert.Equal(http.StatusNoContent, w.Code)
+
+	assert.NoError(writer.Flush())
+
+	// data and tree
+	if assert.Len(inserts, 2) {
+		assert.Contains(string(inserts[0]), "up;job=node")
+		// one point: Path, Value, Time, Date, Timestamp
+		assert.Len(inserts[0], 1+len("up;job=node")+8+4+2+4)
+		assert.Contains(string(inserts[1]), "up;job=node")
+	}
+
+	// not snappy
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "http://localhost/api/v1/write", bytes.NewReader(body)))
+	assert.Equal(http.StatusBadRequest, w.Code)
+}
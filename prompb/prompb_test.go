@@ -0,0 +1,87 @@
+package prompb
+
+import (
+	"testing"
+
+	proto "github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalReadRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	// as sent by prometheus: {start: 1, end: 2, matchers: [a=~"b"]}, accepted_response_types: [SAMPLES]
+	body := []byte{
+		0x0a, 0x0e,
+		0x08, 0x01, 0x10, 0x02, 0x1a, 0x08,
+		0x08, 0x02, 0x12, 0x01, 'a', 0x1a, 0x01, 'b',
+		0x12, 0x01, 0x00,
+	}
+
+	var request ReadRequest
+	assert.NoError(proto.Unmarshal(body, &request))
+
+	assert.Equal(ReadRequest{
+		Queries: []*Query{
+			{
+				StartTimestampMs: 1,
+				EndTimestampMs:   2,
+				Matchers:         []*LabelMatcher{{Type: LabelMatcher_RE, Name: "a", Value: "b"}},
+			},
+		},
+		AcceptedResponseTypes: []int32{0},
+	}, request)
+}
+
+func TestMarshalReadResponse(t *testing.T) {
+	assert := assert.New(t)
+
+	response := &ReadResponse{
+		Results: []*QueryResult{
+			{
+				Timeseries: []*TimeSeries{
+					{
+						Labels:  []Label{{Name: "__name__", Value: "cpu"}},
+						Samples: []Sample{{Value: 1.5, Timestamp: 1000}, {Value: 2, Timestamp: 2000}},
+					},
+				},
+			},
+		},
+	}
+
+	body, err := proto.Marshal(response)
+	assert.NoError(err)
+
+	var decoded ReadResponse
+	assert.NoError(proto.Unmarshal(body, &decoded))
+	assert.Equal(*response, decoded)
+
+	// sample is fixed64 value and varint timestamp
+	body, err = proto.Marshal(&Sample{Value: 1.5, Timestamp: 1})
+	assert.NoError(err)
+	assert.Equal([]byte{0x09, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f, 0x10, 0x01}, body)
+}
+
+func TestUnmarshalWriteRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	// timeseries: [{labels: [__name__="up"], samples: [{value: 1, timestamp: 1000}]}], metadata: [{type: COUNTER}]
+	body := []byte{
+		0x0a, 0x1e,
+		0x0a, 0x0e, 0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_', 0x12, 0x02, 'u', 'p',
+		0x12, 0x0c, 0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f, 0x10, 0xe8, 0x07,
+		0x1a, 0x02, 0x08, 0x01,
+	}
+
+	var request WriteRequest
+	assert.NoError(proto.Unmarshal(body, &request))
+
+	assert.Equal(WriteRequest{
+		Timeseries: []TimeSeries{
+			{
+				Labels:  []Label{{Name: "__name__", Value: "up"}},
+				Samples: []Sample{{Value: 1, Timestamp: 1000}},
+			},
+		},
+	}, request)
+}
@@ -0,0 +1,123 @@
+package receiver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/lomik/graphite-clickhouse/helper/pickle"
+	"github.com/lomik/graphite-clickhouse/helper/point"
+)
+
+var errEmptyPath = errors.New("empty metric path")
+
+// ParsePlain parses "path value timestamp" line. Timestamp -1 is replaced with now
+func ParsePlain(line []byte, now int32) (point.Point, error) {
+	fields := bytes.Fields(line)
+	if len(fields) != 3 {
+		return point.Point{}, fmt.Errorf("bad plaintext line %#v", string(line))
+	}
+
+	value, err := strconv.ParseFloat(string(fields[1]), 64)
+	if err != nil || math.IsNaN(value) {
+		return point.Point{}, fmt.Errorf("bad value in line %#v", string(line))
+	}
+
+	ts, err := strconv.ParseFloat(string(fields[2]), 64)
+	if err != nil || math.IsNaN(ts) || ts > math.MaxInt32 || (ts < 0 && ts != -1) {
+		return point.Point{}, fmt.Errorf("bad timestamp in line %#v", string(line))
+	}
+
+	return newPoint(string(fields[0]), value, ts, now)
+}
+
+func newPoint(path string, value float64, ts float64, now int32) (point.Point, error) {
+	if path == "" {
+		return point.Point{}, errEmptyPath
+	}
+
+	t := int32(ts)
+	if ts == -1 {
+		t = now
+	}
+
+	return point.Point{
+		Metric:    path,
+		Time:      t,
+		Value:     value,
+		Timestamp: now,
+	}, nil
+}
+
+// number returns float of pickled int, float or numeric string
+func number(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// ParsePickle parses pickled list of (path, (timestamp, value)) tuples.
+// Bad metrics are skipped and counted in bad, error is returned if message is not a list
+func ParsePickle(data []byte, now int32) (points []point.Point, bad int, err error) {
+	v, err := pickle.Unpickle(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("pickle message is %T, list expected", v)
+	}
+
+	points = make([]point.Point, 0, len(list))
+
+	for _, item := range list {
+		p, err := parsePickleMetric(item, now)
+		if err != nil {
+			bad++
+			continue
+		}
+		points = append(points, p)
+	}
+
+	return points, bad, nil
+}
+
+// parsePickleMetric parses (path, (timestamp, value)) tuple
+func parsePickleMetric(item interface{}, now int32) (point.Point, error) {
+	metric, ok := item.(pickle.Tuple)
+	if !ok || len(metric) != 2 {
+		return point.Point{}, errors.New("bad pickle metric, (path, (timestamp, value)) expected")
+	}
+
+	path, ok := metric[0].(string)
+	if !ok {
+		return point.Point{}, errors.New("bad pickle metric path")
+	}
+
+	datapoint, ok := metric[1].(pickle.Tuple)
+	if !ok || len(datapoint) != 2 {
+		return point.Point{}, fmt.Errorf("bad pickle datapoint of %#v", path)
+	}
+
+	ts, ok := number(datapoint[0])
+	if !ok || math.IsNaN(ts) || ts > math.MaxInt32 || (ts < 0 && ts != -1) {
+		return point.Point{}, fmt.Errorf("bad pickle timestamp of %#v", path)
+	}
+
+	value, ok := number(datapoint[1])
+	if !ok || math.IsNaN(value) {
+		return point.Point{}, fmt.Errorf("bad pickle value of %#v", path)
+	}
+
+	return newPoint(path, value, ts, now)
+}
@@ -0,0 +1,72 @@
+package receiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/helper/point"
+)
+
+func TestParsePlain(t *testing.T) {
+	assert := assert.New(t)
+
+	table := []struct {
+		line     string
+		expected point.Point
+		err      bool
+	}{
+		{"a.b.c 42 1500000000\n", point.Point{Metric: "a.b.c", Value: 42, Time: 1500000000, Timestamp: 1600000000}, false},
+		{"a.b.c 1.5e3 1500000000.7", point.Point{Metric: "a.b.c", Value: 1500, Time: 1500000000, Timestamp: 1600000000}, false},
+		{"cpu;dc=us -1 -1\r\n", point.Point{Metric: "cpu;dc=us", Value: -1, Time: 1600000000, Timestamp: 1600000000}, false},
+		{"a.b.c 42", point.Point{}, true},
+		{"a.b.c 42 1500000000 x", point.Point{}, true},
+		{"a.b.c nan 1500000000", point.Point{}, true},
+		{"a.b.c 42 -5", point.Point{}, true},
+		{"a.b.c 42 now", point.Point{}, true},
+	}
+
+	for _, test := range table {
+		p, err := ParsePlain([]byte(test.line), 1600000000)
+		if test.err {
+			assert.Error(err, test.line)
+			continue
+		}
+		if assert.NoError(err, test.line) {
+			assert.Equal(test.expected, p, test.line)
+		}
+	}
+}
+
+func TestParsePickle(t *testing.T) {
+	assert := assert.New(t)
+
+	// pickle.dumps([('a.b.c', (1500000000, 1.5)), ('x.y', (1500000001, 2)), ('z', (-1, '3'))], protocol=2)
+	data := []byte("\x80\x02]q\x00(X\x05\x00\x00\x00a.b.cq\x01J\x00/hYG?\xf8\x00\x00\x00\x00\x00\x00\x86q\x02\x86q\x03" +
+		"X\x03\x00\x00\x00x.yq\x04J\x01/hYK\x02\x86q\x05\x86q\x06X\x01\x00\x00\x00zq\x07J\xff\xff\xff\xffX\x01\x00\x00\x003q\x08\x86q\t\x86q\ne.")
+
+	points, bad, err := ParsePickle(data, 1600000000)
+	assert.NoError(err)
+	assert.Equal(0, bad)
+	assert.Equal([]point.Point{
+		{Metric: "a.b.c", Value: 1.5, Time: 1500000000, Timestamp: 1600000000},
+		{Metric: "x.y", Value: 2, Time: 1500000001, Timestamp: 1600000000},
+		{Metric: "z", Value: 3, Time: 1600000000, Timestamp: 1600000000},
+	}, points)
+
+	// pickle.dumps([('a.b', 1500000000, 1)]), datapoint is not tuple
+	points, bad, err = ParsePickle([]byte("(lp0\n(S'a.b'\np1\nI1500000000\nI1\ntp2\na."), 1600000000)
+	assert.NoError(err)
+	assert.Equal(1, bad)
+	assert.Empty(points)
+
+	// pickle.dumps([('a.b', (1500000000, None)), ('c', (1500000000, 1))]), bad value is skipped
+	points, bad, err = ParsePickle([]byte("(lp0\n(S'a.b'\np1\n(I1500000000\nNtp2\ntp3\na(S'c'\np4\n(I1500000000\nI1\ntp5\ntp6\na."), 1600000000)
+	assert.NoError(err)
+	assert.Equal(1, bad)
+	assert.Equal([]point.Point{{Metric: "c", Value: 1, Time: 1500000000, Timestamp: 1600000000}}, points)
+
+	// message is not a list
+	_, _, err = ParsePickle([]byte("I1\n."), 1600000000)
+	assert.Error(err)
+}
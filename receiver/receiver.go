@@ -0,0 +1,188 @@
+package receiver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/point"
+	"github.com/lomik/zapwriter"
+)
+
+const (
+	// maxPickleMessageSize protects against huge allocations by malformed length
+	maxPickleMessageSize = 64 * 1024 * 1024
+	// maxUDPPacketSize is max size of UDP datagram
+	maxUDPPacketSize = 65536
+)
+
+// Receiver accepts graphite plaintext and pickle protocols
+type Receiver struct {
+	config *config.Config
+	writer *Writer
+	logger *zap.Logger
+}
+
+// New returns receiver which adds points to writer. Writer is shared with other protocols and started by caller
+func New(cfg *config.Config, writer *Writer) *Receiver {
+	return &Receiver{
+		config: cfg,
+		writer: writer,
+		logger: zapwriter.Logger("receiver"),
+	}
+}
+
+// Start listens configured addresses
+func (r *Receiver) Start() error {
+	cfg := r.config.Receiver
+
+	if cfg.PlainTCPListen != "" {
+		ln, err := net.Listen("tcp", cfg.PlainTCPListen)
+		if err != nil {
+			return err
+		}
+		go r.accept(ln, r.handlePlain)
+	}
+
+	if cfg.PickleTCPListen != "" {
+		ln, err := net.Listen("tcp", cfg.PickleTCPListen)
+		if err != nil {
+			return err
+		}
+		go r.accept(ln, r.handlePickle)
+	}
+
+	if cfg.PlainUDPListen != "" {
+		conn, err := net.ListenPacket("udp", cfg.PlainUDPListen)
+		if err != nil {
+			return err
+		}
+		go r.receiveUDP(conn)
+	}
+
+	return nil
+}
+
+func (r *Receiver) accept(ln net.Listener, handle func(conn io.Reader) error) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			r.logger.Error("accept failed", zap.Error(err))
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+			if err := handle(conn); err != nil {
+				r.logger.Warn("connection closed", zap.String("peer", conn.RemoteAddr().String()), zap.Error(err))
+			}
+		}()
+	}
+}
+
+func now() int32 {
+	return int32(time.Now().Unix())
+}
+
+// plainLines adds points of lines, bad lines are logged and skipped
+func (r *Receiver) plainLines(lines [][]byte) {
+	t := now()
+	points := make([]point.Point, 0, len(lines))
+
+	for _, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		p, err := ParsePlain(line, t)
+		if err != nil {
+			Dropped.Add("parse", 1)
+			r.logger.Warn("bad line", zap.Error(err))
+			continue
+		}
+		points = append(points, p)
+	}
+
+	r.writer.Add(points...)
+}
+
+// handlePlain reads lines from connection. Lines are added by chunks of already received data
+func (r *Receiver) handlePlain(conn io.Reader) error {
+	reader := bufio.NewReaderSize(conn, 65536)
+	var lines [][]byte
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+
+		if err != nil || reader.Buffered() == 0 {
+			r.plainLines(lines)
+			lines = nil
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handlePickle reads messages prefixed with 4-byte big endian length
+func (r *Receiver) handlePickle(conn io.Reader) error {
+	reader := bufio.NewReader(conn)
+	var header [4]byte
+
+	for {
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		size := binary.BigEndian.Uint32(header[:])
+		if size > maxPickleMessageSize {
+			return fmt.Errorf("pickle message size %d is too large", size)
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return err
+		}
+
+		points, bad, err := ParsePickle(data, now())
+		if err != nil {
+			return err
+		}
+		if bad > 0 {
+			Dropped.Add("parse", int64(bad))
+			r.logger.Warn("bad pickle metrics", zap.Int("count", bad))
+		}
+
+		r.writer.Add(points...)
+	}
+}
+
+func (r *Receiver) receiveUDP(conn net.PacketConn) {
+	buf := make([]byte, maxUDPPacketSize)
+
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			r.logger.Error("udp read failed", zap.Error(err))
+			return
+		}
+
+		r.plainLines(bytes.Split(buf[:n], []byte{'\n'}))
+	}
+}
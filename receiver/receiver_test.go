@@ -0,0 +1,74 @@
+package receiver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/config"
+)
+
+func testReceiver(t *testing.T) *Receiver {
+	cfg := config.New()
+	w, err := NewWriter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(cfg, w)
+}
+
+func TestHandlePlain(t *testing.T) {
+	assert := assert.New(t)
+
+	r := testReceiver(t)
+
+	err := r.handlePlain(strings.NewReader("a.b 1 1500000000\nbad line\n\na.c 2 1500000000"))
+	assert.NoError(err)
+
+	if assert.Len(r.writer.points, 2) {
+		assert.Equal("a.b", r.writer.points[0].Metric)
+		assert.Equal("a.c", r.writer.points[1].Metric)
+	}
+}
+
+func TestHandlePickle(t *testing.T) {
+	assert := assert.New(t)
+
+	r := testReceiver(t)
+
+	// pickle.dumps([('a.b', (1500000000, 1))], protocol=0)
+	message := []byte("(lp0\n(S'a.b'\np1\n(I1500000000\nI1\ntp2\ntp3\na.")
+
+	buf := new(bytes.Buffer)
+	for i := 0; i < 2; i++ {
+		binary.Write(buf, binary.BigEndian, uint32(len(message)))
+		buf.Write(message)
+	}
+
+	assert.NoError(r.handlePickle(buf))
+	assert.Len(r.writer.points, 2)
+
+	// bad metric is skipped and counted: [('a.b', (1500000000, None)), ('c', (1500000000, 1))]
+	dropped := counter(Dropped, "parse")
+	message = []byte("(lp0\n(S'a.b'\np1\n(I1500000000\nNtp2\ntp3\na(S'c'\np4\n(I1500000000\nI1\ntp5\ntp6\na.")
+	buf.Reset()
+	binary.Write(buf, binary.BigEndian, uint32(len(message)))
+	buf.Write(message)
+	assert.NoError(r.handlePickle(buf))
+	assert.Len(r.writer.points, 3)
+	assert.Equal(dropped+1, counter(Dropped, "parse"))
+
+	// truncated message
+	buf.Reset()
+	binary.Write(buf, binary.BigEndian, uint32(len(message)))
+	buf.Write(message[:10])
+	assert.Error(r.handlePickle(buf))
+
+	// too large message
+	buf.Reset()
+	binary.Write(buf, binary.BigEndian, uint32(maxPickleMessageSize+1))
+	assert.Error(r.handlePickle(buf))
+}
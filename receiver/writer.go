@@ -0,0 +1,280 @@
+package receiver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"expvar"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/RowBinary"
+	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/point"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+	"github.com/lomik/zapwriter"
+)
+
+// Dropped counts points dropped by receiver. Key is failed stage: parse of line or pickle metric, encode and insert of batch
+var Dropped = expvar.NewMap("receiver_dropped")
+
+// Writer collects points and inserts them to data table by batches. New paths are inserted to tree and reverse tree.
+// Batch is inserted when it reaches batch-size or every flush-interval
+type Writer struct {
+	config   *config.Config
+	treeDate time.Time
+	logger   *zap.Logger
+
+	mu     sync.Mutex
+	points []point.Point
+	known  map[string]bool // paths inserted to tree, cleared when reaches known-paths
+
+	flushMu sync.Mutex // one insert at time
+	full    chan struct{}
+}
+
+// NewWriter ...
+func NewWriter(cfg *config.Config) (*Writer, error) {
+	treeDate, err := time.ParseInLocation("2006-01-02", cfg.Receiver.TreeDate, time.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		config:   cfg,
+		treeDate: treeDate,
+		logger:   zapwriter.Logger("receiver"),
+		known:    make(map[string]bool),
+		full:     make(chan struct{}, 1),
+	}, nil
+}
+
+// Add appends points to current batch
+func (w *Writer) Add(points ...point.Point) {
+	w.mu.Lock()
+	w.points = append(w.points, points...)
+	full := len(w.points) >= w.config.Receiver.BatchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start inserts batches in background goroutine
+func (w *Writer) Start() {
+	go func() {
+		ticker := time.NewTicker(w.config.Receiver.FlushInterval.Value())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-w.full:
+			}
+			if err := w.Flush(); err != nil {
+				w.logger.Error("flush failed", zap.Error(err))
+			}
+		}
+	}()
+}
+
+// Flush inserts current batch. Batch is dropped on error, paths of failed tree insert are retried with next batch
+func (w *Writer) Flush() error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	points := w.points
+	w.points = nil
+	w.mu.Unlock()
+
+	if len(points) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+
+	var newPaths []string
+	seen := make(map[string]bool)
+	for i := 0; i < len(points); i++ {
+		p := points[i].Metric
+		if !w.known[p] && !seen[p] {
+			seen[p] = true
+			newPaths = append(newPaths, p)
+		}
+	}
+
+	body, err := gzipRows(func(e *RowBinary.Encoder) error { return encodePoints(e, points) })
+	if err != nil {
+		Dropped.Add("encode", int64(len(points)))
+		return err
+	}
+	if err = w.insert(sqlb.NewInsert(w.config.ClickHouse.DataTable, "Path", "Value", "Time", "Date", "Timestamp"), body, w.config.ClickHouse.DataTimeout.Value()); err != nil {
+		Dropped.Add("insert", int64(len(points)))
+		return err
+	}
+
+	if len(newPaths) > 0 {
+		version := uint32(start.Unix())
+		days := RowBinary.DateToUint16(w.treeDate)
+		tables := []struct {
+			table   string
+			reverse bool
+		}{
+			{w.config.ClickHouse.TreeTable, false},
+			{w.config.ClickHouse.ReverseTreeTable, true},
+		}
+
+		for _, t := range tables {
+			if t.table == "" {
+				continue
+			}
+			body, err := gzipRows(func(e *RowBinary.Encoder) error { return encodeTree(e, newPaths, days, version, t.reverse) })
+			if err != nil {
+				return err
+			}
+			if err = w.insert(sqlb.NewInsert(t.table, "Date", "Level", "Path", "Deleted", "Version"), body, w.config.ClickHouse.TreeTimeout.Value()); err != nil {
+				return err
+			}
+		}
+
+		// tree is ReplacingMergeTree, forgotten paths are inserted again with new version
+		if len(w.known)+len(newPaths) > w.config.Receiver.KnownPaths {
+			w.known = make(map[string]bool)
+		}
+		for _, p := range newPaths {
+			w.known[p] = true
+		}
+	}
+
+	w.logger.Info("flush",
+		zap.Int("points", len(points)),
+		zap.Int("new_paths", len(newPaths)),
+		zap.Duration("time", time.Since(start)),
+	)
+
+	return nil
+}
+
+func (w *Writer) insert(insert *sqlb.Insert, body *bytes.Buffer, timeout time.Duration) error {
+	q, err := insert.Format("RowBinary").Build()
+	if err != nil {
+		return err
+	}
+
+	_, err = clickhouse.PostGzip(
+		context.WithValue(context.Background(), "logger", w.logger),
+		w.config.ClickHouse.Url,
+		q,
+		body,
+		timeout,
+	)
+	return err
+}
+
+func gzipRows(encode func(e *RowBinary.Encoder) error) (*bytes.Buffer, error) {
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+
+	if err := encode(RowBinary.NewEncoder(zw)); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// encodePoints writes rows (Path, Value, Time, Date, Timestamp)
+func encodePoints(e *RowBinary.Encoder, points []point.Point) error {
+	for i := 0; i < len(points); i++ {
+		p := &points[i]
+		if err := e.String(p.Metric); err != nil {
+			return err
+		}
+		if err := e.Float64(p.Value); err != nil {
+			return err
+		}
+		if err := e.Uint32(uint32(p.Time)); err != nil {
+			return err
+		}
+		if err := e.Date(time.Unix(int64(p.Time), 0)); err != nil {
+			return err
+		}
+		if err := e.Uint32(uint32(p.Timestamp)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type treeRow struct {
+	Level uint32
+	Path  string
+}
+
+// treeRows returns leaf path and its parent directories ("a.", "a.b.").
+// Tagged series (name;tag=value) are leaf only, reverse tree has no tagged series.
+func treeRows(path string, reverse bool) []treeRow {
+	if strings.IndexByte(path, ';') >= 0 {
+		if reverse {
+			return nil
+		}
+		name := path[:strings.IndexByte(path, ';')]
+		return []treeRow{{Level: uint32(strings.Count(name, ".") + 1), Path: path}}
+	}
+
+	nodes := strings.Split(path, ".")
+	if reverse {
+		for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+			nodes[i], nodes[j] = nodes[j], nodes[i]
+		}
+	}
+
+	rows := make([]treeRow, 0, len(nodes))
+	for i := 1; i < len(nodes); i++ {
+		rows = append(rows, treeRow{Level: uint32(i), Path: strings.Join(nodes[:i], ".") + "."})
+	}
+	rows = append(rows, treeRow{Level: uint32(len(nodes)), Path: strings.Join(nodes, ".")})
+
+	return rows
+}
+
+// encodeTree writes rows (Date, Level, Path, Deleted, Version). Directories shared by paths are written once
+func encodeTree(e *RowBinary.Encoder, paths []string, days uint16, version uint32, reverse bool) error {
+	written := make(map[string]bool)
+
+	for _, p := range paths {
+		for _, row := range treeRows(p, reverse) {
+			if written[row.Path] {
+				continue
+			}
+			written[row.Path] = true
+
+			if err := e.Uint16(days); err != nil {
+				return err
+			}
+			if err := e.Uint32(row.Level); err != nil {
+				return err
+			}
+			if err := e.String(row.Path); err != nil {
+				return err
+			}
+			if err := e.Uint8(0); err != nil {
+				return err
+			}
+			if err := e.Uint32(version); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package receiver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"expvar"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/RowBinary"
+	"github.com/lomik/graphite-clickhouse/helper/point"
+)
+
+func TestTreeRows(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]treeRow{
+		{1, "a."},
+		{2, "a.b."},
+		{3, "a.b.c"},
+	}, treeRows("a.b.c", false))
+
+	assert.Equal([]treeRow{
+		{1, "c."},
+		{2, "c.b."},
+		{3, "c.b.a"},
+	}, treeRows("a.b.c", true))
+
+	assert.Equal([]treeRow{{2, "cpu.load;dc=us.east"}}, treeRows("cpu.load;dc=us.east", false))
+	assert.Nil(treeRows("cpu.load;dc=us.east", true))
+}
+
+type insertMock struct {
+	sync.Mutex
+	queries []string
+	bodies  [][]byte
+	fail    bool
+}
+
+func (m *insertMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.fail {
+		http.Error(w, "clickhouse is down", http.StatusInternalServerError)
+		return
+	}
+
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body, _ := ioutil.ReadAll(zr)
+
+	m.queries = append(m.queries, r.URL.Query().Get("query"))
+	m.bodies = append(m.bodies, body)
+}
+
+func testWriter(t *testing.T, m *insertMock) (*Writer, func()) {
+	srv := httptest.NewServer(m)
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.ClickHouse.ReverseTreeTable = "graphite_reverse_tree"
+	cfg.Receiver.BatchSize = 2
+	cfg.Receiver.FlushInterval = &config.Duration{Duration: time.Hour}
+
+	w, err := NewWriter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return w, srv.Close
+}
+
+func TestWriterFlush(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &insertMock{}
+	w, stop := testWriter(t, m)
+	defer stop()
+
+	// empty batch
+	assert.NoError(w.Flush())
+	assert.Len(m.queries, 0)
+
+	w.Add(
+		point.Point{Metric: "a.b", Value: 1, Time: 1500000000, Timestamp: 1600000000},
+		point.Point{Metric: "a.c", Value: 2, Time: 1500000000, Timestamp: 1600000000},
+		point.Point{Metric: "a.b", Value: 3, Time: 1500000060, Timestamp: 1600000000},
+	)
+	assert.NoError(w.Flush())
+
+	assert.Equal([]string{
+		"INSERT INTO graphite (Path,Value,Time,Date,Timestamp) FORMAT RowBinary",
+		"INSERT INTO graphite_tree (Date,Level,Path,Deleted,Version) FORMAT RowBinary",
+		"INSERT INTO graphite_reverse_tree (Date,Level,Path,Deleted,Version) FORMAT RowBinary",
+	}, m.queries)
+
+	data := new(bytes.Buffer)
+	e := RowBinary.NewEncoder(data)
+	for _, p := range []struct {
+		path  string
+		value float64
+		time  uint32
+	}{{"a.b", 1, 1500000000}, {"a.c", 2, 1500000000}, {"a.b", 3, 1500000060}} {
+		e.String(p.path)
+		e.Float64(p.value)
+		e.Uint32(p.time)
+		e.Date(time.Unix(int64(p.time), 0))
+		e.Uint32(1600000000)
+	}
+	assert.Equal(data.Bytes(), m.bodies[0])
+
+	// "a." is written once. Version is time of flush, so only size of rows is compared
+	tree := new(bytes.Buffer)
+	e = RowBinary.NewEncoder(tree)
+	for _, row := range []treeRow{{1, "a."}, {2, "a.b"}, {2, "a.c"}} {
+		e.Date(w.treeDate)
+		e.Uint32(row.Level)
+		e.String(row.Path)
+		e.Uint8(0)
+		e.Uint32(0)
+	}
+	assert.Len(m.bodies[1], tree.Len())
+	assert.Contains(string(m.bodies[2]), "b.a")
+
+	// known paths are not inserted to tree again
+	m.queries = nil
+	w.Add(point.Point{Metric: "a.c", Value: 4, Time: 1500000060, Timestamp: 1600000000})
+	assert.NoError(w.Flush())
+	assert.Len(m.queries, 1)
+
+	// failed insert
+	dropped := counter(Dropped, "insert")
+	m.fail = true
+	w.Add(
+		point.Point{Metric: "a.d", Value: 4, Time: 1500000060, Timestamp: 1600000000},
+		point.Point{Metric: "a.d", Value: 5, Time: 1500000120, Timestamp: 1600000000},
+	)
+	assert.Error(w.Flush())
+	assert.False(w.known["a.d"])
+	assert.Equal(dropped+2, counter(Dropped, "insert"))
+}
+
+func counter(m *expvar.Map, key string) int64 {
+	if v, ok := m.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestWriterKnownPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &insertMock{}
+	w, stop := testWriter(t, m)
+	defer stop()
+	w.config.Receiver.KnownPaths = 2
+
+	w.Add(point.Point{Metric: "a.b"}, point.Point{Metric: "a.c"})
+	assert.NoError(w.Flush())
+	assert.Len(w.known, 2)
+
+	// set is full, it is cleared before new paths are remembered
+	w.Add(point.Point{Metric: "a.d"})
+	assert.NoError(w.Flush())
+	assert.Equal(map[string]bool{"a.d": true}, w.known)
+
+	// forgotten path is inserted to tree again
+	m.queries = nil
+	w.Add(point.Point{Metric: "a.b"})
+	assert.NoError(w.Flush())
+	assert.Len(m.queries, 3)
+	assert.Len(w.known, 2)
+}
+
+func TestWriterBatchSize(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &insertMock{}
+	w, stop := testWriter(t, m)
+	defer stop()
+
+	w.Add(point.Point{Metric: "a.b", Value: 1, Time: 1500000000})
+	assert.Len(w.full, 0)
+
+	w.Add(point.Point{Metric: "a.b", Value: 2, Time: 1500000060})
+	assert.Len(w.full, 1)
+
+	// signal is not blocked by pending one
+	w.Add(point.Point{Metric: "a.b", Value: 3, Time: 1500000120})
+	assert.Len(w.full, 1)
+}
@@ -169,3 +169,31 @@ func (d *Data) Less(i, j int) bool {
 func (d *Data) Swap(i, j int) {
 	d.Points[i], d.Points[j] = d.Points[j], d.Points[i]
 }
+
+// MergeData concatenates points of several Data with disjoint metric sets.
+// MetricID of each part is shifted so ids stay unique
+func MergeData(list []*Data) *Data {
+	count := 0
+	for _, d := range list {
+		count += len(d.Points)
+	}
+
+	result := &Data{
+		Points:   make([]point.Point, 0, count),
+		nameToID: make(map[string]int),
+	}
+
+	for _, d := range list {
+		offset := result.maxID
+		for _, p := range d.Points {
+			p.MetricID += offset
+			result.Points = append(result.Points, p)
+		}
+		for name, id := range d.nameToID {
+			result.nameToID[name] = id + offset
+		}
+		result.maxID += d.maxID
+	}
+
+	return result
+}
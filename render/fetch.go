@@ -0,0 +1,158 @@
+package render
+
+import (
+	"context"
+	"hash/crc32"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/point"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+)
+
+// splitSeries splits series list into n chunks by count or by hash of name. Empty chunks are omitted
+func splitSeries(list [][]byte, n int, by string) [][][]byte {
+	if n < 1 {
+		n = 1
+	}
+
+	chunks := make([][][]byte, n)
+
+	if by == config.ChunkByHash {
+		for _, m := range list {
+			index := crc32.ChecksumIEEE(m) % uint32(n)
+			chunks[index] = append(chunks[index], m)
+		}
+	} else {
+		size := (len(list) + n - 1) / n
+		for i := 0; i < n && i*size < len(list); i++ {
+			end := (i + 1) * size
+			if end > len(list) {
+				end = len(list)
+			}
+			chunks[i] = list[i*size : end]
+		}
+	}
+
+	skip := 0
+	for i := 0; i < len(chunks); i++ {
+		if len(chunks[i]) == 0 {
+			skip++
+			continue
+		}
+		if skip > 0 {
+			chunks[i-skip] = chunks[i]
+		}
+	}
+
+	return chunks[:len(chunks)-skip]
+}
+
+// fetchData runs query for each chunk of series concurrently and parses results in parallel.
+// Points of each metric are placed in one contiguous sorted group. Returns first error of any chunk
+func (h *Handler) fetchData(ctx context.Context, logger *zap.Logger, query *sqlb.Query, metricList [][]byte, carbonlinkResponseRead func() []point.Point) (*Data, error) {
+	chunks := splitSeries(metricList, h.config.ClickHouse.DataChunks, h.config.ClickHouse.DataChunkBy)
+
+	// carbonlink response is fetched once and distributed between chunks by metric name
+	var carbonlinkOnce sync.Once
+	carbonlinkData := make([][]point.Point, len(chunks))
+	readCarbonlink := func() {
+		points := carbonlinkResponseRead()
+		if len(points) == 0 {
+			return
+		}
+
+		chunkIndex := make(map[string]int)
+		for i, chunk := range chunks {
+			for _, m := range chunk {
+				chunkIndex[unsafeString(m)] = i
+			}
+		}
+
+		for _, p := range points {
+			if i, exists := chunkIndex[p.Metric]; exists {
+				carbonlinkData[i] = append(carbonlinkData[i], p)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := make([]*Data, len(chunks))
+	errors := make(chan error, len(chunks))
+	sem := make(chan struct{}, h.config.ClickHouse.DataConcurrency)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < len(chunks); i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errors <- ctx.Err()
+				return
+			}
+
+			pathTable := clickhouse.NewExternalTable("_paths", "Path String")
+			for _, m := range chunks[index] {
+				pathTable.Append(m)
+			}
+
+			body, err := clickhouse.QueryWithExternalData(
+				ctx,
+				h.config.ClickHouse.Url,
+				query,
+				[]*clickhouse.ExternalTable{pathTable},
+				h.config.ClickHouse.DataTimeout.Value(),
+			)
+			<-sem
+
+			if err != nil {
+				errors <- err
+				cancel()
+				return
+			}
+
+			carbonlinkOnce.Do(readCarbonlink)
+
+			parseStart := time.Now()
+
+			data, err := DataParse(body, carbonlinkData[index])
+			if err != nil {
+				errors <- err
+				cancel()
+				return
+			}
+
+			d := time.Since(parseStart)
+			logger.Debug("parse", zap.Int("chunk", index), zap.String("runtime", d.String()), zap.Duration("runtime_ns", d))
+
+			sortStart := time.Now()
+			sort.Sort(data)
+			d = time.Since(sortStart)
+			logger.Debug("sort", zap.Int("chunk", index), zap.String("runtime", d.String()), zap.Duration("runtime_ns", d))
+
+			data.Points = point.Uniq(data.Points)
+			result[index] = data
+		}(i)
+	}
+
+	wg.Wait()
+	close(errors)
+
+	// first error is the cause, others can be context.Canceled
+	if err := <-errors; err != nil {
+		return nil, err
+	}
+
+	return MergeData(result), nil
+}
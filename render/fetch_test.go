@@ -0,0 +1,139 @@
+package render
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/point"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+	"github.com/lomik/graphite-clickhouse/helper/tests"
+)
+
+func TestSplitSeries(t *testing.T) {
+	assert := assert.New(t)
+
+	list := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+
+	chunks := splitSeries(list, 2, config.ChunkByCount)
+	assert.Equal([][][]byte{list[:3], list[3:]}, chunks)
+
+	chunks = splitSeries(list, 10, config.ChunkByCount)
+	assert.Len(chunks, 5)
+
+	chunks = splitSeries(list, 1, config.ChunkByCount)
+	assert.Equal([][][]byte{list}, chunks)
+
+	chunks = splitSeries(list, 3, config.ChunkByHash)
+	count := 0
+	for _, c := range chunks {
+		assert.NotEmpty(c)
+		count += len(c)
+	}
+	assert.Equal(len(list), count)
+}
+
+func TestFetchData(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("_paths")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(file)
+		paths := strings.Split(strings.TrimSpace(string(body)), "\n")
+		if paths[0] == "fail" {
+			http.Error(w, "fail", http.StatusInternalServerError)
+			return
+		}
+		// same metric twice
+		for i, p := range append(paths, paths...) {
+			w.Write(tests.Points(p, [2]float64{float64(1000 + i), float64(i)}))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.ClickHouse.DataChunks = 3
+	cfg.ClickHouse.DataConcurrency = 2
+
+	h := NewHandler(cfg)
+
+	list := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")}
+	noCarbonlink := func() []point.Point { return nil }
+
+	query, err := sqlb.NewSelect("Path", "Time", "Value", "Timestamp").From("graphite").Where(sqlb.InTable("Path", "_paths")).Build()
+	assert.NoError(err)
+
+	data, err := h.fetchData(context.Background(), zap.NewNop(), query, list, noCarbonlink)
+	assert.NoError(err)
+	assert.Len(data.Points, 8)
+
+	// points of each metric are grouped
+	seen := make(map[string]bool)
+	for i, p := range data.Points {
+		if i > 0 && data.Points[i-1].Metric == p.Metric {
+			continue
+		}
+		assert.False(seen[p.Metric], p.Metric)
+		seen[p.Metric] = true
+	}
+	assert.Len(seen, 4)
+
+	_, err = h.fetchData(context.Background(), zap.NewNop(), query, [][]byte{[]byte("fail"), []byte("b")}, noCarbonlink)
+	assert.Error(err)
+}
+
+func TestFetchDataCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("_paths")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(file)
+		if strings.TrimSpace(string(body)) == "fail" {
+			http.Error(w, "fail", http.StatusInternalServerError)
+			return
+		}
+		// slow chunk waits until client aborts request
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+		w.Write(tests.Points("slow", [2]float64{1000, 0}))
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.ClickHouse.DataChunks = 4
+	cfg.ClickHouse.DataConcurrency = 4
+
+	h := NewHandler(cfg)
+
+	list := [][]byte{[]byte("fail"), []byte("b"), []byte("c"), []byte("d")}
+	noCarbonlink := func() []point.Point { return nil }
+
+	query, err := sqlb.NewSelect("Path", "Time", "Value", "Timestamp").From("graphite").Where(sqlb.InTable("Path", "_paths")).Build()
+	assert.NoError(err)
+
+	start := time.Now()
+	_, err = h.fetchData(context.Background(), zap.NewNop(), query, list, noCarbonlink)
+	assert.Error(err)
+	assert.Contains(err.Error(), "fail")
+	assert.True(time.Since(start) < 5*time.Second, time.Since(start).String())
+}
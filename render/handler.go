@@ -2,11 +2,8 @@ package render
 
 import (
 	"bufio"
-	"bytes"
 	"context"
-	"fmt"
 	"net/http"
-	"sort"
 	"strconv"
 	"time"
 
@@ -16,10 +13,12 @@ import (
 	"github.com/lomik/graphite-clickhouse/carbonzipperpb"
 	"github.com/lomik/graphite-clickhouse/config"
 	"github.com/lomik/graphite-clickhouse/finder"
-	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/limit"
 	"github.com/lomik/graphite-clickhouse/helper/log"
+	"github.com/lomik/graphite-clickhouse/helper/msgpack"
 	"github.com/lomik/graphite-clickhouse/helper/pickle"
 	"github.com/lomik/graphite-clickhouse/helper/point"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
 
 	graphitePickle "github.com/lomik/graphite-pickle"
 )
@@ -104,11 +103,15 @@ func (h *Handler) queryCarbonlink(parentCtx context.Context, logger *zap.Logger,
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("format") == "carbonapi_v3_pb" {
+		h.ServeV3(w, r)
+		return
+	}
+
 	logger := log.FromContext(r.Context())
 	target := r.URL.Query().Get("target")
 
 	var prefix string
-	var err error
 
 	fromTimestamp, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 32)
 	if err != nil {
@@ -122,23 +125,41 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	data, err := h.fetch(r.Context(), logger, target, fromTimestamp, untilTimestamp)
+	if err != nil {
+		http.Error(w, err.Error(), limit.Status(err))
+		return
+	}
+
+	// pp.Println(points)
+	h.Reply(w, r, data, int32(fromTimestamp), int32(untilTimestamp), prefix)
+}
+
+// fetch finds series of target and fetches their points. Data.Finder is set
+func (h *Handler) fetch(ctx context.Context, logger *zap.Logger, target string, fromTimestamp int64, untilTimestamp int64) (*Data, error) {
 	// Search in small index table first
-	finder := finder.New(r.Context(), h.config)
+	finder := finder.New(ctx, h.config)
+
+	if subquery, ok := h.treeSubquery(finder, target); ok {
+		return h.fetchSubquery(ctx, logger, finder, subquery, fromTimestamp, untilTimestamp)
+	}
 
-	err = finder.Execute(target)
+	err := finder.Execute(target)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
 	metricList := finder.Series()
 
-	maxStep := int32(0)
+	if err := limit.Check("max-metrics-in-render-answer", len(metricList), h.config.Common.MaxMetricsInRenderAnswer); err != nil {
+		return nil, err
+	}
 
-	listBuf := bytes.NewBuffer(nil)
+	maxStep := int32(0)
 
-	// make Path IN (...), calculate max step
-	for index, m := range metricList {
+	// calculate max step, skip empty names
+	seriesList := make([][]byte, 0, len(metricList))
+	for _, m := range metricList {
 		if len(m) == 0 {
 			continue
 		}
@@ -147,92 +168,56 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			maxStep = step
 		}
 
-		if index > 0 {
-			listBuf.Write([]byte{','})
-		}
-
-		listBuf.WriteString("'" + clickhouse.Escape(unsafeString(m)) + "'")
+		seriesList = append(seriesList, m)
 	}
 
-	if listBuf.Len() == 0 {
+	if len(seriesList) == 0 {
 		// Return empty response
-		h.Reply(w, r, &Data{Points: make([]point.Point, 0), Finder: finder}, 0, 0, "")
-		return
+		return &Data{Points: make([]point.Point, 0), Finder: finder}, nil
 	}
 
-	var pathWhere = fmt.Sprintf(
-		"Path IN (%s)",
-		string(listBuf.Bytes()),
-	)
-
-	until := untilTimestamp - untilTimestamp%int64(maxStep) + int64(maxStep) - 1
-	dateWhere := fmt.Sprintf(
-		"(Date >='%s' AND Date <= '%s')",
-		time.Unix(fromTimestamp, 0).Format("2006-01-02"),
-		time.Unix(untilTimestamp, 0).Format("2006-01-02"),
-	)
-	timeWhere := fmt.Sprintf(
-		"(Time >= %d AND Time <= %d)",
-		fromTimestamp,
-		until,
-	)
-
-	query := fmt.Sprintf(
-		`
-		SELECT
-			Path, Time, Value, Timestamp
-		FROM %s
-		PREWHERE (%s)
-		WHERE (%s) AND (%s)
-		FORMAT RowBinary
-		`,
-		h.config.ClickHouse.DataTable,
-		dateWhere,
-		pathWhere,
-		timeWhere,
-	)
-
-	// start carbonlink request
-	carbonlinkResponseRead := h.queryCarbonlink(r.Context(), logger, metricList)
-
-	body, err := clickhouse.Query(
-		r.Context(),
-		h.config.ClickHouse.Url,
-		query,
-		h.config.ClickHouse.DataTimeout.Value(),
-	)
+	// estimate points count with max step. Real count can be less
+	pointsCount := int64(len(seriesList)) * ((untilTimestamp-fromTimestamp)/int64(maxStep) + 1)
+	if err := limit.Check("max-points-in-render-answer", int(pointsCount), h.config.Common.MaxPointsInRenderAnswer); err != nil {
+		return nil, err
+	}
 
+	// series list is sent as external data table _paths, so query size does not depend on series count
+	query, err := h.dataQuery(sqlb.InTable("Path", "_paths"), fromTimestamp, untilTimestamp, maxStep)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	// fetch carbonlink response
-	carbonlinkData := carbonlinkResponseRead()
-
-	parseStart := time.Now()
-
-	// pass carbonlinkData to DataParse
-	data, err := DataParse(body, carbonlinkData)
+	// start carbonlink request
+	carbonlinkResponseRead := h.queryCarbonlink(ctx, logger, metricList)
 
+	data, err := h.fetchData(ctx, logger, query, seriesList, carbonlinkResponseRead)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	d := time.Since(parseStart)
-	logger.Debug("parse", zap.String("runtime", d.String()), zap.Duration("runtime_ns", d))
+	data.Finder = finder
 
-	sortStart := time.Now()
-	sort.Sort(data)
-	d = time.Since(sortStart)
-	logger.Debug("sort", zap.String("runtime", d.String()), zap.Duration("runtime_ns", d))
+	return data, nil
+}
 
-	data.Points = point.Uniq(data.Points)
-	data.Finder = finder
+// dataQuery returns query for points of series selected by pathCond
+func (h *Handler) dataQuery(pathCond sqlb.Cond, fromTimestamp int64, untilTimestamp int64, maxStep int32) (*sqlb.Query, error) {
+	until := untilTimestamp - untilTimestamp%int64(maxStep) + int64(maxStep) - 1
 
-	// pp.Println(points)
-	h.Reply(w, r, data, int32(fromTimestamp), int32(untilTimestamp), prefix)
+	return sqlb.NewSelect("Path", "Time", "Value", "Timestamp").
+		From(h.config.ClickHouse.DataTable).
+		Prewhere(
+			sqlb.Ge("Date", sqlb.Date(time.Unix(fromTimestamp, 0))),
+			sqlb.Le("Date", sqlb.Date(time.Unix(untilTimestamp, 0))),
+		).
+		Where(
+			pathCond,
+			sqlb.Ge("Time", uint32(fromTimestamp)),
+			sqlb.Le("Time", uint32(until)),
+		).
+		Format("RowBinary").
+		Build()
 }
 
 func (h *Handler) Reply(w http.ResponseWriter, r *http.Request, data *Data, from, until int32, prefix string) {
@@ -242,6 +227,8 @@ func (h *Handler) Reply(w http.ResponseWriter, r *http.Request, data *Data, from
 		h.ReplyPickle(w, r, data, from, until, prefix)
 	case "protobuf":
 		h.ReplyProtobuf(w, r, data, from, until, prefix)
+	case "msgpack":
+		h.ReplyMsgpack(w, r, data, from, until, prefix)
 	}
 	d := time.Since(start)
 	log.FromContext(r.Context()).Debug("reply", zap.String("runtime", d.String()), zap.Duration("runtime_ns", d))
@@ -417,3 +404,88 @@ func (h *Handler) ReplyProtobuf(w http.ResponseWriter, r *http.Request, data *Da
 	body, _ := proto.Marshal(&multiResponse)
 	w.Write(body)
 }
+
+func (h *Handler) ReplyMsgpack(w http.ResponseWriter, r *http.Request, data *Data, from, until int32, prefix string) {
+	points := data.Points
+
+	w.Header().Set("Content-Type", "application/x-msgpack")
+
+	if len(points) == 0 {
+		w.Write(msgpack.EmptyArray)
+		return
+	}
+
+	writer := bufio.NewWriterSize(w, 1024*1024)
+	p := msgpack.NewWriter(writer)
+	defer writer.Flush()
+
+	pathExpression := r.URL.Query().Get("target")
+
+	// msgpack array needs count of metrics before items
+	count := 1
+	for i := 1; i < len(points); i++ {
+		if points[i].Metric != points[i-1].Metric {
+			count++
+		}
+	}
+	p.Array(count)
+
+	writeMetric := func(points []point.Point) {
+		points, step := h.config.Rollup.RollupMetric(points)
+
+		start := from - (from % step)
+		if start < from {
+			start += step
+		}
+		end := until - (until % step)
+
+		p.Map(6)
+
+		p.String("name")
+		p.Bytes(data.Finder.Abs([]byte(points[0].Metric)))
+
+		p.String("pathExpression")
+		p.String(pathExpression)
+
+		p.String("start")
+		p.Uint32(uint32(start))
+
+		p.String("end")
+		p.Uint32(uint32(end))
+
+		p.String("step")
+		p.Uint32(uint32(step))
+
+		p.String("values")
+		p.Array(int((end-start)/step) + 1)
+
+		var index int
+		// skip points before start
+		for index = 0; index < len(points) && points[index].Time < start; index++ {
+		}
+
+		for t := start; t <= end; t += step {
+			if index < len(points) && points[index].Time == t {
+				p.Float64(points[index].Value)
+				index++
+			} else {
+				p.Nil()
+			}
+		}
+	}
+
+	// group by Metric
+	var i, n int
+	// i - current position of iterator
+	// n - position of the first record with current metric
+	l := len(points)
+
+	for i = 1; i < l; i++ {
+		if points[i].Metric != points[n].Metric {
+			writeMetric(points[n:i])
+			n = i
+			continue
+		}
+	}
+	writeMetric(points[n:i])
+}
@@ -0,0 +1,43 @@
+package render
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/tests"
+)
+
+func TestReplyMsgpack(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(&tests.ClickHouse{
+		Index: []byte("a.b\n"),
+		Data:  tests.Points("a.b", [2]float64{1000, 0}),
+	})
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.Rollup = tests.Rollup(t, tests.RollupXML)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "http://localhost/render/?format=msgpack&target=a.*&from=1000&until=1010", nil)
+	r = r.WithContext(context.WithValue(r.Context(), "logger", zap.NewNop()))
+	NewHandler(cfg).ServeHTTP(w, r)
+
+	expected := []byte{0x91, 0x86}
+	expected = append(expected, "\xa4name\xa3a.b"...)
+	expected = append(expected, "\xaepathExpression\xa3a.*"...)
+	expected = append(expected, "\xa5start\xcd\x03\xe8"...)
+	expected = append(expected, "\xa3end\xcd\x03\xf2"...)
+	expected = append(expected, "\xa4step\x0a"...)
+	expected = append(expected, "\xa6values\x92\xcb\x00\x00\x00\x00\x00\x00\x00\x00\xc0"...)
+
+	assert.Equal("application/x-msgpack", w.Header().Get("Content-Type"))
+	assert.Equal(expected, w.Body.Bytes())
+}
@@ -0,0 +1,93 @@
+package render
+
+import (
+	"context"
+	"math"
+
+	"github.com/lomik/graphite-clickhouse/helper/log"
+	"github.com/lomik/graphite-clickhouse/helper/point"
+)
+
+// Series is rolled up points of one metric aligned to step. Absent values are NaN
+type Series struct {
+	Metric string // name in clickhouse
+	Name   string // name with extra prefix
+	Start  int32
+	Stop   int32
+	Step   int32
+	Values []float64
+}
+
+// Fetch returns points of target sorted by metric and time. Used by APIs other than graphite render
+func (h *Handler) Fetch(ctx context.Context, target string, fromTimestamp int64, untilTimestamp int64) (*Data, error) {
+	return h.fetch(ctx, log.FromContext(ctx), target, fromTimestamp, untilTimestamp)
+}
+
+// Series groups points by metric and rolls them up. Values are aligned to step from first step after from to until
+func (h *Handler) Series(data *Data, from int32, until int32) []Series {
+	points := data.Points
+
+	if len(points) == 0 {
+		return nil
+	}
+
+	var result []Series
+
+	writeMetric := func(points []point.Point) {
+		metric := points[0].Metric
+
+		points, step := h.config.Rollup.RollupMetric(points)
+
+		start := from - (from % step)
+		if start < from {
+			start += step
+		}
+		stop := until - (until % step)
+		count := ((stop - start) / step) + 1
+		if count < 0 {
+			count = 0
+		}
+
+		s := Series{
+			Metric: metric,
+			Name:   string(data.Finder.Abs([]byte(metric))),
+			Start:  start,
+			Stop:   stop,
+			Step:   step,
+			Values: make([]float64, count),
+		}
+
+		var index int32
+		// skip points before start
+		for index = 0; index < int32(len(points)) && points[index].Time < start; index++ {
+		}
+
+		for i := int32(0); i < count; i++ {
+			if index < int32(len(points)) && points[index].Time == start+step*i {
+				s.Values[i] = points[index].Value
+				index++
+			} else {
+				s.Values[i] = math.NaN()
+			}
+		}
+
+		result = append(result, s)
+	}
+
+	// group by Metric
+	var i, n int
+	// i - current position of iterator
+	// n - position of the first record with current metric
+	l := len(points)
+
+	for i = 1; i < l; i++ {
+		if points[i].Metric != points[n].Metric {
+			writeMetric(points[n:i])
+			n = i
+			continue
+		}
+	}
+	writeMetric(points[n:i])
+
+	return result
+}
@@ -0,0 +1,77 @@
+package render
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/finder"
+	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/point"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+)
+
+// treeSubquery returns subquery selecting series of glob target from tree table.
+// Only for plain BaseFinder/ReverseFinder chains. Series count is unknown before data query,
+// so mode is disabled if render limits or carbonlink are configured
+func (h *Handler) treeSubquery(f finder.Finder, target string) (*sqlb.Select, bool) {
+	if !h.config.ClickHouse.TreeSubquery || !finder.HasWildcard(target) {
+		return nil, false
+	}
+
+	if h.carbonlink != nil ||
+		h.config.Common.MaxMetricsInRenderAnswer > 0 ||
+		h.config.Common.MaxPointsInRenderAnswer > 0 {
+		return nil, false
+	}
+
+	sf, ok := f.(finder.SubqueryFinder)
+	if !ok {
+		return nil, false
+	}
+
+	return sf.SeriesSubquery(target)
+}
+
+// fetchSubquery fetches points with single query. ClickHouse resolves series itself,
+// step for until rounding is max step of rollup patterns
+func (h *Handler) fetchSubquery(ctx context.Context, logger *zap.Logger, f finder.Finder, subquery *sqlb.Select, fromTimestamp int64, untilTimestamp int64) (*Data, error) {
+	maxStep := h.config.Rollup.MaxStep(int32(fromTimestamp))
+
+	query, err := h.dataQuery(sqlb.InSelect("Path", subquery), fromTimestamp, untilTimestamp, maxStep)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := clickhouse.Query(
+		ctx,
+		h.config.ClickHouse.Url,
+		query,
+		h.config.ClickHouse.DataTimeout.Value(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	parseStart := time.Now()
+
+	data, err := DataParse(body, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	d := time.Since(parseStart)
+	logger.Debug("parse", zap.String("runtime", d.String()), zap.Duration("runtime_ns", d))
+
+	sortStart := time.Now()
+	sort.Sort(data)
+	d = time.Since(sortStart)
+	logger.Debug("sort", zap.String("runtime", d.String()), zap.Duration("runtime_ns", d))
+
+	data.Points = point.Uniq(data.Points)
+	data.Finder = f
+
+	return data, nil
+}
@@ -0,0 +1,53 @@
+package render
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/finder"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+)
+
+func TestTreeSubquery(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := config.New()
+	cfg.ClickHouse.TreeSubquery = true
+	h := NewHandler(cfg)
+
+	s, ok := h.treeSubquery(finder.New(context.Background(), cfg), "host.*.cpu")
+	assert.True(ok)
+
+	q, err := h.dataQuery(sqlb.InSelect("Path", s), 1500000000, 1500003600, 60)
+	assert.NoError(err)
+	assert.Equal("SELECT Path, Time, Value, Timestamp FROM graphite PREWHERE (Date >= {p0:Date}) AND (Date <= {p1:Date}) WHERE (Path IN (SELECT Path FROM graphite_tree WHERE (Level = {p2:UInt32}) AND (Path LIKE {p3:String}) AND (match(Path, {p4:String})) GROUP BY Path HAVING argMax(Deleted, Version)==0)) AND (Time >= {p5:UInt32}) AND (Time <= {p6:UInt32}) FORMAT RowBinary", q.String())
+	assert.Equal("3", q.Params().Get("param_p2"))
+	assert.Equal("host.%", q.Params().Get("param_p3"))
+	assert.Equal("^host.([^.]*?).cpu[.]?$", q.Params().Get("param_p4"))
+	assert.Equal("1500000000", q.Params().Get("param_p5"))
+	assert.Equal("1500003659", q.Params().Get("param_p6"))
+
+	// not glob
+	_, ok = h.treeSubquery(finder.New(context.Background(), cfg), "host.cpu")
+	assert.False(ok)
+
+	// not plain chain
+	cfg.ClickHouse.ExtraPrefix = "prefix"
+	_, ok = h.treeSubquery(finder.New(context.Background(), cfg), "prefix.host.*.cpu")
+	assert.False(ok)
+	cfg.ClickHouse.ExtraPrefix = ""
+
+	// limits are set
+	cfg.Common.MaxMetricsInRenderAnswer = 100
+	_, ok = h.treeSubquery(finder.New(context.Background(), cfg), "host.*.cpu")
+	assert.False(ok)
+	cfg.Common.MaxMetricsInRenderAnswer = 0
+
+	// disabled
+	cfg.ClickHouse.TreeSubquery = false
+	_, ok = h.treeSubquery(finder.New(context.Background(), cfg), "host.*.cpu")
+	assert.False(ok)
+}
@@ -0,0 +1,105 @@
+package render
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/lomik/graphite-clickhouse/carbonapi_v3_pb"
+	"github.com/lomik/graphite-clickhouse/helper/limit"
+	"github.com/lomik/graphite-clickhouse/helper/log"
+)
+
+// ServeV3 handles carbonapi_v3_pb request: MultiFetchRequest in POST body or target, from, until arguments.
+// Every target is fetched with own time range
+func (h *Handler) ServeV3(w http.ResponseWriter, r *http.Request) {
+	logger := log.FromContext(r.Context())
+
+	var request carbonapi_v3_pb.MultiFetchRequest
+
+	if r.Method == http.MethodPost {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := proto.Unmarshal(body, &request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		fromTimestamp, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 32)
+		if err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		untilTimestamp, err := strconv.ParseInt(r.URL.Query().Get("until"), 10, 32)
+		if err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		for _, target := range r.URL.Query()["target"] {
+			request.Metrics = append(request.Metrics, carbonapi_v3_pb.FetchRequest{
+				Name:           target,
+				StartTime:      fromTimestamp,
+				StopTime:       untilTimestamp,
+				PathExpression: target,
+			})
+		}
+	}
+
+	var response carbonapi_v3_pb.MultiFetchResponse
+
+	for i := 0; i < len(request.Metrics); i++ {
+		req := &request.Metrics[i]
+
+		data, err := h.fetch(r.Context(), logger, req.Name, req.StartTime, req.StopTime)
+		if err != nil {
+			http.Error(w, err.Error(), limit.Status(err))
+			return
+		}
+
+		response.Metrics = append(response.Metrics, h.fetchResponsesV3(req, data)...)
+	}
+
+	body, err := proto.Marshal(&response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(body)
+}
+
+// fetchResponsesV3 converts sorted points to FetchResponse per metric. Absent values are NaN
+func (h *Handler) fetchResponsesV3(req *carbonapi_v3_pb.FetchRequest, data *Data) []carbonapi_v3_pb.FetchResponse {
+	pathExpression := req.PathExpression
+	if pathExpression == "" {
+		pathExpression = req.Name
+	}
+
+	var result []carbonapi_v3_pb.FetchResponse
+
+	for _, s := range h.Series(data, int32(req.StartTime), int32(req.StopTime)) {
+		pattern := h.config.Rollup.Match(s.Metric)
+		result = append(result, carbonapi_v3_pb.FetchResponse{
+			Name:              s.Name,
+			PathExpression:    pathExpression,
+			ConsolidationFunc: pattern.Function,
+			XFilesFactor:      pattern.XFilesFactor,
+			StartTime:         int64(s.Start),
+			StopTime:          int64(s.Stop),
+			StepTime:          int64(s.Step),
+			Values:            s.Values,
+			RequestStartTime:  req.StartTime,
+			RequestStopTime:   req.StopTime,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,94 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/carbonapi_v3_pb"
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/tests"
+)
+
+func TestServeV3(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(&tests.ClickHouse{
+		Index: []byte("a.b\na.c\n"),
+		Data:  append(tests.Points("a.b", [2]float64{1000, 0}), tests.Points("a.c", [2]float64{1001, 1})...),
+	})
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.Rollup = tests.Rollup(t, `
+<graphite_rollup>
+	<default>
+		<function>avg</function>
+		<xFilesFactor>0.5</xFilesFactor>
+		<retention>
+			<age>0</age>
+			<precision>10</precision>
+		</retention>
+	</default>
+</graphite_rollup>`)
+
+	h := NewHandler(cfg)
+
+	request := &carbonapi_v3_pb.MultiFetchRequest{
+		Metrics: []carbonapi_v3_pb.FetchRequest{
+			{Name: "a.*", StartTime: 1000, StopTime: 1010},
+		},
+	}
+	body, err := proto.Marshal(request)
+	assert.NoError(err)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "http://localhost/render/?format=carbonapi_v3_pb", bytes.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), "logger", zap.NewNop()))
+	h.ServeHTTP(w, r)
+
+	assert.Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal("application/x-protobuf", w.Header().Get("Content-Type"))
+
+	var response carbonapi_v3_pb.MultiFetchResponse
+	assert.NoError(proto.Unmarshal(w.Body.Bytes(), &response))
+
+	if !assert.Len(response.Metrics, 2) {
+		return
+	}
+
+	for i, name := range []string{"a.b", "a.c"} {
+		m := response.Metrics[i]
+		assert.Equal(name, m.Name)
+		assert.Equal("a.*", m.PathExpression)
+		assert.Equal("avg", m.ConsolidationFunc)
+		assert.Equal(float32(0.5), m.XFilesFactor)
+		assert.Equal(int64(1000), m.StartTime)
+		assert.Equal(int64(1010), m.StopTime)
+		assert.Equal(int64(10), m.StepTime)
+		assert.Equal(int64(1000), m.RequestStartTime)
+		assert.Equal(int64(1010), m.RequestStopTime)
+		if assert.Len(m.Values, 2) {
+			assert.Equal(float64(i), m.Values[0])
+			assert.True(math.IsNaN(m.Values[1]))
+		}
+	}
+
+	// GET request with arguments
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "http://localhost/render/?format=carbonapi_v3_pb&target=a.*&from=1000&until=1010", nil)
+	r = r.WithContext(context.WithValue(r.Context(), "logger", zap.NewNop()))
+	h.ServeHTTP(w, r)
+
+	response = carbonapi_v3_pb.MultiFetchResponse{}
+	assert.NoError(proto.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(response.Metrics, 2)
+}
@@ -0,0 +1,131 @@
+package tagger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lomik/graphite-clickhouse/config"
+)
+
+// Explanation of tags of one path
+type Explanation struct {
+	Metric *Metric `json:"metric"`
+	// Sources of each tag: rule as "file#index", "parent" for inherited from parent directory, "child" for copied from child
+	Sources map[string][]string `json:"sources"`
+}
+
+func (r *Rule) String() string {
+	return fmt.Sprintf("%s#%d", r.File, r.Index)
+}
+
+// Explain evaluates rules for paths as tagger does (with inheritance between parents and childs)
+func Explain(rules *Rules, paths []string) []Explanation {
+	metricList := make([]Metric, 0, len(paths))
+	requested := make(map[string]bool)
+
+	for _, p := range paths {
+		if p == "" || requested[p] {
+			continue
+		}
+		requested[p] = true
+		metricList = append(metricList, Metric{Path: []byte(p), Level: pathLevel([]byte(p))})
+	}
+
+	metricList = withParents(metricList)
+	sort.Sort(ByPath(metricList))
+
+	var maxLevel int
+	for i := 0; i < len(metricList); i++ {
+		if metricList[i].Level > maxLevel {
+			maxLevel = metricList[i].Level
+		}
+	}
+
+	linkParents(metricList, maxLevel)
+	matchTags(rules, metricList, 1)
+
+	// tags before copy from childs
+	matched := make([]*Set, len(metricList))
+	for i := 0; i < len(metricList); i++ {
+		matched[i] = metricList[i].Tags
+	}
+
+	copyToParents(metricList)
+
+	result := make([]Explanation, 0, len(requested))
+
+	for i := 0; i < len(metricList); i++ {
+		m := &metricList[i]
+		if !requested[unsafeString(m.Path)] {
+			continue
+		}
+
+		sources := make(map[string][]string)
+
+		// own rules. Each rule is checked separately on clean set
+		for j := 0; j < len(rules.Rule); j++ {
+			own := Metric{Path: m.Path, Level: m.Level, Tags: EmptySet}
+			rules.Rule[j].Match(&own)
+			for _, tag := range own.Tags.List() {
+				sources[tag] = append(sources[tag], rules.Rule[j].String())
+			}
+		}
+
+		var parentTags *Set
+		if m.ParentIndex >= 0 {
+			parentTags = matched[m.ParentIndex]
+		}
+
+		for _, tag := range m.Tags.List() {
+			if parentTags != nil && parentTags.data[tag] {
+				sources[tag] = append(sources[tag], "parent")
+			}
+			if !matched[i].data[tag] {
+				sources[tag] = append(sources[tag], "child")
+			}
+		}
+
+		result = append(result, Explanation{Metric: m, Sources: sources})
+	}
+
+	return result
+}
+
+// ExplainPaths loads rules from config and writes explanation of each path as JSON line.
+// Paths are read from file (one per line) if filename is not empty
+func ExplainPaths(cfg *config.Config, paths []string, filename string, w io.Writer) error {
+	rules, err := ParseGlob(cfg.Tags.Rules)
+	if err != nil {
+		return err
+	}
+
+	if filename != "" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			paths = append(paths, strings.TrimSpace(scanner.Text()))
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, e := range Explain(rules, paths) {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
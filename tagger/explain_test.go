@@ -0,0 +1,77 @@
+package tagger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/config"
+)
+
+func TestExplain(t *testing.T) {
+	assert := assert.New(t)
+
+	rules, err := Parse(`
+[[rule]]
+tag = "servers"
+has-prefix = "servers."
+
+[[rule]]
+tag = "cpu"
+has-suffix = ".cpu"
+`)
+	assert.NoError(err)
+
+	result := Explain(rules, []string{"servers.web1.cpu", "servers.web1."})
+	if !assert.Len(result, 2) {
+		return
+	}
+
+	// sorted by path
+	assert.Equal("servers.web1.", string(result[0].Metric.Path))
+	assert.Equal([]string{"#0", "parent"}, result[0].Sources["servers"])
+	assert.Equal([]string{"child"}, result[0].Sources["cpu"])
+
+	assert.Equal("servers.web1.cpu", string(result[1].Metric.Path))
+	assert.Equal([]string{"#0", "parent"}, result[1].Sources["servers"])
+	assert.Equal([]string{"#1"}, result[1].Sources["cpu"])
+	assert.Equal([]string{"servers", "cpu"}, result[1].Metric.Tags.List())
+}
+
+func TestExplainPaths(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "tagger")
+	if !assert.NoError(err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	assert.NoError(ioutil.WriteFile(filepath.Join(dir, "a.conf"), []byte("[[rule]]\ntag = \"a\"\nhas-prefix = \"a.\"\n"), 0644))
+	assert.NoError(ioutil.WriteFile(filepath.Join(dir, "b.conf"), []byte("[[rule]]\ntag = \"x\"\nequal = \"x\"\n\n[[rule]]\ntag = \"b\"\ncontains = \".b\"\n"), 0644))
+	assert.NoError(ioutil.WriteFile(filepath.Join(dir, "paths.txt"), []byte("a.b\n"), 0644))
+
+	cfg := config.New()
+	cfg.Tags.Rules = filepath.Join(dir, "*.conf")
+
+	buf := new(bytes.Buffer)
+	assert.NoError(ExplainPaths(cfg, nil, filepath.Join(dir, "paths.txt"), buf))
+
+	var e struct {
+		Metric struct {
+			Path string
+			Tags []string
+		} `json:"metric"`
+		Sources map[string][]string `json:"sources"`
+	}
+	assert.NoError(json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal("a.b", e.Metric.Path)
+	assert.Equal([]string{"a", "b"}, e.Metric.Tags)
+	assert.Equal([]string{filepath.Join(dir, "a.conf") + "#0", "parent"}, e.Sources["a"])
+	assert.Equal([]string{filepath.Join(dir, "b.conf") + "#1"}, e.Sources["b"])
+}
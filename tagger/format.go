@@ -0,0 +1,251 @@
+package tagger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+)
+
+// parseRowBinary reads paths from RowBinary bodies (String column). Paths are not copied
+func parseRowBinary(bodies [][]byte) ([]Metric, int, error) {
+	var count int
+
+	for i := 0; i < len(bodies); i++ {
+		c, err := countMetrics(bodies[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		count += c
+	}
+
+	metricList := make([]Metric, count)
+
+	index := 0
+
+	var maxLevel int
+
+	for i := 0; i < len(bodies); i++ {
+		body := bodies[i]
+		var namelen uint64
+		bodyLen := len(body)
+		var offset, readBytes int
+		var err error
+
+		for ; ; index++ {
+			if offset >= bodyLen {
+				if offset == bodyLen {
+					break
+				}
+				return nil, 0, clickhouse.ErrClickHouseResponse
+			}
+
+			namelen, readBytes, err = clickhouse.ReadUvarint(body[offset:])
+			if err != nil {
+				return nil, 0, err
+			}
+
+			metricList[index].Path = body[offset+readBytes : offset+readBytes+int(namelen)]
+			metricList[index].Level = pathLevel(metricList[index].Path)
+
+			if metricList[index].Level > maxLevel {
+				maxLevel = metricList[index].Level
+			}
+
+			offset += readBytes + int(namelen)
+		}
+	}
+
+	return metricList, maxLevel, nil
+}
+
+// unescapeTSV unescapes value of TabSeparated format
+func unescapeTSV(value []byte) []byte {
+	if bytes.IndexByte(value, '\\') < 0 {
+		return value
+	}
+
+	out := make([]byte, 0, len(value))
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			out = append(out, value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 't':
+			out = append(out, '\t')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case '0':
+			out = append(out, 0)
+		default:
+			out = append(out, value[i])
+		}
+	}
+	return out
+}
+
+// parseInput reads paths from input file. Text is path per line, tsv is Path in first column,
+// json is JSON lines with Path field. Other columns (Level, IsLeaf, Tags) are ignored
+func parseInput(body []byte, format string) ([]Metric, int, error) {
+	if format == config.FormatRowBinary {
+		return parseRowBinary([][]byte{body})
+	}
+
+	metricList := make([]Metric, 0, bytes.Count(body, []byte{'\n'})+1)
+	var maxLevel int
+
+	for n, line := range bytes.Split(body, []byte{'\n'}) {
+		line = bytes.TrimRight(line, "\r")
+		if len(line) == 0 {
+			continue
+		}
+
+		var path []byte
+
+		switch format {
+		case config.FormatText:
+			path = line
+		case config.FormatTSV:
+			if index := bytes.IndexByte(line, '\t'); index >= 0 {
+				line = line[:index]
+			}
+			path = unescapeTSV(line)
+		case config.FormatJSON:
+			var row struct {
+				Path string
+			}
+			if err := json.Unmarshal(line, &row); err != nil {
+				return nil, 0, fmt.Errorf("line %d: %s", n+1, err.Error())
+			}
+			path = []byte(row.Path)
+		default:
+			return nil, 0, fmt.Errorf("unknown input format %#v", format)
+		}
+
+		if len(path) == 0 {
+			continue
+		}
+
+		m := Metric{Path: path, Level: pathLevel(path)}
+		if m.Level > maxLevel {
+			maxLevel = m.Level
+		}
+		metricList = append(metricList, m)
+	}
+
+	return metricList, maxLevel, nil
+}
+
+// escapeTSV escapes value for TabSeparated format
+func escapeTSV(w *bufio.Writer, value []byte) {
+	for _, c := range value {
+		switch c {
+		case '\\':
+			w.WriteString(`\\`)
+		case '\t':
+			w.WriteString(`\t`)
+		case '\n':
+			w.WriteString(`\n`)
+		default:
+			w.WriteByte(c)
+		}
+	}
+}
+
+// writeTSVArray writes tags as ClickHouse Array(String) in TabSeparated: ['a','b']
+func writeTSVArray(w *bufio.Writer, list []string) {
+	w.WriteByte('[')
+	for i, s := range list {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteByte('\'')
+		for j := 0; j < len(s); j++ {
+			switch s[j] {
+			case '\'':
+				w.WriteString(`\'`)
+			case '\\':
+				w.WriteString(`\\`)
+			case '\t':
+				w.WriteString(`\t`)
+			case '\n':
+				w.WriteString(`\n`)
+			default:
+				w.WriteByte(s[j])
+			}
+		}
+		w.WriteByte('\'')
+	}
+	w.WriteByte(']')
+}
+
+// outputFile writes tagged paths by parts from pipeline
+type outputFile struct {
+	f      *os.File
+	w      *bufio.Writer
+	format string
+}
+
+func createOutputFile(filename string, format string) (*outputFile, error) {
+	if format != config.FormatTSV && format != config.FormatJSON {
+		return nil, fmt.Errorf("unknown output format %#v", format)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &outputFile{f: f, w: bufio.NewWriter(f), format: format}, nil
+}
+
+// write writes tagged metrics. Returns count of tagged paths
+func (o *outputFile) write(metricList []Metric) (int, error) {
+	var tagged int
+
+	for i := 0; i < len(metricList); i++ {
+		m := &metricList[i]
+
+		if m.Tags == nil || m.Tags.Len() == 0 {
+			continue
+		}
+		tagged++
+
+		if o.format == config.FormatTSV {
+			escapeTSV(o.w, m.Path)
+			o.w.WriteByte('\t')
+			o.w.WriteString(strconv.Itoa(m.Level))
+			o.w.WriteByte('\t')
+			o.w.WriteString(strconv.Itoa(int(m.IsLeaf())))
+			o.w.WriteByte('\t')
+			writeTSVArray(o.w, m.Tags.List())
+		} else {
+			b, err := m.MarshalJSON()
+			if err != nil {
+				return 0, err
+			}
+			o.w.Write(b)
+		}
+		o.w.WriteByte('\n')
+	}
+
+	return tagged, nil
+}
+
+// Close flushes buffer and closes file
+func (o *outputFile) Close() error {
+	if err := o.w.Flush(); err != nil {
+		o.f.Close()
+		return err
+	}
+	return o.f.Close()
+}
@@ -0,0 +1,80 @@
+package tagger
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/config"
+)
+
+func TestParseInput(t *testing.T) {
+	assert := assert.New(t)
+
+	table := []struct {
+		format string
+		body   string
+	}{
+		{config.FormatText, "a.b.c\n\na.\r\n"},
+		{config.FormatTSV, "a.b.c\t3\t1\t['x']\na.\t1\t0\t[]\n"},
+		{config.FormatJSON, "{\"Path\":\"a.b.c\",\"Level\":3}\n{\"Path\":\"a.\"}\n"},
+	}
+
+	for _, test := range table {
+		metricList, maxLevel, err := parseInput([]byte(test.body), test.format)
+		if !assert.NoError(err, test.format) {
+			continue
+		}
+		assert.Equal(3, maxLevel, test.format)
+		if assert.Len(metricList, 2, test.format) {
+			assert.Equal("a.b.c", string(metricList[0].Path), test.format)
+			assert.Equal(3, metricList[0].Level, test.format)
+			assert.Equal("a.", string(metricList[1].Path), test.format)
+			assert.Equal(1, metricList[1].Level, test.format)
+		}
+	}
+
+	metricList, _, err := parseInput([]byte("a\\tb\\\\c\t1\n"), config.FormatTSV)
+	assert.NoError(err)
+	assert.Equal("a\tb\\c", string(metricList[0].Path))
+
+	_, _, err = parseInput([]byte("{bad\n"), config.FormatJSON)
+	assert.Error(err)
+}
+
+func TestRunOutputFile(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &treeMock{paths: treePaths("a.b", "c")}
+	cfg, dir, cleanup := testRun(t, `
+[[rule]]
+tag = "t"
+has-prefix = "a."
+
+[[rule]]
+tag = "x'y"
+equal = "a.b"
+`, m)
+	defer cleanup()
+
+	cfg.Tags.OutputFile = filepath.Join(dir, "out")
+	cfg.Tags.OutputFormat = config.FormatTSV
+
+	// leaf is complete before its directory
+	stat, err := Run(cfg)
+	assert.NoError(err)
+	assert.Equal(2, stat.Tagged)
+	body, _ := ioutil.ReadFile(cfg.Tags.OutputFile)
+	assert.Equal("a.b\t2\t1\t['t','x\\'y']\na.\t1\t0\t['t','x\\'y']\n", string(body))
+
+	cfg.Tags.OutputFormat = config.FormatJSON
+	_, err = Run(cfg)
+	assert.NoError(err)
+	body, _ = ioutil.ReadFile(cfg.Tags.OutputFile)
+	assert.Equal("{\"IsLeaf\":1,\"Level\":2,\"Path\":\"a.b\",\"Tags\":[\"t\",\"x'y\"]}\n{\"IsLeaf\":0,\"Level\":1,\"Path\":\"a.\",\"Tags\":[\"t\",\"x'y\"]}\n", string(body))
+
+	// nothing is uploaded
+	assert.Empty(m.inserts)
+}
@@ -0,0 +1,303 @@
+package tagger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+	"github.com/lomik/zapwriter"
+)
+
+// readState returns checkpoint saved by last successful run. Zero if state file not exists
+func readState(filename string) (uint32, error) {
+	body, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := strconv.ParseUint(strings.TrimSpace(string(body)), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("malformed state file %#v: %s", filename, err.Error())
+	}
+
+	return uint32(v), nil
+}
+
+func writeState(filename string, checkpoint uint32) error {
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(fmt.Sprintf("%d\n", checkpoint)), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// readCheckpoint returns version of last full run from tag table (max version of marker record)
+// and checkpoint of last successful run. Checkpoint is read from state file, version of last full run is used if file not exists
+func readCheckpoint(cfg *config.Config) (tagVersion uint32, checkpoint uint32, err error) {
+	q, err := sqlb.NewSelect("Max(Version)").
+		From(cfg.ClickHouse.TagTable).
+		Where(
+			sqlb.Eq("Tag1", ""),
+			sqlb.Eq("Level", uint32(0)),
+			sqlb.Eq("Path", ""),
+		).
+		Format("TabSeparated").
+		Build()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	body, err := clickhouse.Query(
+		context.WithValue(context.Background(), "logger", zapwriter.Logger("tagger")),
+		cfg.ClickHouse.Url,
+		q,
+		cfg.ClickHouse.TreeTimeout.Value(),
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	v, err := strconv.ParseUint(strings.TrimSpace(string(body)), 10, 32)
+	if err != nil {
+		return 0, 0, clickhouse.ErrClickHouseResponse
+	}
+	tagVersion = uint32(v)
+
+	checkpoint, err = readState(cfg.Tags.StateFile)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if checkpoint == 0 {
+		checkpoint = tagVersion
+	}
+
+	return tagVersion, checkpoint, nil
+}
+
+// withParents adds all parent directories of paths which are not in list.
+// Parents are needed for tags inheritance and for copy tags from childs
+func withParents(metricList []Metric) []Metric {
+	exists := make(map[string]bool)
+	for i := 0; i < len(metricList); i++ {
+		exists[unsafeString(metricList[i].Path)] = true
+	}
+
+	count := len(metricList)
+	for i := 0; i < count; i++ {
+		path := metricList[i].Path
+		for len(path) > 0 {
+			index := bytes.LastIndexByte(path[:len(path)-1], '.')
+			if index < 0 {
+				break
+			}
+			path = path[:index+1]
+
+			if exists[unsafeString(path)] {
+				break
+			}
+			exists[unsafeString(path)] = true
+
+			metricList = append(metricList, Metric{
+				Path:  path,
+				Level: pathLevel(path),
+			})
+		}
+	}
+
+	return metricList
+}
+
+// readTags returns tags stored in version for paths. Paths are sent as external data table _paths,
+// so list of changed directories is not limited by max_query_size
+func readTags(cfg *config.Config, logger *zap.Logger, version uint32, paths [][]byte) (map[string][]string, error) {
+	result := make(map[string][]string)
+	if len(paths) == 0 {
+		return result, nil
+	}
+
+	pathTable := clickhouse.NewExternalTable("_paths", "Path String")
+	for _, p := range paths {
+		pathTable.Append(p)
+	}
+
+	q, err := sqlb.NewSelect("Path", "groupUniqArrayArray(Tags)").
+		From(cfg.ClickHouse.TagTable).
+		Where(
+			sqlb.Eq("Version", version),
+			sqlb.InTable("Path", "_paths"),
+		).
+		GroupBy("Path").
+		Format("RowBinary").
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := clickhouse.QueryWithExternalData(
+		context.WithValue(context.Background(), "logger", logger),
+		cfg.ClickHouse.Url,
+		q,
+		[]*clickhouse.ExternalTable{pathTable},
+		cfg.ClickHouse.TreeTimeout.Value(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = parseTagRows(body, func(path string, tags []string) {
+		result[path] = tags
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// parseTagRows reads RowBinary rows of (Path String, Tags Array(String))
+func parseTagRows(body []byte, callback func(path string, tags []string)) error {
+	var offset int
+
+	readString := func() (string, error) {
+		n, readBytes, err := clickhouse.ReadUvarint(body[offset:])
+		if err != nil {
+			return "", err
+		}
+		offset += readBytes
+		if offset+int(n) > len(body) {
+			return "", clickhouse.ErrClickHouseResponse
+		}
+		s := string(body[offset : offset+int(n)])
+		offset += int(n)
+		return s, nil
+	}
+
+	for offset < len(body) {
+		path, err := readString()
+		if err != nil {
+			return err
+		}
+
+		count, readBytes, err := clickhouse.ReadUvarint(body[offset:])
+		if err != nil {
+			return err
+		}
+		offset += readBytes
+
+		tags := make([]string, 0, count)
+		for i := uint64(0); i < count; i++ {
+			tag, err := readString()
+			if err != nil {
+				return err
+			}
+			tags = append(tags, tag)
+		}
+
+		callback(path, tags)
+	}
+
+	return nil
+}
+
+// directories returns paths of directories from list
+func directories(metricList []Metric) [][]byte {
+	var paths [][]byte
+	for i := 0; i < len(metricList); i++ {
+		if metricList[i].IsLeaf() == 0 {
+			paths = append(paths, metricList[i].Path)
+		}
+	}
+	return paths
+}
+
+// mergeCurrentTags adds stored tags to directories. Stored tags of directory contain tags copied from
+// unchanged childs, without them incremental row would replace complete tag set with partial one.
+// Tags of removed childs are kept until next full run
+func mergeCurrentTags(metricList []Metric, current map[string][]string) {
+	for i := 0; i < len(metricList); i++ {
+		m := &metricList[i]
+		if m.IsLeaf() != 0 {
+			continue
+		}
+		if tags, ok := current[unsafeString(m.Path)]; ok {
+			if m.Tags == nil {
+				m.Tags = EmptySet
+			}
+			m.Tags = m.Tags.Add(tags...)
+		}
+	}
+}
+
+// RemoveChunkSize is count of paths in one DELETE query. Paths are sent as query parameters
+const RemoveChunkSize = 1000
+
+// readRemoved returns paths deleted from tree since checkpoint
+func readRemoved(cfg *config.Config, logger *zap.Logger, versionCond sqlb.Cond) ([]Metric, error) {
+	q, err := sqlb.NewSelect("Path").
+		From(cfg.ClickHouse.TreeTable).
+		Where(versionCond).
+		GroupBy("Path").
+		Having(sqlb.Raw("argMax(Deleted, Version)==1")).
+		Format("RowBinary").
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := clickhouse.Query(
+		context.WithValue(context.Background(), "logger", logger),
+		cfg.ClickHouse.Url,
+		q,
+		cfg.ClickHouse.TreeTimeout.Value(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	metricList, _, err := parseRowBinary([][]byte{body})
+	return metricList, err
+}
+
+// removeTags deletes rows of removed paths from version of tag table
+func removeTags(cfg *config.Config, logger *zap.Logger, version uint32, metricList []Metric) error {
+	for offset := 0; offset < len(metricList); offset += RemoveChunkSize {
+		end := offset + RemoveChunkSize
+		if end > len(metricList) {
+			end = len(metricList)
+		}
+
+		paths := make([]string, 0, end-offset)
+		for i := offset; i < end; i++ {
+			paths = append(paths, string(metricList[i].Path))
+		}
+
+		q, err := sqlb.NewDelete(cfg.ClickHouse.TagTable).
+			Where(
+				sqlb.Eq("Version", version),
+				sqlb.In("Path", paths...),
+			).
+			Build()
+		if err != nil {
+			return err
+		}
+
+		if _, err = pruneQuery(cfg, logger, q); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package tagger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/RowBinary"
+	"github.com/lomik/graphite-clickhouse/helper/tests"
+)
+
+func TestWithParents(t *testing.T) {
+	assert := assert.New(t)
+
+	metricList := []Metric{
+		{Path: []byte("a.b.c"), Level: 3},
+		{Path: []byte("a.b.d"), Level: 3},
+		{Path: []byte("a.e."), Level: 2},
+		{Path: []byte("f"), Level: 1},
+	}
+
+	result := withParents(metricList)
+
+	paths := make([]string, 0)
+	for _, m := range result {
+		paths = append(paths, string(m.Path))
+		assert.Equal(pathLevel(m.Path), m.Level, string(m.Path))
+	}
+	sort.Strings(paths)
+
+	assert.Equal([]string{"a.", "a.b.", "a.b.c", "a.b.d", "a.e.", "f"}, paths)
+}
+
+func TestState(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "tagger")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "state")
+
+	v, err := readState(filename)
+	assert.NoError(err)
+	assert.Equal(uint32(0), v)
+
+	assert.NoError(writeState(filename, 1517313600))
+
+	v, err = readState(filename)
+	assert.NoError(err)
+	assert.Equal(uint32(1517313600), v)
+}
+
+func TestReadTags(t *testing.T) {
+	assert := assert.New(t)
+
+	var query, paths string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("query")
+		if file, _, err := r.FormFile("_paths"); err == nil {
+			body, _ := ioutil.ReadAll(file)
+			paths = string(body)
+		}
+
+		buf := new(bytes.Buffer)
+		e := RowBinary.NewEncoder(buf)
+		e.String("a.")
+		e.StringList([]string{"dc=x", "host=y"})
+		e.String("a.b.")
+		e.StringList([]string{})
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.ClickHouse.TagTable = "graphite_tag"
+
+	metricList := withParents([]Metric{{Path: []byte("a.b.c"), Level: 3}})
+	current, err := readTags(cfg, zap.NewNop(), 100, directories(metricList))
+	assert.NoError(err)
+
+	assert.Equal("SELECT Path, groupUniqArrayArray(Tags) FROM graphite_tag WHERE (Version = {p0:UInt32}) AND (Path IN _paths) GROUP BY Path FORMAT RowBinary", query)
+	assert.Equal("a.b.\na.\n", paths)
+	assert.Equal(map[string][]string{"a.": {"dc=x", "host=y"}, "a.b.": {}}, current)
+
+	for i := range metricList {
+		metricList[i].Tags = EmptySet.Add("new=1")
+	}
+	mergeCurrentTags(metricList, current)
+
+	tags := make(map[string][]string)
+	for _, m := range metricList {
+		tags[string(m.Path)] = m.Tags.List()
+	}
+	assert.Equal(map[string][]string{
+		"a.":    {"new=1", "dc=x", "host=y"},
+		"a.b.":  {"new=1"},
+		"a.b.c": {"new=1"},
+	}, tags)
+}
+
+func TestRunIncremental(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "tagger")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(ioutil.WriteFile(filepath.Join(dir, "rules.conf"), []byte(`
+[[rule]]
+tag = "new"
+has-suffix = ".new"
+`), 0644))
+
+	var lock sync.Mutex
+	var inserted []byte
+	var deleted []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("query")
+		if q == "" {
+			body, _ := ioutil.ReadAll(r.Body)
+			q = string(body)
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch {
+		case strings.HasPrefix(q, "SELECT Max(Version)"):
+			w.Write([]byte("100\n"))
+		case strings.Contains(q, "argMax(Deleted, Version)==0"):
+			w.Write(tests.Paths("a.b.new"))
+		case strings.Contains(q, "argMax(Deleted, Version)==1"):
+			w.Write(tests.Paths("a.b.old"))
+		case strings.Contains(q, "groupUniqArrayArray"):
+			buf := new(bytes.Buffer)
+			e := RowBinary.NewEncoder(buf)
+			e.String("a.b.")
+			e.StringList([]string{"old"})
+			w.Write(buf.Bytes())
+		case strings.HasPrefix(q, "INSERT"):
+			reader, err := gzip.NewReader(r.Body)
+			if err == nil {
+				body, _ := ioutil.ReadAll(reader)
+				inserted = append(inserted, body...)
+			}
+		case strings.HasPrefix(q, "DELETE"):
+			deleted = append(deleted, q, r.URL.Query().Get("param_p0"), r.URL.Query().Get("param_p1"))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.ClickHouse.TreeTable = "graphite_tree"
+	cfg.ClickHouse.TagTable = "graphite_tag"
+	cfg.Tags.Rules = filepath.Join(dir, "*.conf")
+	cfg.Tags.Incremental = true
+
+	// state file is required
+	_, err = Run(cfg)
+	assert.Error(err)
+
+	cfg.Tags.StateFile = filepath.Join(dir, "state")
+
+	// output to file does not move checkpoint and does not remove rows
+	cfg.Tags.OutputFile = filepath.Join(dir, "output")
+	stat, err := Run(cfg)
+	assert.NoError(err)
+	assert.Equal(0, stat.Removed)
+	_, err = os.Stat(cfg.Tags.StateFile)
+	assert.True(os.IsNotExist(err))
+	assert.Empty(deleted)
+
+	cfg.Tags.OutputFile = ""
+	stat, err = Run(cfg)
+	assert.NoError(err)
+	assert.Equal(1, stat.Removed)
+	assert.Equal([]string{"DELETE FROM graphite_tag WHERE (Version = {p0:UInt32}) AND (Path IN ({p1:String}))", "100", "a.b.old"}, deleted)
+
+	checkpoint, err := readState(cfg.Tags.StateFile)
+	assert.NoError(err)
+	assert.NotEqual(uint32(0), checkpoint)
+
+	// directory row has complete tag set: stored and new
+	assert.True(bytes.Contains(inserted, []byte("a.b.")))
+	var dirTags bytes.Buffer
+	RowBinary.NewEncoder(&dirTags).StringList([]string{"new", "old"})
+	assert.True(bytes.Contains(inserted, dirTags.Bytes()))
+}
@@ -0,0 +1,553 @@
+package tagger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/RowBinary"
+	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+)
+
+// ChunkSize is count of paths in one chunk of tagger pipeline
+const ChunkSize = 100000
+
+// source sends chunks of metrics sorted by path to out. It must return when stop is closed
+type source func(out chan<- []Metric, stop <-chan struct{}) error
+
+// readTreeSorted streams tree paths matched cond sorted by path and sends them by chunks of chunkSize paths
+func readTreeSorted(cfg *config.Config, logger *zap.Logger, cond sqlb.Cond, chunkSize int) source {
+	return func(out chan<- []Metric, stop <-chan struct{}) error {
+		q, err := sqlb.NewSelect("Path").
+			From(cfg.ClickHouse.TreeTable).
+			Where(cond).
+			GroupBy("Path").
+			Having(sqlb.Raw("argMax(Deleted, Version)==0")).
+			OrderBy("Path").
+			Format("RowBinary").
+			Build()
+		if err != nil {
+			return err
+		}
+
+		body, err := clickhouse.Reader(
+			context.WithValue(context.Background(), "logger", logger),
+			cfg.ClickHouse.Url,
+			q,
+			cfg.ClickHouse.TreeTimeout.Value(),
+		)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		r := bufio.NewReaderSize(body, 1024*1024)
+		chunk := make([]Metric, 0, chunkSize)
+
+		send := func() bool {
+			select {
+			case out <- chunk:
+				chunk = make([]Metric, 0, chunkSize)
+				return true
+			case <-stop:
+				return false
+			}
+		}
+
+		for {
+			namelen, err := binary.ReadUvarint(r)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			path := make([]byte, namelen)
+			if _, err = io.ReadFull(r, path); err != nil {
+				return clickhouse.ErrClickHouseResponse
+			}
+
+			chunk = append(chunk, Metric{Path: path, Level: pathLevel(path)})
+			if len(chunk) >= chunkSize && !send() {
+				return nil
+			}
+		}
+
+		if len(chunk) > 0 {
+			send()
+		}
+
+		return nil
+	}
+}
+
+// readTreeList reads whole tree sorted by path. For small selections, like changes of incremental run
+func readTreeList(cfg *config.Config, logger *zap.Logger, versionCond sqlb.Cond) ([]Metric, error) {
+	out := make(chan []Metric)
+	result := make(chan error, 1)
+
+	go func() {
+		result <- readTreeSorted(cfg, logger, versionCond, ChunkSize)(out, nil)
+		close(out)
+	}()
+
+	var metricList []Metric
+	for chunk := range out {
+		metricList = append(metricList, chunk...)
+	}
+
+	return metricList, <-result
+}
+
+// sliceSource sends sorted metricList by chunks of chunkSize
+func sliceSource(metricList []Metric, chunkSize int) source {
+	return func(out chan<- []Metric, stop <-chan struct{}) error {
+		for offset := 0; offset < len(metricList); offset += chunkSize {
+			end := offset + chunkSize
+			if end > len(metricList) {
+				end = len(metricList)
+			}
+			select {
+			case out <- metricList[offset:end]:
+			case <-stop:
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+// openDir is directory which subtree is not passed completely
+type openDir struct {
+	metric Metric
+	down   *Set // own and inherited tags, inherited by childs
+	linked bool // previous open directory is parent
+}
+
+// linker inherits tags from parents and copies tags of childs to parents over stream of paths sorted by path.
+// Paths of subtree are contiguous in sorted order, so only ancestors of last path are open and kept between chunks
+type linker struct {
+	open []openDir
+}
+
+// link processes chunk and returns complete metrics: leafs and directories with passed subtree.
+// Tags of metrics must contain own tags only
+func (l *linker) link(metricList []Metric) []Metric {
+	result := make([]Metric, 0, len(metricList))
+
+	for i := 0; i < len(metricList); i++ {
+		m := metricList[i]
+		m.ParentIndex = -1
+
+		// close directories which are not ancestors of path
+		for len(l.open) > 0 && !bytes.HasPrefix(m.Path, l.open[len(l.open)-1].metric.Path) {
+			result = append(result, l.open[len(l.open)-1].metric)
+			l.open = l.open[:len(l.open)-1]
+		}
+
+		linked := len(l.open) > 0 && bytes.Equal(l.open[len(l.open)-1].metric.Path, m.ParentPath())
+
+		if linked {
+			m.Tags = l.open[len(l.open)-1].down.Merge(m.Tags)
+
+			// copy to parents by chain of linked directories
+			for j := len(l.open) - 1; j >= 0; j-- {
+				d := &l.open[j]
+				d.metric.Tags = d.metric.Tags.Merge(m.Tags)
+				if !d.linked {
+					break
+				}
+			}
+		}
+
+		if m.IsLeaf() == 1 {
+			result = append(result, m)
+		} else {
+			l.open = append(l.open, openDir{metric: m, down: m.Tags, linked: linked})
+		}
+	}
+
+	return result
+}
+
+// close returns all open directories
+func (l *linker) close() []Metric {
+	result := make([]Metric, 0, len(l.open))
+	for i := len(l.open) - 1; i >= 0; i-- {
+		result = append(result, l.open[i].metric)
+	}
+	l.open = nil
+	return result
+}
+
+// matchChunk sets own tags of each path. Tags are empty without rules
+func matchChunk(rules *Rules, metricList []Metric) {
+	for i := 0; i < len(metricList); i++ {
+		m := &metricList[i]
+		m.Tags = EmptySet
+		if rules != nil {
+			rules.Match(m)
+		}
+	}
+}
+
+// runPipeline reads chunks from source, matches rules in workers goroutines (chunk per worker),
+// links chunks in source order and passes complete metrics to sink in separate goroutine.
+// Only chunks in stages and open directories are kept in memory. rules may be nil for paths without rule tags
+func runPipeline(src source, rules *Rules, workers int, sink func(metricList []Metric) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	type matchedChunk struct {
+		metrics []Metric
+		done    chan struct{}
+	}
+
+	stop := make(chan struct{})
+	chunks := make(chan []Metric)
+	work := make(chan *matchedChunk, workers)
+	ordered := make(chan *matchedChunk, workers)
+	linked := make(chan []Metric, 1)
+	sourceResult := make(chan error, 1)
+	sinkResult := make(chan error, 1)
+
+	// read
+	go func() {
+		sourceResult <- src(chunks, stop)
+		close(chunks)
+	}()
+
+	// dispatch to match workers keeping order for linker
+	go func() {
+		defer close(work)
+		defer close(ordered)
+		for metrics := range chunks {
+			c := &matchedChunk{metrics: metrics, done: make(chan struct{})}
+			select {
+			case ordered <- c:
+			case <-stop:
+				return
+			}
+			work <- c
+		}
+	}()
+
+	// match
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range work {
+				matchChunk(rules, c.metrics)
+				close(c.done)
+			}
+		}()
+	}
+
+	// encode and upload
+	go func() {
+		var err error
+		for metricList := range linked {
+			if err != nil {
+				continue
+			}
+			if err = sink(metricList); err != nil {
+				close(stop)
+			}
+		}
+		sinkResult <- err
+	}()
+
+	// link
+	l := &linker{}
+	for c := range ordered {
+		<-c.done
+		select {
+		case <-stop:
+			continue
+		default:
+		}
+		linked <- l.link(c.metrics)
+	}
+
+	wg.Wait()
+
+	err := <-sourceResult
+	if err == nil {
+		linked <- l.close()
+	}
+	close(linked)
+
+	if sinkErr := <-sinkResult; sinkErr != nil {
+		return sinkErr
+	}
+
+	return err
+}
+
+// matchTags matches rules of each path (without parents) in workers goroutines
+// and then inherits tags from parents in sorted order. metricList must be linked with linkParents
+func matchTags(rules *Rules, metricList []Metric, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	chunkSize := len(metricList)/(workers*4) + 1
+	chunks := make(chan int, len(metricList)/chunkSize+1)
+	for offset := 0; offset < len(metricList); offset += chunkSize {
+		chunks <- offset
+	}
+	close(chunks)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for offset := range chunks {
+				end := offset + chunkSize
+				if end > len(metricList) {
+					end = len(metricList)
+				}
+				for index := offset; index < end; index++ {
+					m := &metricList[index]
+					m.Tags = EmptySet
+					rules.Match(m)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// parents are before childs in sorted list
+	for index := 0; index < len(metricList); index++ {
+		m := &metricList[index]
+		if m.ParentIndex >= 0 {
+			m.Tags = metricList[m.ParentIndex].Tags.Merge(m.Tags)
+		}
+	}
+}
+
+// batchEncoder encodes rows to gzipped RowBinary. Batch is sent to out every batchSize rows
+type batchEncoder struct {
+	batchSize int
+	rows      int
+	buf       *bytes.Buffer
+	writer    *gzip.Writer
+	encoder   *RowBinary.Encoder
+	out       chan *bytes.Buffer
+	stop      chan struct{}
+}
+
+func newBatchEncoder(batchSize int, out chan *bytes.Buffer, stop chan struct{}) *batchEncoder {
+	e := &batchEncoder{
+		batchSize: batchSize,
+		out:       out,
+		stop:      stop,
+	}
+	e.reset()
+	return e
+}
+
+func (e *batchEncoder) reset() {
+	e.rows = 0
+	e.buf = new(bytes.Buffer)
+	e.writer = gzip.NewWriter(e.buf)
+	e.encoder = RowBinary.NewEncoder(e.writer)
+}
+
+// flush sends batch if it is not empty. Returns error if consumer is stopped
+func (e *batchEncoder) flush() error {
+	if e.rows == 0 {
+		return nil
+	}
+
+	if err := e.writer.Close(); err != nil {
+		return err
+	}
+
+	select {
+	case e.out <- e.buf:
+	case <-e.stop:
+		return fmt.Errorf("upload stopped")
+	}
+
+	e.reset()
+	return nil
+}
+
+// row is called after each written row
+func (e *batchEncoder) row() error {
+	e.rows++
+	if e.batchSize > 0 && e.rows >= e.batchSize {
+		return e.flush()
+	}
+	return nil
+}
+
+// tagsSink returns function which uploads one gzipped batch to clickhouse with insert query or appends it to output file.
+// Concatenated gzip batches are valid gzip file
+func tagsSink(cfg *config.Config, logger *zap.Logger, insert *sqlb.Insert) (func(body *bytes.Buffer) error, func() error, error) {
+	if cfg.Tags.OutputFile != "" {
+		f, err := os.Create(cfg.Tags.OutputFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(body *bytes.Buffer) error {
+			_, err := io.Copy(f, body)
+			return err
+		}, f.Close, nil
+	}
+
+	q, err := insert.Format("RowBinary").Build()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return func(body *bytes.Buffer) error {
+		_, err := clickhouse.PostGzip(
+			context.WithValue(context.Background(), "logger", logger),
+			cfg.ClickHouse.Url,
+			q,
+			body,
+			cfg.ClickHouse.TreeTimeout.Value(),
+		)
+		return err
+	}, func() error { return nil }, nil
+}
+
+// writeBatches runs encode and sends batches to sink. Next batch is encoded while previous is uploading
+func writeBatches(batchSize int, sink func(body *bytes.Buffer) error, encode func(e *batchEncoder) (int, error)) (int, error) {
+	batches := make(chan *bytes.Buffer, 1)
+	stop := make(chan struct{})
+	uploadResult := make(chan error, 1)
+
+	go func() {
+		var err error
+		for body := range batches {
+			if err != nil {
+				continue
+			}
+			if err = sink(body); err != nil {
+				close(stop)
+			}
+		}
+		uploadResult <- err
+	}()
+
+	count, err := encode(newBatchEncoder(batchSize, batches, stop))
+	close(batches)
+
+	if uploadErr := <-uploadResult; uploadErr != nil {
+		return 0, uploadErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// encodeRows encodes row for each tag of each tagged metric. Returns count of tagged paths
+func encodeRows(metricList []Metric, days uint16, version uint32, e *batchEncoder) (int, error) {
+	// INSERT INTO graphite_tag (Date,Version,Level,Path,IsLeaf,Tags,Tag1) FORMAT RowBinary
+	// with Content-Encoding: gzip
+	var tagged int
+
+	metricBuffer := new(bytes.Buffer)
+	metricEncoder := RowBinary.NewEncoder(metricBuffer)
+
+	for i := 0; i < len(metricList); i++ {
+		m := &metricList[i]
+
+		if m.Tags == nil || m.Tags.Len() == 0 {
+			continue
+		}
+
+		tagged++
+		metricBuffer.Reset()
+
+		// Date
+		err := metricEncoder.Uint16(days)
+		if err != nil {
+			return 0, err
+		}
+		// Version
+		err = metricEncoder.Uint32(version)
+		if err != nil {
+			return 0, err
+		}
+		// Level
+		err = metricEncoder.Uint32(uint32(m.Level))
+		if err != nil {
+			return 0, err
+		}
+		// Path
+		err = metricEncoder.Bytes(m.Path)
+		if err != nil {
+			return 0, err
+		}
+		// IsLeaf
+		err = metricEncoder.Uint8(m.IsLeaf())
+		if err != nil {
+			return 0, err
+		}
+		// Tags
+		err = metricEncoder.StringList(m.Tags.List())
+		if err != nil {
+			return 0, err
+		}
+
+		for _, tag := range m.Tags.List() {
+			_, err = e.writer.Write(metricBuffer.Bytes())
+			if err != nil {
+				return 0, err
+			}
+
+			// Tag1
+			err = e.encoder.String(tag)
+			if err != nil {
+				return 0, err
+			}
+
+			if err = e.row(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return tagged, nil
+}
+
+// finishTags sends last batch of rows and version marker in separate batch
+func finishTags(days uint16, version uint32, marker bool, e *batchEncoder) error {
+	if err := e.flush(); err != nil {
+		return err
+	}
+
+	// AND Empty record With Level=0, Path=0 and Without Tags
+	if marker {
+		if err := writeVersionMarker(e.encoder, days, version); err != nil {
+			return err
+		}
+		e.rows++
+		if err := e.flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package tagger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/helper/RowBinary"
+)
+
+func testMetricList(n int) []Metric {
+	paths := make([]Metric, 0)
+	for i := 0; i < n; i++ {
+		p := []byte(fmt.Sprintf("servers.host%d.cpu.user", i))
+		paths = append(paths, Metric{Path: p, Level: pathLevel(p)})
+	}
+	metricList := withParents(paths)
+	sort.Sort(ByPath(metricList))
+	linkParents(metricList, 4)
+	return metricList
+}
+
+func TestMatchTagsParallel(t *testing.T) {
+	assert := assert.New(t)
+
+	rules, err := Parse(`
+[[rule]]
+tag = "servers"
+has-prefix = "servers."
+
+[[rule]]
+regexp = "^servers\\.(host[0-9]*1)\\."
+tag = "host=$1"
+
+[[rule]]
+tag = "cpu"
+contains = ".cpu."
+`)
+	assert.NoError(err)
+
+	sequential := testMetricList(100)
+	matchTags(rules, sequential, 1)
+
+	parallel := testMetricList(100)
+	matchTags(rules, parallel, 8)
+
+	for i := 0; i < len(sequential); i++ {
+		assert.Equal(sequential[i].Tags.List(), parallel[i].Tags.List(), string(sequential[i].Path))
+	}
+}
+
+func TestRunBatches(t *testing.T) {
+	assert := assert.New(t)
+
+	var paths []string
+	for i := 0; i < 10; i++ {
+		paths = append(paths, fmt.Sprintf("servers.host%d.cpu.user", i))
+	}
+
+	m := &treeMock{paths: treePaths(paths...)}
+	cfg, _, cleanup := testRun(t, `
+[[rule]]
+tag = "servers"
+has-prefix = "servers."
+`, m)
+	defer cleanup()
+
+	cfg.Tags.BatchSize = 4
+
+	stat, err := Run(cfg)
+	assert.NoError(err)
+	// servers. + 10 hosts (dir, cpu. dir, user leaf)
+	assert.Equal(31, stat.Metrics)
+	assert.Equal(31, stat.Tagged)
+	// 31 rows by 4 and separate batch with version marker
+	if assert.Len(m.inserts, 9) {
+		marker := new(bytes.Buffer)
+		writeVersionMarker(RowBinary.NewEncoder(marker), RowBinary.DateToUint16(time.Now()), 0)
+		// Date and Version differ
+		assert.Len(m.inserts[8], marker.Len())
+	}
+
+	// upload error stops pipeline
+	m.inserts = nil
+	m.queries = nil
+	m.failInsert = true
+	cfg.Tags.BatchSize = 1
+
+	_, err = Run(cfg)
+	assert.Error(err)
+
+	var inserts int
+	for _, q := range m.queries {
+		if strings.HasPrefix(q, "INSERT") {
+			inserts++
+		}
+	}
+	assert.Equal(1, inserts)
+}
+
+func TestPipeline(t *testing.T) {
+	assert := assert.New(t)
+
+	rules, err := Parse(`
+[[rule]]
+tag = "servers"
+has-prefix = "servers."
+
+[[rule]]
+regexp = "^servers\\.(host[0-9]*1)\\."
+tag = "host=$1"
+
+[[rule]]
+tag = "user"
+has-suffix = ".user"
+`)
+	assert.NoError(err)
+
+	// in memory reference. Parents of orphan.x.y are missing
+	expected := append(testMetricList(50), Metric{Path: []byte("orphan.x.y"), Level: 3})
+	sort.Sort(ByPath(expected))
+	linkParents(expected, 4)
+	matchTags(rules, expected, 1)
+	copyToParents(expected)
+
+	input := append(testMetricList(50), Metric{Path: []byte("orphan.x.y"), Level: 3})
+	sort.Sort(ByPath(input))
+
+	var lock sync.Mutex
+	result := make(map[string][]string)
+
+	// small chunks, directories are carried between chunks
+	err = runPipeline(sliceSource(input, 7), rules, 4, func(metricList []Metric) error {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, m := range metricList {
+			result[string(m.Path)] = m.Tags.List()
+		}
+		return nil
+	})
+	assert.NoError(err)
+
+	assert.Len(result, len(expected))
+	for _, m := range expected {
+		assert.Equal(m.Tags.List(), result[string(m.Path)], string(m.Path))
+	}
+}
+
+func TestPipelineMemoryBounded(t *testing.T) {
+	assert := assert.New(t)
+
+	rules, err := Parse(`
+[[rule]]
+tag = "servers"
+has-prefix = "servers."
+`)
+	assert.NoError(err)
+
+	const chunkSize = 100
+	const chunks = 200
+	const workers = 4
+
+	var lock sync.Mutex
+	var live, maxLive, total int
+
+	// paths are generated chunk by chunk and counted as live until written
+	src := func(out chan<- []Metric, stop <-chan struct{}) error {
+		for c := 0; c < chunks; c++ {
+			chunk := make([]Metric, 0, chunkSize)
+			for i := 0; i < chunkSize; i++ {
+				p := []byte(fmt.Sprintf("servers.s%06d", c*chunkSize+i))
+				chunk = append(chunk, Metric{Path: p, Level: pathLevel(p)})
+			}
+
+			lock.Lock()
+			live += len(chunk)
+			if live > maxLive {
+				maxLive = live
+			}
+			lock.Unlock()
+
+			select {
+			case out <- chunk:
+			case <-stop:
+				return nil
+			}
+		}
+		return nil
+	}
+
+	err = runPipeline(src, rules, workers, func(metricList []Metric) error {
+		lock.Lock()
+		live -= len(metricList)
+		total += len(metricList)
+		lock.Unlock()
+		return nil
+	})
+	assert.NoError(err)
+
+	assert.Equal(chunks*chunkSize, total)
+	assert.Equal(0, live)
+	// chunks in read, dispatch, match, link and write stages
+	assert.True(maxLive <= (workers+5)*chunkSize, "max live metrics %d", maxLive)
+}
+
+func TestPipelineError(t *testing.T) {
+	assert := assert.New(t)
+
+	rules, err := Parse(`
+[[rule]]
+tag = "servers"
+has-prefix = "servers."
+`)
+	assert.NoError(err)
+
+	input := testMetricList(100)
+
+	// write error stops pipeline
+	calls := 0
+	err = runPipeline(sliceSource(input, 1), rules, 2, func(metricList []Metric) error {
+		calls++
+		return errors.New("clickhouse is down")
+	})
+	assert.EqualError(err, "clickhouse is down")
+	assert.Equal(1, calls)
+
+	// read error, open directories are not written
+	var written []string
+	err = runPipeline(func(out chan<- []Metric, stop <-chan struct{}) error {
+		out <- input[:10]
+		return errors.New("read failed")
+	}, rules, 2, func(metricList []Metric) error {
+		for _, m := range metricList {
+			written = append(written, string(m.Path))
+		}
+		return nil
+	})
+	assert.EqualError(err, "read failed")
+	sort.Strings(written)
+	assert.Equal([]string{
+		"servers.host0.", "servers.host0.cpu.", "servers.host0.cpu.user",
+		"servers.host1.", "servers.host1.cpu.", "servers.host1.cpu.user",
+		"servers.host10.cpu.user",
+	}, written)
+}
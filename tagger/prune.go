@@ -0,0 +1,200 @@
+package tagger
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+)
+
+type partitionVersion struct {
+	id         string
+	maxVersion uint32
+}
+
+// oldVersions returns versions to remove and minimal version to keep. Versions are sorted desc
+func oldVersions(versions []uint32, keep int) (uint32, []uint32) {
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	if keep < 1 || len(versions) <= keep {
+		return 0, nil
+	}
+
+	return versions[keep-1], versions[keep:]
+}
+
+// oldPartitions returns partitions with rows of removed versions only
+func oldPartitions(parts []partitionVersion, threshold uint32) []string {
+	var ids []string
+	for _, p := range parts {
+		if p.maxVersion < threshold {
+			ids = append(ids, p.id)
+		}
+	}
+	return ids
+}
+
+// parseTSV splits TabSeparated response into rows of columns
+func parseTSV(body []byte) [][]string {
+	var rows [][]string
+	for _, line := range strings.Split(string(body), "\n") {
+		if line == "" {
+			continue
+		}
+		rows = append(rows, strings.Split(line, "\t"))
+	}
+	return rows
+}
+
+func pruneQuery(cfg *config.Config, logger *zap.Logger, q *sqlb.Query) ([]byte, error) {
+	return clickhouse.Query(
+		context.WithValue(context.Background(), "logger", logger),
+		cfg.ClickHouse.Url,
+		q,
+		cfg.ClickHouse.TreeTimeout.Value(),
+	)
+}
+
+// readVersions returns versions of all full runs (versions of marker records)
+func readVersions(cfg *config.Config, logger *zap.Logger, table string, marker sqlb.Cond) ([]uint32, error) {
+	q, err := sqlb.NewSelect("Version").
+		From(table).
+		Where(marker).
+		GroupBy("Version").
+		Format("TabSeparated").
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := pruneQuery(cfg, logger, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []uint32
+	for _, row := range parseTSV(body) {
+		v, err := strconv.ParseUint(row[0], 10, 32)
+		if err != nil {
+			return nil, clickhouse.ErrClickHouseResponse
+		}
+		versions = append(versions, uint32(v))
+	}
+
+	return versions, nil
+}
+
+func readPartitions(cfg *config.Config, logger *zap.Logger, table string) ([]partitionVersion, error) {
+	q, err := sqlb.NewSelect("_partition_id", "max(Version)").
+		From(table).
+		GroupBy("_partition_id").
+		Format("TabSeparated").
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := pruneQuery(cfg, logger, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []partitionVersion
+	for _, row := range parseTSV(body) {
+		if len(row) != 2 {
+			return nil, clickhouse.ErrClickHouseResponse
+		}
+		v, err := strconv.ParseUint(row[1], 10, 32)
+		if err != nil {
+			return nil, clickhouse.ErrClickHouseResponse
+		}
+		parts = append(parts, partitionVersion{id: row[0], maxVersion: uint32(v)})
+	}
+
+	return parts, nil
+}
+
+// prune removes versions of tag table older than last tags.keep-versions. Returns removed versions (or versions to remove in dry-run)
+func prune(cfg *config.Config, logger *zap.Logger) ([]uint32, error) {
+	return pruneTable(cfg, logger, cfg.ClickHouse.TagTable, sqlb.And(
+		sqlb.Eq("Tag1", ""),
+		sqlb.Eq("Level", uint32(0)),
+		sqlb.Eq("Path", ""),
+	))
+}
+
+// pruneTable removes versions of table older than last tags.keep-versions. Versions of runs are versions of marker records
+func pruneTable(cfg *config.Config, logger *zap.Logger, table string, marker sqlb.Cond) ([]uint32, error) {
+	versions, err := readVersions(cfg, logger, table, marker)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold, removed := oldVersions(versions, cfg.Tags.KeepVersions)
+	if len(removed) == 0 {
+		logger.Info("nothing to prune", zap.Int("versions", len(versions)))
+		return nil, nil
+	}
+
+	var queries []*sqlb.Query
+
+	if cfg.Tags.PruneMethod == config.PrunePartition {
+		parts, err := readPartitions(cfg, logger, table)
+		if err != nil {
+			return nil, err
+		}
+
+		ids := oldPartitions(parts, threshold)
+		logger.Info("prune partitions",
+			zap.String("table", table),
+			zap.Uint32("keep_from_version", threshold),
+			zap.Strings("partitions", ids),
+			zap.Int("total_partitions", len(parts)),
+			zap.Bool("dry_run", cfg.Tags.PruneDryRun),
+		)
+
+		for _, id := range ids {
+			q, err := sqlb.DropPartition(table, id)
+			if err != nil {
+				return nil, err
+			}
+			queries = append(queries, q)
+		}
+	} else {
+		logger.Info("prune versions",
+			zap.String("table", table),
+			zap.Uint32("keep_from_version", threshold),
+			zap.Int("versions", len(removed)),
+			zap.Uint32("oldest", removed[len(removed)-1]),
+			zap.Uint32("newest", removed[0]),
+			zap.Bool("dry_run", cfg.Tags.PruneDryRun),
+		)
+
+		q, err := sqlb.NewDelete(table).Where(sqlb.Lt("Version", threshold)).Build()
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+
+	if cfg.Tags.PruneDryRun {
+		for _, q := range queries {
+			logger.Info("dry-run", zap.String("query", q.String()), zap.String("params", q.Params().Encode()))
+		}
+		return removed, nil
+	}
+
+	for _, q := range queries {
+		if _, err = pruneQuery(cfg, logger, q); err != nil {
+			return nil, err
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,84 @@
+package tagger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+)
+
+func TestOldVersions(t *testing.T) {
+	assert := assert.New(t)
+
+	threshold, removed := oldVersions([]uint32{10, 30, 20, 40}, 2)
+	assert.Equal(uint32(30), threshold)
+	assert.Equal([]uint32{20, 10}, removed)
+
+	_, removed = oldVersions([]uint32{10, 20}, 2)
+	assert.Nil(removed)
+
+	assert.Equal([]string{"a"}, oldPartitions([]partitionVersion{{"a", 20}, {"b", 30}, {"c", 40}}, 30))
+}
+
+func TestPrune(t *testing.T) {
+	assert := assert.New(t)
+
+	var lock sync.Mutex
+	var queries []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		q := string(body)
+
+		lock.Lock()
+		queries = append(queries, q)
+		lock.Unlock()
+
+		switch {
+		case strings.HasPrefix(q, "SELECT Version"):
+			w.Write([]byte("10\n20\n30\n"))
+		case strings.HasPrefix(q, "SELECT _partition_id"):
+			w.Write([]byte("20180101\t10\n20180102\t20\n20180103\t30\n"))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.ClickHouse.TagTable = "graphite_tag"
+	cfg.Tags.KeepVersions = 2
+
+	// delete
+	removed, err := prune(cfg, zap.NewNop())
+	assert.NoError(err)
+	assert.Equal([]uint32{10}, removed)
+	assert.Len(queries, 2)
+	assert.Equal("DELETE FROM graphite_tag WHERE (Version < {p0:UInt32})", queries[1])
+
+	// partition, dry-run
+	queries = nil
+	cfg.Tags.PruneMethod = config.PrunePartition
+	cfg.Tags.PruneDryRun = true
+	removed, err = prune(cfg, zap.NewNop())
+	assert.NoError(err)
+	assert.Equal([]uint32{10}, removed)
+	assert.Len(queries, 2)
+
+	// partition
+	queries = nil
+	cfg.Tags.PruneDryRun = false
+	_, err = prune(cfg, zap.NewNop())
+	assert.NoError(err)
+	assert.Equal([]string{
+		"SELECT Version FROM graphite_tag WHERE (Tag1 = {p0:String}) AND (Level = {p1:UInt32}) AND (Path = {p2:String}) GROUP BY Version FORMAT TabSeparated",
+		"SELECT _partition_id, max(Version) FROM graphite_tag GROUP BY _partition_id FORMAT TabSeparated",
+		"ALTER TABLE graphite_tag DROP PARTITION ID '20180101'",
+	}, queries)
+}
@@ -0,0 +1,146 @@
+package tagger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// RuleReport is count of leafs matched by rule
+type RuleReport struct {
+	Rule    string   `json:"rule"` // "file#index"
+	Tags    []string `json:"tags"`
+	Matched uint64   `json:"matched"`
+}
+
+// SetReport is path with large tag set
+type SetReport struct {
+	Path string   `json:"path"`
+	Tags []string `json:"tags"`
+}
+
+// Report of tagger run: coverage of metrics by rules and cardinality of tags
+type Report struct {
+	Leafs          int            `json:"leafs"`
+	UnmatchedLeafs int            `json:"unmatched_leafs"`
+	Rules          []RuleReport   `json:"rules"`
+	Tags           map[string]int `json:"tags"`      // count of leafs with tag
+	Unmatched      map[string]int `json:"unmatched"` // count of leafs without tags by first nodes of path
+	Largest        []SetReport    `json:"largest"`
+
+	depth int
+	top   int
+}
+
+// pathPrefix returns first depth nodes of path with trailing dot or path if it is shorter
+func pathPrefix(path []byte, depth int) []byte {
+	offset := 0
+	for i := 0; i < depth; i++ {
+		index := bytes.IndexByte(path[offset:], '.')
+		if index < 0 {
+			return path
+		}
+		offset += index + 1
+	}
+	return path[:offset]
+}
+
+func newReport(depth int, top int) *Report {
+	return &Report{
+		Tags:      make(map[string]int),
+		Unmatched: make(map[string]int),
+		Largest:   make([]SetReport, 0),
+		depth:     depth,
+		top:       top,
+	}
+}
+
+// add counts tagged metrics. Called for each part of metrics from pipeline
+func (r *Report) add(metricList []Metric) {
+	for i := 0; i < len(metricList); i++ {
+		m := &metricList[i]
+		if m.IsLeaf() == 0 {
+			continue
+		}
+		r.Leafs++
+
+		if m.Tags == nil || m.Tags.Len() == 0 {
+			r.UnmatchedLeafs++
+			r.Unmatched[string(pathPrefix(m.Path, r.depth))]++
+			continue
+		}
+
+		for _, tag := range m.Tags.List() {
+			r.Tags[tag]++
+		}
+
+		if r.top <= 0 {
+			continue
+		}
+		if len(r.Largest) < r.top || m.Tags.Len() > len(r.Largest[len(r.Largest)-1].Tags) {
+			r.Largest = append(r.Largest, SetReport{Path: string(m.Path), Tags: m.Tags.List()})
+			sort.SliceStable(r.Largest, func(i, j int) bool {
+				return len(r.Largest[i].Tags) > len(r.Largest[j].Tags)
+			})
+			if len(r.Largest) > r.top {
+				r.Largest = r.Largest[:r.top]
+			}
+		}
+	}
+}
+
+// finish adds match counters of rules
+func (r *Report) finish(rules *Rules) {
+	r.Rules = make([]RuleReport, 0, len(rules.Rule))
+
+	for i := 0; i < len(rules.Rule); i++ {
+		rule := &rules.Rule[i]
+		tags := rule.Tags.List()
+		if rule.templates != nil {
+			tags = append(append([]string{}, tags...), rule.templates...)
+		}
+		r.Rules = append(r.Rules, RuleReport{
+			Rule:    rule.String(),
+			Tags:    tags,
+			Matched: atomic.LoadUint64(&rule.matched),
+		})
+	}
+}
+
+// WriteFile writes report as JSON
+func (r *Report) WriteFile(filename string) error {
+	body, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, body, 0644)
+}
+
+// Log writes summary of report
+func (r *Report) Log(logger *zap.Logger) {
+	unused := make([]string, 0)
+	for _, rule := range r.Rules {
+		if rule.Matched == 0 {
+			unused = append(unused, rule.Rule)
+		}
+	}
+
+	var largest int
+	if len(r.Largest) > 0 {
+		largest = len(r.Largest[0].Tags)
+	}
+
+	logger.Info("report",
+		zap.Int("leafs", r.Leafs),
+		zap.Int("unmatched_leafs", r.UnmatchedLeafs),
+		zap.Int("unmatched_prefixes", len(r.Unmatched)),
+		zap.Int("tags", len(r.Tags)),
+		zap.Int("rules", len(r.Rules)),
+		zap.Strings("unused_rules", unused),
+		zap.Int("largest_set", largest),
+	)
+}
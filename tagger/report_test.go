@@ -0,0 +1,98 @@
+package tagger
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathPrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("a.", string(pathPrefix([]byte("a.b.c"), 1)))
+	assert.Equal("a.b.", string(pathPrefix([]byte("a.b.c"), 2)))
+	assert.Equal("a.b.c", string(pathPrefix([]byte("a.b.c"), 3)))
+	assert.Equal("a.b.", string(pathPrefix([]byte("a.b."), 3)))
+}
+
+func TestReport(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &treeMock{paths: treePaths("servers.a.cpu.user", "servers.a.mem", "other.x.y", "other.z", "top")}
+	cfg, dir, cleanup := testRun(t, `
+[[rule]]
+tag = "servers"
+has-prefix = "servers."
+
+[[rule]]
+tag = "cpu"
+contains = ".cpu."
+
+[[rule]]
+tag = "never"
+equal = "never"
+`, m)
+	defer cleanup()
+
+	cfg.Tags.ReportFile = filepath.Join(dir, "report.json")
+	cfg.Tags.ReportDepth = 1
+	cfg.Tags.ReportTop = 1
+
+	stat, err := Run(cfg)
+	if !assert.NoError(err) {
+		return
+	}
+	r := stat.Report
+
+	assert.Equal(5, r.Leafs)
+	assert.Equal(3, r.UnmatchedLeafs)
+	assert.Equal(map[string]int{"other.": 2, "top": 1}, r.Unmatched)
+	assert.Equal(map[string]int{"servers": 2, "cpu": 1}, r.Tags)
+
+	assert.Equal(filepath.Join(dir, "rules.conf")+"#0", r.Rules[0].Rule)
+	assert.True(r.Rules[0].Matched > 0)
+	assert.Equal(uint64(0), r.Rules[2].Matched)
+
+	assert.Equal([]SetReport{{Path: "servers.a.cpu.user", Tags: []string{"servers", "cpu"}}}, r.Largest)
+
+	body, err := ioutil.ReadFile(cfg.Tags.ReportFile)
+	assert.NoError(err)
+	assert.Contains(string(body), `"unmatched_leafs": 3`)
+}
+
+func TestReportMatchedOnce(t *testing.T) {
+	assert := assert.New(t)
+
+	// contains matches twice in each leaf, directories are not counted
+	m := &treeMock{paths: treePaths("a.cpu.b.cpu.user", "a.cpu.b.cpu.system")}
+	cfg, dir, cleanup := testRun(t, `
+[[rule]]
+tag = "cpu"
+contains = ".cpu."
+`, m)
+	defer cleanup()
+
+	cfg.Tags.ReportFile = filepath.Join(dir, "report.json")
+
+	stat, err := Run(cfg)
+	if assert.NoError(err) {
+		assert.Equal(uint64(2), stat.Report.Rules[0].Matched)
+	}
+}
+
+func TestReportTopZero(t *testing.T) {
+	assert := assert.New(t)
+
+	r := newReport(1, 0)
+	r.add([]Metric{
+		{Path: []byte("a.b"), Level: 2, Tags: EmptySet.Add("a")},
+		{Path: []byte("c"), Level: 1, Tags: EmptySet},
+	})
+
+	assert.Equal(2, r.Leafs)
+	assert.Equal(map[string]int{"c": 1}, r.Unmatched)
+	assert.Equal(map[string]int{"a": 1}, r.Tags)
+	assert.Empty(r.Largest)
+}
@@ -2,27 +2,105 @@ package tagger
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"sync/atomic"
 
 	"github.com/BurntSushi/toml"
 )
 
 type Rule struct {
-	Single         string         `toml:"tag"`
-	List           []string       `toml:"tags"`
-	re             *regexp.Regexp `toml:"-"`
-	Equal          string         `toml:"equal"`
-	HasPrefix      string         `toml:"has-prefix"`
-	HasSuffix      string         `toml:"has-suffix"`
-	Contains       string         `toml:"contains"`
-	Regexp         string         `toml:"regexp"`
-	BytesEqual     []byte         `toml:"-"`
-	BytesHasPrefix []byte         `toml:"-"`
-	BytesHasSuffix []byte         `toml:"-"`
-	BytesContains  []byte         `toml:"-"`
-	Tags           *Set           `toml:"-"`
+	Matcher
+	Single         string    `toml:"tag"`
+	List           []string  `toml:"tags"`
+	AnyOf          []Matcher `toml:"any-of"`
+	BytesEqual     []byte    `toml:"-"`
+	BytesHasPrefix []byte    `toml:"-"`
+	BytesHasSuffix []byte    `toml:"-"`
+	BytesContains  []byte    `toml:"-"`
+	Tags           *Set      `toml:"-"`
+	templates      []string  `toml:"-"` // tags with $1, ${name} expanded from regexp submatches
+	File           string    `toml:"-"` // source file of rule, empty for Parse
+	Index          int       `toml:"-"` // index of rule in file
+	matched        uint64    `toml:"-"` // count of matched leafs, for report
+}
+
+// Matcher is set of conditions on path. Path matches if all of non-empty conditions are true
+type Matcher struct {
+	Equal        string         `toml:"equal"`
+	HasPrefix    string         `toml:"has-prefix"`
+	HasSuffix    string         `toml:"has-suffix"`
+	Contains     string         `toml:"contains"`
+	Regexp       string         `toml:"regexp"`
+	NotEqual     string         `toml:"not-equal"`
+	NotHasPrefix string         `toml:"not-has-prefix"`
+	NotHasSuffix string         `toml:"not-has-suffix"`
+	NotContains  string         `toml:"not-contains"`
+	NotRegexp    string         `toml:"not-regexp"`
+	re           *regexp.Regexp `toml:"-"`
+	notRe        *regexp.Regexp `toml:"-"`
+}
+
+func (m *Matcher) compile() error {
+	var err error
+	if m.Regexp != "" {
+		if m.re, err = regexp.Compile(m.Regexp); err != nil {
+			return err
+		}
+	}
+	if m.NotRegexp != "" {
+		if m.notRe, err = regexp.Compile(m.NotRegexp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match checks all conditions. Empty matcher matches any path
+func (m *Matcher) Match(path []byte) bool {
+	s := unsafeString(path)
+
+	if m.Equal != "" && s != m.Equal {
+		return false
+	}
+	if m.HasPrefix != "" && !strings.HasPrefix(s, m.HasPrefix) {
+		return false
+	}
+	if m.HasSuffix != "" && !strings.HasSuffix(s, m.HasSuffix) {
+		return false
+	}
+	if m.Contains != "" && !strings.Contains(s, m.Contains) {
+		return false
+	}
+	if m.re != nil && !m.re.Match(path) {
+		return false
+	}
+	return m.matchNot(path)
+}
+
+// matchNot checks negative conditions only
+func (m *Matcher) matchNot(path []byte) bool {
+	s := unsafeString(path)
+
+	if m.NotEqual != "" && s == m.NotEqual {
+		return false
+	}
+	if m.NotHasPrefix != "" && strings.HasPrefix(s, m.NotHasPrefix) {
+		return false
+	}
+	if m.NotHasSuffix != "" && strings.HasSuffix(s, m.NotHasSuffix) {
+		return false
+	}
+	if m.NotContains != "" && strings.Contains(s, m.NotContains) {
+		return false
+	}
+	if m.notRe != nil && m.notRe.Match(path) {
+		return false
+	}
+	return true
 }
 
 type Rules struct {
@@ -42,57 +120,102 @@ func ParseFile(filename string) (*Rules, error) {
 	return Parse(string(c))
 }
 
+// ParseGlob parses all files matched by glob. File name and index in file are saved in each rule
 func ParseGlob(glob string) (*Rules, error) {
-	content := []byte{}
-
 	files, err := filepath.Glob(glob)
 	if err != nil {
 		return nil, err
 	}
 
+	rules := &Rules{}
+
 	for i := 0; i < len(files); i++ {
 		c, err := ioutil.ReadFile(files[i])
 		if err != nil {
 			return nil, err
 		}
 
-		content = append(append(content, '\n'), c...)
+		fileRules := &Rules{}
+		if _, err := toml.Decode(string(c), fileRules); err != nil {
+			return nil, fmt.Errorf("%s: %s", files[i], err.Error())
+		}
+
+		for j := 0; j < len(fileRules.Rule); j++ {
+			fileRules.Rule[j].File = files[i]
+			fileRules.Rule[j].Index = j
+		}
+
+		rules.Rule = append(rules.Rule, fileRules.Rule...)
+	}
+
+	if err := rules.compile(); err != nil {
+		return nil, err
 	}
 
-	return Parse(string(content))
+	return rules, nil
 }
 
 func Parse(content string) (*Rules, error) {
-	rules := &Rules{
-		prefix:   &Tree{},
-		suffix:   &Tree{},
-		contains: &Tree{},
-		other:    make([]*Rule, 0),
-	}
+	rules := &Rules{}
 
 	if _, err := toml.Decode(content, rules); err != nil {
 		return nil, err
 	}
 
+	for i := 0; i < len(rules.Rule); i++ {
+		rules.Rule[i].Index = i
+	}
+
+	if err := rules.compile(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// compile prepares rules and builds match trees
+func (rules *Rules) compile() error {
+	rules.prefix = &Tree{}
+	rules.suffix = &Tree{}
+	rules.contains = &Tree{}
+	rules.other = make([]*Rule, 0)
+
 	var err error
 
 	for i := 0; i < len(rules.Rule); i++ {
 		rule := &rules.Rule[i]
 		rule.Tags = EmptySet
 
+		tags := rule.List
 		if rule.Single != "" {
-			rule.Tags = rule.Tags.Add(rule.Single)
+			tags = append([]string{rule.Single}, tags...)
 		}
 
-		if rule.List != nil {
-			rule.Tags = rule.Tags.Add(rule.List...)
+		for _, tag := range tags {
+			if rule.Regexp != "" && strings.Contains(tag, "$") {
+				rule.templates = append(rule.templates, tag)
+			} else {
+				rule.Tags = rule.Tags.Add(tag)
+			}
 		}
 
-		// compile and check regexp
-		rule.re, err = regexp.Compile(rule.Regexp)
-		if err != nil {
-			return nil, err
+		// compile and check regexps
+		if err = rule.Matcher.compile(); err != nil {
+			return err
+		}
+		if rule.templates != nil {
+			if rule.re.NumSubexp() == 0 {
+				return fmt.Errorf("tags %#v use submatches but regexp %#v has no groups", rule.templates, rule.Regexp)
+			}
 		}
+
+		// negative conditions and any-of are checked after positive part
+		for j := 0; j < len(rule.AnyOf); j++ {
+			if err = rule.AnyOf[j].compile(); err != nil {
+				return err
+			}
+		}
+
 		if rule.Equal != "" {
 			rule.BytesEqual = []byte(rule.Equal)
 		}
@@ -119,17 +242,39 @@ func Parse(content string) (*Rules, error) {
 		}
 	}
 
-	return rules, nil
+	return nil
 }
 
+// Match adds tags of all matched rules to metric. Each leaf is counted once in report counter of matched rule
 func (r *Rules) Match(m *Metric) {
-	r.matchPrefix(m)
-	r.matchSuffix(m)
-	r.matchContains(m)
-	r.matchOther(m)
+	var buf [8]*Rule
+	matched := buf[:0]
+
+	matched = r.matchPrefix(m, matched)
+	matched = r.matchSuffix(m, matched)
+	matched = r.matchContains(m, matched)
+	matched = r.matchOther(m, matched)
+
+	if m.IsLeaf() == 0 {
+		return
+	}
+
+	// contains rule can match same path at several positions
+	for i, rule := range matched {
+		counted := false
+		for j := 0; j < i; j++ {
+			if matched[j] == rule {
+				counted = true
+				break
+			}
+		}
+		if !counted {
+			atomic.AddUint64(&rule.matched, 1)
+		}
+	}
 }
 
-func matchByPrefix(path []byte, tree *Tree, m *Metric) {
+func matchByPrefix(path []byte, tree *Tree, m *Metric, matched []*Rule) []*Rule {
 	x := tree
 	i := 0
 	for {
@@ -144,15 +289,19 @@ func matchByPrefix(path []byte, tree *Tree, m *Metric) {
 
 		if x.Rules != nil {
 			for _, rule := range x.Rules {
-				rule.Match(m)
+				if rule.Match(m) {
+					matched = append(matched, rule)
+				}
 			}
 		}
 
 		i++
 	}
+
+	return matched
 }
 
-func matchBySuffix(path []byte, tree *Tree, m *Metric) {
+func matchBySuffix(path []byte, tree *Tree, m *Metric, matched []*Rule) []*Rule {
 	x := tree
 	i := len(path) - 1
 	for {
@@ -167,54 +316,107 @@ func matchBySuffix(path []byte, tree *Tree, m *Metric) {
 
 		if x.Rules != nil {
 			for _, rule := range x.Rules {
-				rule.Match(m)
+				if rule.Match(m) {
+					matched = append(matched, rule)
+				}
 			}
 		}
 
 		i--
 	}
+
+	return matched
 }
 
-func (r *Rules) matchPrefix(m *Metric) {
-	matchByPrefix(m.Path, r.prefix, m)
+func (r *Rules) matchPrefix(m *Metric, matched []*Rule) []*Rule {
+	return matchByPrefix(m.Path, r.prefix, m, matched)
 }
 
-func (r *Rules) matchSuffix(m *Metric) {
-	matchBySuffix(m.Path, r.suffix, m)
+func (r *Rules) matchSuffix(m *Metric, matched []*Rule) []*Rule {
+	return matchBySuffix(m.Path, r.suffix, m, matched)
 }
 
-func (r *Rules) matchContains(m *Metric) {
+func (r *Rules) matchContains(m *Metric, matched []*Rule) []*Rule {
 	for i := 0; i < len(m.Path); i++ {
-		matchByPrefix(m.Path[i:], r.contains, m)
+		matched = matchByPrefix(m.Path[i:], r.contains, m, matched)
 	}
+	return matched
 }
 
-func (r *Rules) matchOther(m *Metric) {
+func (r *Rules) matchOther(m *Metric, matched []*Rule) []*Rule {
 	for _, rule := range r.other {
-		rule.Match(m)
+		if rule.Match(m) {
+			matched = append(matched, rule)
+		}
 	}
+	return matched
 }
 
-func (r *Rule) Match(m *Metric) {
+// Match adds tags of rule to metric. Returns true if path is matched
+func (r *Rule) Match(m *Metric) bool {
 	if r.BytesEqual != nil && !bytes.Equal(m.Path, r.BytesEqual) {
-		return
+		return false
 	}
 
 	if r.BytesHasPrefix != nil && !bytes.HasPrefix(m.Path, r.BytesHasPrefix) {
-		return
+		return false
 	}
 
 	if r.BytesHasSuffix != nil && !bytes.HasSuffix(m.Path, r.BytesHasSuffix) {
-		return
+		return false
 	}
 
 	if r.BytesContains != nil && !bytes.Contains(m.Path, r.BytesContains) {
-		return
+		return false
+	}
+
+	if !r.matchNot(m.Path) {
+		return false
+	}
+
+	if len(r.AnyOf) > 0 && !r.matchAnyOf(m.Path) {
+		return false
+	}
+
+	if r.templates != nil {
+		return r.matchTemplates(m)
 	}
 
 	if r.re != nil && !r.re.Match(m.Path) {
-		return
+		return false
 	}
 
 	m.Tags = m.Tags.Merge(r.Tags)
+	return true
+}
+
+func (r *Rule) matchAnyOf(path []byte) bool {
+	for i := 0; i < len(r.AnyOf); i++ {
+		if r.AnyOf[i].Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTemplates adds static tags and tags expanded from regexp submatches.
+// Tags with empty expansion (e.g. "host=" for unmatched optional group) are skipped
+func (r *Rule) matchTemplates(m *Metric) bool {
+	match := r.re.FindSubmatchIndex(m.Path)
+	if match == nil {
+		return false
+	}
+
+	tags := make([]string, 0, len(r.templates))
+	for _, template := range r.templates {
+		tag := r.re.Expand(nil, []byte(template), m.Path, match)
+		if len(tag) == 0 || tag[len(tag)-1] == '=' {
+			continue
+		}
+		tags = append(tags, string(tag))
+	}
+
+	// static tags are shared, set with expanded tags is made once per metric
+	m.Tags = m.Tags.Merge(r.Tags).Add(tags...)
+	return true
 }
@@ -77,13 +77,13 @@ func TestRules(t *testing.T) {
 		case "":
 			rules.Match(&m)
 		case "prefix":
-			rules.matchPrefix(&m)
+			rules.matchPrefix(&m, nil)
 		case "suffix":
-			rules.matchSuffix(&m)
+			rules.matchSuffix(&m, nil)
 		case "contains":
-			rules.matchContains(&m)
+			rules.matchContains(&m, nil)
 		case "other":
-			rules.matchOther(&m)
+			rules.matchOther(&m, nil)
 		}
 
 		expected := t.expectedTags
@@ -97,3 +97,110 @@ func TestRules(t *testing.T) {
 		assert.Equal(expected, tags, fmt.Sprintf("path: %s, method: %s", t.path, t.method))
 	}
 }
+
+func TestRulesSubmatch(t *testing.T) {
+	assert := assert.New(t)
+
+	rules, err := Parse(`
+[[rule]]
+has-prefix = "servers."
+regexp = "^servers\\.([^.]+)\\.(?P<dc>[^.]+)(\\.(cpu))?"
+tags = ["servers", "host=$1", "dc=${dc}", "metric=$4"]
+`)
+	assert.NoError(err)
+
+	table := []struct {
+		path         string
+		expectedTags []string
+	}{
+		{"servers.web1.dc1.cpu.user", []string{"servers", "host=web1", "dc=dc1", "metric=cpu"}},
+		{"servers.web2.dc1.mem", []string{"servers", "host=web2", "dc=dc1"}},
+		{"servers.web2.", nil},
+		{"other.web1.dc1.cpu", nil},
+	}
+
+	for _, test := range table {
+		m := Metric{Path: []byte(test.path), Tags: EmptySet}
+		rules.Match(&m)
+
+		expected := test.expectedTags
+		if expected == nil {
+			expected = []string{}
+		}
+		sort.Strings(expected)
+		tags := append([]string{}, m.Tags.List()...)
+		sort.Strings(tags)
+
+		assert.Equal(expected, tags, test.path)
+	}
+
+	// expanded tags are not added to static tags of rule
+	assert.Equal([]string{"servers"}, rules.Rule[0].Tags.List())
+
+	_, err = Parse(`
+[[rule]]
+regexp = "^servers\\."
+tag = "host=$1"
+`)
+	assert.Error(err)
+}
+
+func TestRulesNegative(t *testing.T) {
+	assert := assert.New(t)
+
+	rules, err := Parse(`
+[[rule]]
+tag = "prod"
+has-prefix = "prefix."
+not-contains = ".test."
+
+[[rule]]
+tag = "count"
+has-suffix = ".count"
+not-has-prefix = "servers."
+
+[[rule]]
+tag = "not-regexp"
+not-regexp = "^(prefix|servers)\\."
+not-equal = "skip"
+
+[[rule]]
+tag = "any"
+any-of = [{has-prefix = "servers.", contains = ".cpu."}, {equal = "prefix.x.count"}]
+`)
+	assert.NoError(err)
+
+	table := []struct {
+		path         string
+		expectedTags []string
+	}{
+		{"prefix.x.count", []string{"prod", "count", "any"}},
+		{"prefix.test.count", []string{"count"}},
+		{"servers.a.count", nil},
+		{"servers.a.cpu.count", []string{"any"}},
+		{"other.count", []string{"count", "not-regexp"}},
+		{"skip", nil},
+	}
+
+	for _, test := range table {
+		m := Metric{Path: []byte(test.path), Tags: EmptySet}
+		rules.Match(&m)
+
+		expected := test.expectedTags
+		if expected == nil {
+			expected = []string{}
+		}
+		sort.Strings(expected)
+		tags := append([]string{}, m.Tags.List()...)
+		sort.Strings(tags)
+
+		assert.Equal(expected, tags, test.path)
+	}
+
+	_, err = Parse(`
+[[rule]]
+tag = "bad"
+any-of = [{regexp = "("}]
+`)
+	assert.Error(err)
+}
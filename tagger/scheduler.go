@@ -0,0 +1,127 @@
+package tagger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/zapwriter"
+)
+
+// Status of scheduled tagger
+type Status struct {
+	Running  bool      `json:"running"`
+	Runs     int       `json:"runs"`
+	Skipped  int       `json:"skipped"`
+	LastRun  time.Time `json:"last_run"`
+	Duration string    `json:"duration"`
+	Metrics  int       `json:"metrics"`
+	Tagged   int       `json:"tagged"`
+	Error    string    `json:"error"`
+}
+
+// Scheduler runs tagger in background with interval from config. Run is skipped if previous is not finished
+type Scheduler struct {
+	config *config.Config
+	run    func(cfg *config.Config) (*Stat, error)
+
+	sync.Mutex
+	status Status
+}
+
+// NewScheduler ...
+func NewScheduler(cfg *config.Config) *Scheduler {
+	return &Scheduler{
+		config: cfg,
+		run:    Run,
+	}
+}
+
+// Start runs tagger every tags.interval in background goroutine. First run is after one interval
+func (s *Scheduler) Start() {
+	interval := s.config.Tags.Interval.Value()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			go s.Tick()
+		}
+	}()
+}
+
+// Tick runs tagger if it is not running now
+func (s *Scheduler) Tick() {
+	logger := zapwriter.Logger("tagger")
+
+	s.Lock()
+	if s.status.Running {
+		s.status.Skipped++
+		s.Unlock()
+		logger.Warn("previous run is not finished, skip")
+		return
+	}
+	s.status.Running = true
+	s.Unlock()
+
+	start := time.Now()
+	var stat *Stat
+	var err error
+
+	// Running is reset even if tagger panics, otherwise all next runs are skipped
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			logger.Error("scheduled run panic", zap.Any("panic", r), zap.Stack("stack"))
+		}
+		s.finish(logger, start, stat, err)
+	}()
+
+	stat, err = s.run(s.config)
+}
+
+// finish saves result of run to status
+func (s *Scheduler) finish(logger *zap.Logger, start time.Time, stat *Stat, err error) {
+	d := time.Since(start)
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.status.Running = false
+	s.status.Runs++
+	s.status.LastRun = start
+	s.status.Duration = d.String()
+
+	if err != nil {
+		s.status.Error = err.Error()
+		logger.Error("scheduled run failed", zap.Error(err), zap.Duration("time", d))
+		return
+	}
+
+	s.status.Error = ""
+	s.status.Metrics = stat.Metrics
+	s.status.Tagged = stat.Tagged
+	logger.Info("scheduled run finished", zap.Int("tagged", stat.Tagged), zap.Duration("time", d))
+}
+
+// Status returns copy of current status
+func (s *Scheduler) Status() Status {
+	s.Lock()
+	defer s.Unlock()
+	return s.status
+}
+
+func (s *Scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := json.Marshal(s.Status())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
@@ -0,0 +1,96 @@
+package tagger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/config"
+)
+
+func TestSchedulerSkipOverlapping(t *testing.T) {
+	assert := assert.New(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	s := NewScheduler(config.New())
+	s.run = func(cfg *config.Config) (*Stat, error) {
+		close(started)
+		<-release
+		return &Stat{Metrics: 10, Tagged: 3}, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.Tick()
+		close(done)
+	}()
+
+	<-started
+	assert.True(s.Status().Running)
+
+	// second tick while first is running
+	s.Tick()
+	assert.Equal(1, s.Status().Skipped)
+
+	close(release)
+	<-done
+
+	status := s.Status()
+	assert.False(status.Running)
+	assert.Equal(1, status.Runs)
+	assert.Equal(10, status.Metrics)
+	assert.Equal(3, status.Tagged)
+	assert.Equal("", status.Error)
+	assert.False(status.LastRun.IsZero())
+}
+
+func TestSchedulerError(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewScheduler(config.New())
+	s.run = func(cfg *config.Config) (*Stat, error) {
+		return nil, errors.New("clickhouse is down")
+	}
+	s.Tick()
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/admin/tagger/", nil))
+	assert.Equal(http.StatusOK, w.Code)
+
+	var status Status
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(1, status.Runs)
+	assert.Equal("clickhouse is down", status.Error)
+}
+
+func TestSchedulerPanic(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewScheduler(config.New())
+	s.run = func(cfg *config.Config) (*Stat, error) {
+		panic("index out of range")
+	}
+	s.Tick()
+
+	status := s.Status()
+	assert.False(status.Running)
+	assert.Equal(1, status.Runs)
+	assert.Equal("panic: index out of range", status.Error)
+
+	// next run is not skipped
+	s.run = func(cfg *config.Config) (*Stat, error) {
+		return &Stat{Tagged: 1}, nil
+	}
+	s.Tick()
+
+	status = s.Status()
+	assert.Equal(2, status.Runs)
+	assert.Equal(0, status.Skipped)
+	assert.Equal("", status.Error)
+}
@@ -49,7 +49,11 @@ func (s *Set) Add(tag ...string) *Set {
 	return n
 }
 
+// Merge returns union of sets. One of sets is returned as is if it contains other
 func (s *Set) Merge(other *Set) *Set {
+	if len(s.list) == 0 {
+		return other
+	}
 	return s.Add(other.list...)
 }
 
@@ -0,0 +1,198 @@
+package tagger
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/RowBinary"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
+	"github.com/lomik/zapwriter"
+)
+
+// ParseTaggedName parses Graphite 1.1 tagged series name "name;tag1=v1;tag2=v2".
+// Returns sorted tags "tag=value" with name as "__name__=name". ok is false for plain or malformed name
+func ParseTaggedName(path string) (tags []string, ok bool) {
+	parts := strings.Split(path, ";")
+	if len(parts) < 2 || parts[0] == "" {
+		return nil, false
+	}
+
+	tags = make([]string, 0, len(parts))
+	tags = append(tags, "__name__="+parts[0])
+
+	for _, p := range parts[1:] {
+		eq := strings.IndexByte(p, '=')
+		if eq <= 0 || eq == len(p)-1 {
+			return nil, false
+		}
+		tags = append(tags, p)
+	}
+
+	sort.Strings(tags)
+	return tags, true
+}
+
+// encodeTaggedRows writes row per tag of each tagged series: Date, Version, Path, Tags, Tag1.
+// Returns count of tagged series, plain paths are skipped
+func encodeTaggedRows(metricList []Metric, days uint16, version uint32, e *batchEncoder) (int, error) {
+	var count int
+
+	rowBuffer := new(bytes.Buffer)
+	rowEncoder := RowBinary.NewEncoder(rowBuffer)
+
+	for i := 0; i < len(metricList); i++ {
+		path := metricList[i].Path
+		tags, ok := ParseTaggedName(unsafeString(path))
+		if !ok {
+			continue
+		}
+		count++
+
+		rowBuffer.Reset()
+		if err := rowEncoder.Uint16(days); err != nil {
+			return 0, err
+		}
+		if err := rowEncoder.Uint32(version); err != nil {
+			return 0, err
+		}
+		if err := rowEncoder.Bytes(path); err != nil {
+			return 0, err
+		}
+		if err := rowEncoder.StringList(tags); err != nil {
+			return 0, err
+		}
+
+		for _, tag := range tags {
+			if _, err := e.writer.Write(rowBuffer.Bytes()); err != nil {
+				return 0, err
+			}
+			// Tag1
+			if err := e.encoder.String(tag); err != nil {
+				return 0, err
+			}
+			if err := e.row(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return count, nil
+}
+
+// finishTagged sends last batch of rows and version marker (Path="", Tags=[], Tag1="") in separate batch
+func finishTagged(days uint16, version uint32, e *batchEncoder) error {
+	if err := e.flush(); err != nil {
+		return err
+	}
+
+	if err := e.encoder.Uint16(days); err != nil {
+		return err
+	}
+	if err := e.encoder.Uint32(version); err != nil {
+		return err
+	}
+	if err := e.encoder.Bytes([]byte{}); err != nil {
+		return err
+	}
+	if err := e.encoder.StringList([]string{}); err != nil {
+		return err
+	}
+	if err := e.encoder.String(""); err != nil {
+		return err
+	}
+	e.rows++
+	return e.flush()
+}
+
+// MakeTagged builds index of tagged series (name;tag=value) from tree into tagged-table.
+// Versions older than last tags.keep-versions are removed after upload as in tag table
+func MakeTagged(cfg *config.Config) (*Stat, error) {
+	logger := zapwriter.Logger("tagger")
+
+	if cfg.ClickHouse.TaggedTable == "" {
+		return nil, fmt.Errorf("tagged-table is not set")
+	}
+
+	start := time.Now()
+	version := uint32(start.Unix())
+
+	date, err := time.ParseInLocation("2006-01-02", cfg.Tags.Date, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Tags.DateScheme == config.DateSchemeRun {
+		date = start
+	}
+
+	// tagged series are streamed sorted by path through pipeline without rules and inserted by batches
+	var src source
+	if cfg.Tags.InputFile != "" {
+		body, err := ioutil.ReadFile(cfg.Tags.InputFile)
+		if err != nil {
+			return nil, err
+		}
+		metricList, _, err := parseInput(body, cfg.Tags.InputFormat)
+		if err != nil {
+			return nil, err
+		}
+		sort.Sort(ByPath(metricList))
+		src = sliceSource(metricList, ChunkSize)
+	} else {
+		src = readTreeSorted(cfg, logger, sqlb.Like("Path", "%;%"), ChunkSize)
+	}
+
+	sink, closeSink, err := tagsSink(cfg, logger,
+		sqlb.NewInsert(cfg.ClickHouse.TaggedTable, "Date", "Version", "Path", "Tags", "Tag1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	stat := &Stat{}
+	days := RowBinary.DateToUint16(date)
+
+	stat.Tagged, err = writeBatches(cfg.Tags.BatchSize, sink, func(e *batchEncoder) (int, error) {
+		var tagged int
+		err := runPipeline(src, nil, cfg.Common.MaxCPU, func(metricList []Metric) error {
+			stat.Metrics += len(metricList)
+			n, err := encodeTaggedRows(metricList, days, version, e)
+			tagged += n
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+		return tagged, finishTagged(days, version, e)
+	})
+	if closeErr := closeSink(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("tagged series index",
+		zap.Int("paths", stat.Metrics),
+		zap.Int("tagged", stat.Tagged),
+		zap.Duration("time", time.Since(start)),
+	)
+
+	if cfg.Tags.KeepVersions > 0 && cfg.Tags.OutputFile == "" {
+		stat.Pruned, err = pruneTable(cfg, logger, cfg.ClickHouse.TaggedTable, sqlb.And(
+			sqlb.Eq("Tag1", ""),
+			sqlb.Eq("Path", ""),
+		))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stat, nil
+}
@@ -0,0 +1,129 @@
+package tagger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lomik/graphite-clickhouse/config"
+)
+
+func TestParseTaggedName(t *testing.T) {
+	assert := assert.New(t)
+
+	tags, ok := ParseTaggedName("cpu.usage;host=web1;dc=us")
+	assert.True(ok)
+	assert.Equal([]string{"__name__=cpu.usage", "dc=us", "host=web1"}, tags)
+
+	for _, path := range []string{"cpu.usage", "cpu;host", ";host=web1", "cpu;host=", "cpu;=web1"} {
+		_, ok = ParseTaggedName(path)
+		assert.False(ok, path)
+	}
+}
+
+func TestEncodeTagged(t *testing.T) {
+	assert := assert.New(t)
+
+	var batches int
+	count, err := writeBatches(0, func(body *bytes.Buffer) error {
+		batches++
+		return nil
+	}, func(e *batchEncoder) (int, error) {
+		metricList := []Metric{{Path: []byte("cpu;host=a")}, {Path: []byte("plain.metric")}, {Path: []byte("mem;host=b;dc=us")}}
+		count, err := encodeTaggedRows(metricList, 1, 2, e)
+		if err != nil {
+			return 0, err
+		}
+		return count, finishTagged(1, 2, e)
+	})
+
+	assert.NoError(err)
+	assert.Equal(2, count)
+	// rows and version marker
+	assert.Equal(2, batches)
+}
+
+func TestMakeTagged(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &treeMock{paths: []string{"cpu;host=a", "mem;dc=us;host=b"}}
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.ClickHouse.TreeTable = "graphite_tree"
+	cfg.ClickHouse.TaggedTable = "graphite_tagged"
+	cfg.Tags.BatchSize = 2
+
+	stat, err := MakeTagged(cfg)
+	assert.NoError(err)
+	assert.Equal(2, stat.Metrics)
+	assert.Equal(2, stat.Tagged)
+
+	m.Lock()
+	defer m.Unlock()
+
+	assert.Contains(m.queries[0], "FROM graphite_tree WHERE (Path LIKE")
+	assert.Contains(m.queries[0], "ORDER BY Path")
+	// 5 rows by tag in batches of 2 and version marker
+	assert.Len(m.inserts, 4)
+	assert.Contains(string(m.inserts[0]), "cpu;host=a")
+	assert.Contains(string(m.inserts[1]), "mem;dc=us;host=b")
+}
+
+func TestMakeTaggedPrune(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "tagger")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input")
+	assert.NoError(ioutil.WriteFile(input, []byte("cpu;host=a\nplain.metric\n"), 0644))
+
+	var lock sync.Mutex
+	var queries []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("query")
+		if q == "" {
+			body, _ := ioutil.ReadAll(r.Body)
+			q = string(body)
+		}
+
+		lock.Lock()
+		queries = append(queries, q)
+		lock.Unlock()
+
+		if strings.HasPrefix(q, "SELECT Version") {
+			w.Write([]byte("10\n20\n30\n"))
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.ClickHouse.TaggedTable = "graphite_tagged"
+	cfg.Tags.InputFile = input
+	cfg.Tags.InputFormat = config.FormatText
+	cfg.Tags.KeepVersions = 2
+
+	stat, err := MakeTagged(cfg)
+	assert.NoError(err)
+	assert.Equal(1, stat.Tagged)
+	assert.Equal([]uint32{10}, stat.Pruned)
+
+	assert.Equal([]string{
+		"SELECT Version FROM graphite_tagged WHERE (Tag1 = {p0:String}) AND (Path = {p1:String}) GROUP BY Version FORMAT TabSeparated",
+		"DELETE FROM graphite_tagged WHERE (Version < {p0:UInt32})",
+	}, queries[len(queries)-2:])
+}
@@ -2,8 +2,6 @@ package tagger
 
 import (
 	"bytes"
-	"compress/gzip"
-	"context"
 	"fmt"
 	"io/ioutil"
 	"runtime"
@@ -16,11 +14,10 @@ import (
 	"github.com/lomik/graphite-clickhouse/config"
 	"github.com/lomik/graphite-clickhouse/helper/RowBinary"
 	"github.com/lomik/graphite-clickhouse/helper/clickhouse"
+	"github.com/lomik/graphite-clickhouse/helper/sqlb"
 	"github.com/lomik/zapwriter"
 )
 
-const SelectChunksCount = 10
-
 func unsafeString(b []byte) string {
 	return *(*string)(unsafe.Pointer(&b))
 }
@@ -62,7 +59,23 @@ func pathLevel(path []byte) int {
 	return bytes.Count(path, []byte{'.'}) + 1
 }
 
+// Make runs tagger and returns error only. See Run
 func Make(cfg *config.Config) error {
+	_, err := Run(cfg)
+	return err
+}
+
+// Stat is result of tagger run
+type Stat struct {
+	Metrics int // paths read from tree, with parents in incremental mode
+	Tagged  int // paths with at least one tag
+	Removed int // paths deleted from tree in incremental mode
+	Report  *Report
+	Pruned  []uint32 // removed versions
+}
+
+// Run reads tree, matches rules and inserts tags. Returns count of tagged paths
+func Run(cfg *config.Config) (*Stat, error) {
 	var start time.Time
 	var block string
 
@@ -84,107 +97,222 @@ func Make(cfg *config.Config) error {
 		)
 	}
 
+	stat := &Stat{}
+
 	version := uint32(time.Now().Unix())
+	// run start. Saved as checkpoint of incremental mode
+	runVersion := version
 
 	// Parse rules
 	begin("parse rules")
 	rules, err := ParseGlob(cfg.Tags.Rules)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	date, err := time.ParseInLocation("2006-01-02", cfg.Tags.Date, time.Local)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if cfg.Tags.DateScheme == config.DateSchemeRun {
+		date = time.Unix(int64(runVersion), 0)
 	}
 	end()
 
-	// Read clickhouse
-	begin("read and parse tree")
-	// bodies := make([][]byte, 0)
+	// incremental mode: read tree rows newer than checkpoint and insert delta with version of last full run
+	var incremental bool
+	var treeVersionCond sqlb.Cond
+
+	if cfg.Tags.Incremental {
+		begin("read checkpoint")
+		if cfg.Tags.InputFile != "" {
+			return nil, fmt.Errorf("incremental mode requires reading tree from clickhouse, unset input-file")
+		}
+		if cfg.Tags.StateFile == "" {
+			return nil, fmt.Errorf("incremental mode requires state-file")
+		}
+
+		tagVersion, checkpoint, err := readCheckpoint(cfg)
+		if err != nil {
+			return nil, err
+		}
+		end()
+
+		if tagVersion == 0 || checkpoint == 0 {
+			logger.Info("no previous run found, make full run")
+		} else {
+			logger.Info("incremental run",
+				zap.Uint32("checkpoint", checkpoint),
+				zap.Uint32("version", tagVersion),
+			)
+			incremental = true
+			version = tagVersion
+			treeVersionCond = sqlb.Ge("Version", checkpoint)
+		}
+	}
 
-	var bodies [][]byte
+	// Sorted paths are passed by chunks through match, link and write stages. Whole tree is not kept in memory,
+	// except input file and changes of incremental run
+	var src source
+	var current map[string][]string
 
 	if cfg.Tags.InputFile != "" {
+		begin(fmt.Sprintf("read and sort %#v", cfg.Tags.InputFile))
 		body, err := ioutil.ReadFile(cfg.Tags.InputFile)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		bodies = [][]byte{body}
-	} else {
-		bodies = make([][]byte, SelectChunksCount)
-		for i := 0; i < SelectChunksCount; i++ {
-			bodies[i], err = clickhouse.Query(
-				context.WithValue(context.Background(), "logger", logger),
-				cfg.ClickHouse.Url,
-				fmt.Sprintf(
-					"SELECT Path FROM %s WHERE cityHash64(Path) %% %d == %d GROUP BY Path HAVING argMax(Deleted, Version)==0 FORMAT RowBinary",
-					cfg.ClickHouse.TreeTable,
-					SelectChunksCount,
-					i,
-				),
-				cfg.ClickHouse.TreeTimeout.Value(),
-			)
+		metricList, _, err := parseInput(body, cfg.Tags.InputFormat)
+		if err != nil {
+			return nil, err
+		}
+		sort.Sort(ByPath(metricList))
+		src = sliceSource(metricList, ChunkSize)
+		end()
+	} else if incremental {
+		begin("read changed paths")
+		metricList, err := readTreeList(cfg, logger, treeVersionCond)
+		if err != nil {
+			return nil, err
 		}
+		// parents are needed for tags inheritance and for copy tags from childs
+		metricList = withParents(metricList)
+		sort.Sort(ByPath(metricList))
+		end()
 
+		begin("read current tags of changed directories")
+		current, err = readTags(cfg, logger, version, directories(metricList))
 		if err != nil {
-			return err
+			return nil, err
 		}
+		src = sliceSource(metricList, ChunkSize)
+		end()
+	} else {
+		src = readTreeSorted(cfg, logger, nil, ChunkSize)
 	}
 
-	var count int
+	var report *Report
+	if cfg.Tags.ReportFile != "" {
+		report = newReport(cfg.Tags.ReportDepth, cfg.Tags.ReportTop)
+	}
 
-	for i := 0; i < len(bodies); i++ {
-		c, err := countMetrics(bodies[i])
-		if err != nil {
-			return err
+	// called in write stage for each part of complete metrics
+	collect := func(metricList []Metric) {
+		stat.Metrics += len(metricList)
+		if current != nil {
+			mergeCurrentTags(metricList, current)
+		}
+		if report != nil {
+			report.add(metricList)
 		}
-		count += c
 	}
 
-	metricList := make([]Metric, count)
-
-	index := 0
+	if cfg.Tags.OutputFile != "" && cfg.Tags.OutputFormat != config.FormatRowBinary {
+		begin(fmt.Sprintf("match, write %s to %#v", cfg.Tags.OutputFormat, cfg.Tags.OutputFile))
+		out, err := createOutputFile(cfg.Tags.OutputFile, cfg.Tags.OutputFormat)
+		if err != nil {
+			return nil, err
+		}
 
-	var maxLevel int
+		err = runPipeline(src, rules, cfg.Common.MaxCPU, func(metricList []Metric) error {
+			collect(metricList)
+			tagged, err := out.write(metricList)
+			stat.Tagged += tagged
+			return err
+		})
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		end()
+	} else {
+		if cfg.Tags.OutputFile != "" {
+			begin(fmt.Sprintf("match, marshal RowBinary + gzip, write to %#v", cfg.Tags.OutputFile))
+		} else {
+			begin("match, marshal RowBinary + gzip, upload to clickhouse")
+		}
 
-	for i := 0; i < len(bodies); i++ {
-		body := bodies[i]
-		var namelen uint64
-		bodyLen := len(body)
-		var offset, readBytes int
+		sink, closeSink, err := tagsSink(cfg, logger,
+			sqlb.NewInsert(cfg.ClickHouse.TagTable, "Date", "Version", "Level", "Path", "IsLeaf", "Tags", "Tag1"),
+		)
+		if err != nil {
+			return nil, err
+		}
 
-		for ; ; index++ {
-			if offset >= bodyLen {
-				if offset == bodyLen {
-					break
-				}
-				return clickhouse.ErrClickHouseResponse
-			}
+		days := RowBinary.DateToUint16(date)
 
-			namelen, readBytes, err = clickhouse.ReadUvarint(body[offset:])
-			if err != nil {
+		stat.Tagged, err = writeBatches(cfg.Tags.BatchSize, sink, func(e *batchEncoder) (int, error) {
+			var tagged int
+			err := runPipeline(src, rules, cfg.Common.MaxCPU, func(metricList []Metric) error {
+				collect(metricList)
+				n, err := encodeRows(metricList, days, version, e)
+				tagged += n
 				return err
+			})
+			if err != nil {
+				return 0, err
 			}
+			// Incremental delta is added to last full version, so version marker is not changed
+			return tagged, finishTags(days, version, !incremental, e)
+		})
+		if closeErr := closeSink(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		end()
+	}
 
-			metricList[index].Path = body[offset+readBytes : offset+readBytes+int(namelen)]
-			metricList[index].Level = pathLevel(metricList[index].Path)
+	if report != nil {
+		begin(fmt.Sprintf("write report to %#v", cfg.Tags.ReportFile))
+		report.finish(rules)
+		if err = report.WriteFile(cfg.Tags.ReportFile); err != nil {
+			return nil, err
+		}
+		report.Log(logger)
+		stat.Report = report
+		end()
+	}
 
-			if metricList[index].Level > maxLevel {
-				maxLevel = metricList[index].Level
-			}
+	if incremental && cfg.Tags.OutputFile == "" {
+		begin("remove tags of deleted paths")
+		removed, err := readRemoved(cfg, logger, treeVersionCond)
+		if err != nil {
+			return nil, err
+		}
+		if err = removeTags(cfg, logger, version, removed); err != nil {
+			return nil, err
+		}
+		stat.Removed = len(removed)
+		end()
+	}
 
-			offset += readBytes + int(namelen)
+	// checkpoint is moved only after successful upload to clickhouse
+	if cfg.Tags.StateFile != "" && cfg.Tags.OutputFile == "" {
+		begin(fmt.Sprintf("write checkpoint to %#v", cfg.Tags.StateFile))
+		if err = writeState(cfg.Tags.StateFile, runVersion); err != nil {
+			return nil, err
 		}
+		end()
 	}
-	end()
 
-	begin("sort")
-	start = time.Now()
-	sort.Sort(ByPath(metricList))
-	end()
+	if cfg.Tags.KeepVersions > 0 && cfg.Tags.OutputFile == "" {
+		begin("prune old versions")
+		stat.Pruned, err = prune(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		end()
+	}
+
+	return stat, nil
+}
 
-	begin("make map")
+// linkParents sets ParentIndex of each metric. metricList must be sorted by path
+func linkParents(metricList []Metric, maxLevel int) {
 	levelMap := make([]int, maxLevel+1)
 	for index := 0; index < len(metricList); index++ {
 		m := &metricList[index]
@@ -199,104 +327,23 @@ func Make(cfg *config.Config) error {
 			}
 		}
 	}
-	end()
-
-	begin("match")
-	for index := 0; index < count; index++ {
-		m := &metricList[index]
-
-		if m.ParentIndex < 0 {
-			m.Tags = EmptySet
-		} else {
-			m.Tags = metricList[m.ParentIndex].Tags
-		}
-
-		rules.Match(m)
-	}
-	end()
+}
 
-	// copy from childs to parents
-	begin("copy tags from childs to parents")
-	for index := 0; index < count; index++ {
+func copyToParents(metricList []Metric) {
+	for index := 0; index < len(metricList); index++ {
 		m := &metricList[index]
 
 		for p := m.ParentIndex; p >= 0; p = metricList[p].ParentIndex {
 			metricList[p].Tags = metricList[p].Tags.Merge(m.Tags)
 		}
 	}
-	end()
-
-	begin("marshal RowBinary + gzip")
-	// INSERT INTO graphite_tag (Date,Version,Level,Path,IsLeaf,Tags,Tag1) FORMAT RowBinary
-	// with Content-Encoding: gzip
-
-	outBuf := new(bytes.Buffer)
-	writer := gzip.NewWriter(outBuf)
-
-	encoder := RowBinary.NewEncoder(writer)
-
-	days := RowBinary.DateToUint16(date)
-	metricBuffer := new(bytes.Buffer)
-	metricEncoder := RowBinary.NewEncoder(metricBuffer)
-
-	for i := 0; i < len(metricList); i++ {
-		m := &metricList[i]
-
-		if m.Tags == nil || m.Tags.Len() == 0 {
-			continue
-		}
-
-		metricBuffer.Reset()
-
-		// Date
-		err := metricEncoder.Uint16(days)
-		if err != nil {
-			return err
-		}
-		// Version
-		err = metricEncoder.Uint32(version)
-		if err != nil {
-			return err
-		}
-		// Level
-		err = metricEncoder.Uint32(uint32(m.Level))
-		if err != nil {
-			return err
-		}
-		// Path
-		err = metricEncoder.Bytes(m.Path)
-		if err != nil {
-			return err
-		}
-		// IsLeaf
-		err = metricEncoder.Uint8(m.IsLeaf())
-		if err != nil {
-			return err
-		}
-		// Tags
-		err = metricEncoder.StringList(m.Tags.List())
-		if err != nil {
-			return err
-		}
-
-		for _, tag := range m.Tags.List() {
-			_, err = writer.Write(metricBuffer.Bytes())
-			if err != nil {
-				return err
-			}
-
-			// Tag1
-			err = encoder.String(tag)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	// AND Empty record With Level=0, Path=0 and Without Tags
+}
 
+// writeVersionMarker writes empty record with Level=0, Path="" and without tags.
+// TagFinder uses max version of marker as current version of tag table
+func writeVersionMarker(encoder *RowBinary.Encoder, days uint16, version uint32) error {
 	// Date
-	err = encoder.Uint16(days)
+	err := encoder.Uint16(days)
 	if err != nil {
 		return err
 	}
@@ -326,32 +373,5 @@ func Make(cfg *config.Config) error {
 		return err
 	}
 	// Tag1=""
-	err = encoder.String("")
-	if err != nil {
-		return err
-	}
-
-	writer.Close()
-	end()
-
-	if cfg.Tags.OutputFile != "" {
-		begin(fmt.Sprintf("write to %#v", cfg.Tags.OutputFile))
-		ioutil.WriteFile(cfg.Tags.OutputFile, outBuf.Bytes(), 0644)
-		end()
-	} else {
-		begin("upload to clickhouse")
-		_, err = clickhouse.PostGzip(
-			context.WithValue(context.Background(), "logger", logger),
-			cfg.ClickHouse.Url,
-			fmt.Sprintf("INSERT INTO %s (Date,Version,Level,Path,IsLeaf,Tags,Tag1) FORMAT RowBinary", cfg.ClickHouse.TagTable),
-			outBuf,
-			cfg.ClickHouse.TreeTimeout.Value(),
-		)
-		if err != nil {
-			return err
-		}
-		end()
-	}
-
-	return nil
+	return encoder.String("")
 }
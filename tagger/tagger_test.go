@@ -0,0 +1,97 @@
+package tagger
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lomik/graphite-clickhouse/config"
+	"github.com/lomik/graphite-clickhouse/helper/tests"
+)
+
+// treeMock answers tree queries with sorted paths and collects gunzipped bodies of inserts
+type treeMock struct {
+	sync.Mutex
+	paths      []string
+	inserts    [][]byte
+	queries    []string
+	failInsert bool
+}
+
+func (m *treeMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("query")
+	if q == "" {
+		body, _ := ioutil.ReadAll(r.Body)
+		q = string(body)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.queries = append(m.queries, q)
+
+	switch {
+	case strings.HasPrefix(q, "INSERT"):
+		if m.failInsert {
+			http.Error(w, "clickhouse is down", http.StatusInternalServerError)
+			return
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(zr)
+		m.inserts = append(m.inserts, body)
+	case strings.Contains(q, "argMax(Deleted, Version)==0"):
+		w.Write(tests.Paths(m.paths...))
+	}
+}
+
+// treePaths returns paths with parent directories sorted as tree table query returns them
+func treePaths(paths ...string) []string {
+	metricList := make([]Metric, 0, len(paths))
+	for _, p := range paths {
+		metricList = append(metricList, Metric{Path: []byte(p), Level: pathLevel([]byte(p))})
+	}
+	metricList = withParents(metricList)
+	sort.Sort(ByPath(metricList))
+
+	result := make([]string, len(metricList))
+	for i := 0; i < len(metricList); i++ {
+		result[i] = string(metricList[i].Path)
+	}
+	return result
+}
+
+// testRun returns config of Run with rules and ClickHouse mock. Call cleanup after test
+func testRun(t *testing.T, rules string, m *treeMock) (cfg *config.Config, dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "tagger")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "rules.conf"), []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(m)
+
+	cfg = config.New()
+	cfg.ClickHouse.Url = srv.URL
+	cfg.ClickHouse.TreeTable = "graphite_tree"
+	cfg.ClickHouse.TagTable = "graphite_tag"
+	cfg.Tags.Rules = filepath.Join(dir, "*.conf")
+
+	return cfg, dir, func() {
+		srv.Close()
+		os.RemoveAll(dir)
+	}
+}